@@ -0,0 +1,34 @@
+package blobstore
+
+import (
+	"fmt"
+	"os"
+)
+
+// NewBlobStoreFromEnv returns the store chosen by BLOB_STORE, "local" (default)
+// writing under BLOB_LOCAL_DIR or "s3" configured by the S3_* variables.
+func NewBlobStoreFromEnv() (BlobStore, error) {
+	switch store := os.Getenv("BLOB_STORE"); store {
+	case "", "local":
+		dir := os.Getenv("BLOB_LOCAL_DIR")
+		if dir == "" {
+			dir = "data/blobs"
+		}
+		publicURL := os.Getenv("BLOB_PUBLIC_URL")
+		if publicURL == "" {
+			publicURL = "http://localhost:8080/blobs"
+		}
+		return NewLocalStore(dir, publicURL), nil
+	case "s3":
+		return NewS3Store(S3Options{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyId:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PublicURL:       os.Getenv("BLOB_PUBLIC_URL"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown BLOB_STORE %q, expected local or s3", store)
+	}
+}
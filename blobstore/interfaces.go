@@ -0,0 +1,15 @@
+// This file contains the interfaces for the blob stores.
+// Uploaded files are kept in a BlobStore so the storage can be swapped, the
+// local store is meant for a single instance, the S3 store for the others.
+package blobstore
+
+import "context"
+
+type BlobStore interface {
+	// Put stores the content under key, replacing the previous content.
+	Put(ctx context.Context, key string, content []byte, contentType string) error
+	// Delete removes the key, a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL the key is served at.
+	URL(key string) string
+}
@@ -1,31 +1,223 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+	// the IANA time zones of the profiles, whatever the image ships
+	_ "time/tzdata"
 
+	"github.com/SawitProRecruitment/UserService/blobstore"
 	"github.com/SawitProRecruitment/UserService/generated"
 	"github.com/SawitProRecruitment/UserService/handler"
+	"github.com/SawitProRecruitment/UserService/keymanager"
+	"github.com/SawitProRecruitment/UserService/passwordhash"
 	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/sms"
+	"github.com/go-webauthn/webauthn/webauthn"
 
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	e := echo.New()
 
-	var server generated.ServerInterface = newServer()
+	swagger, err := generated.GetSwagger()
+	if err != nil {
+		panic(err)
+	}
 
-	generated.RegisterHandlers(e, server)
-	e.Logger.Fatal(e.Start(":1323"))
-}
+	// responses are only validated in test environments, it buffers every response
+	validationMiddleware, err := handler.ValidationMiddleware(swagger, handler.ValidationOptions{
+		ValidateResponses: os.Getenv("VALIDATE_RESPONSES") == "true",
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// the per IP login limit relies on the client IP, X-Forwarded-For is only
+	// trusted when the service runs behind a proxy setting it
+	if os.Getenv("TRUST_X_FORWARDED_FOR") == "true" {
+		e.IPExtractor = echo.ExtractIPFromXFFHeader()
+	} else {
+		e.IPExtractor = echo.ExtractIPDirect()
+	}
 
-func newServer() *handler.Server {
 	dbDsn := os.Getenv("DATABASE_URL")
 	var repo repository.RepositoryInterface = repository.NewRepository(repository.NewRepositoryOptions{
 		Dsn: dbDsn,
 	})
+
+	blobStore := newBlobStore()
+	// the local store is served by the service itself, S3 serves its objects
+	if localStore, ok := blobStore.(*blobstore.LocalStore); ok {
+		e.Static("/blobs", localStore.Dir())
+	}
+
+	server := newServer(repo, blobStore)
+	// the avatars of the purged users are deleted along with them
+	go runPurgeJob(server, durationFromEnv("ACCOUNT_RETENTION", 30*24*time.Hour), durationFromEnv("PURGE_INTERVAL", time.Hour))
+
+	// every error is answered as application/problem+json
+	e.HTTPErrorHandler = handler.HTTPErrorHandler
+	e.Use(handler.RequestIdMiddleware())
+	// the bodies are read whole by the request validation, the avatar is the largest one
+	e.Use(middleware.BodyLimit(fmt.Sprintf("%dB", server.AvatarMaxBytes+1<<20)))
+	e.Use(server.AuthMiddleware(swagger))
+	e.Use(validationMiddleware)
+
+	generated.RegisterHandlers(e, server)
+	go func() {
+		if err := e.Start(":1323"); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			e.Logger.Fatal(err)
+		}
+	}()
+
+	// on SIGTERM the requests in flight and the SMS still being sent are finished
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		e.Logger.Error(err)
+	}
+	server.WaitBackground()
+}
+
+// shutdownTimeout bounds the wait for the requests in flight on shutdown
+const shutdownTimeout = 30 * time.Second
+
+func newServer(repo repository.RepositoryInterface, blobStore blobstore.BlobStore) *handler.Server {
+	loginProtection, err := handler.LoginProtectionOptionsFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	passwordPolicy, err := handler.PasswordPolicyFromEnv()
+	if err != nil {
+		panic(err)
+	}
 	opts := handler.NewServerOptions{
-		Repository: repo,
+		Repository:      repo,
+		KeyManager:      newKeyManager(),
+		LoginProtection: &loginProtection,
+		AdminApiKey:     os.Getenv("ADMIN_API_KEY"),
+		SMSSender:       newSMSSender(),
+		WebAuthn:        newWebAuthn(),
+		PasswordPolicy:  &passwordPolicy,
+		PasswordHasher:  newPasswordHasher(),
+		BlobStore:       blobStore,
+		AvatarMaxBytes:  handler.DefaultAvatarMaxBytes,
+	}
+	if value := os.Getenv("REQUIRE_PHONE_VERIFICATION"); value != "" {
+		opts.RequirePhoneVerification, err = strconv.ParseBool(value)
+		if err != nil {
+			panic("REQUIRE_PHONE_VERIFICATION must be a boolean")
+		}
+	}
+	if value := os.Getenv("AVATAR_MAX_BYTES"); value != "" {
+		opts.AvatarMaxBytes, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || opts.AvatarMaxBytes <= 0 {
+			panic("AVATAR_MAX_BYTES must be a positive integer")
+		}
 	}
 	return handler.NewServer(opts)
 }
+
+func newBlobStore() blobstore.BlobStore {
+	store, err := blobstore.NewBlobStoreFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	return store
+}
+
+func newSMSSender() sms.SMSSender {
+	sender, err := sms.NewSenderFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	return sender
+}
+
+func newPasswordHasher() passwordhash.PasswordHasher {
+	hasher, err := passwordhash.NewHasherFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	return hasher
+}
+
+func newWebAuthn() *webauthn.WebAuthn {
+	relyingParty, err := handler.NewWebAuthnFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	return relyingParty
+}
+
+func newKeyManager() keymanager.KeyManagerInterface {
+	keyOpts := keymanager.LoadOptionsFromEnv()
+	if keyOpts.Configured() {
+		keyManager, err := keymanager.Load(keyOpts)
+		if err != nil {
+			panic(err)
+		}
+		return keyManager
+	}
+
+	// tokens signed with an ephemeral key don't survive a restart, fine for local development only
+	log.Println("JWT_SIGNING_KEY is not set, signing tokens with an ephemeral EdDSA key")
+	key, err := keymanager.GenerateKey(keymanager.AlgorithmEdDSA)
+	if err != nil {
+		panic(err)
+	}
+	keyManager := keymanager.NewKeyManager()
+	if err := keyManager.Rotate(key); err != nil {
+		panic(err)
+	}
+	return keyManager
+}
+
+// runPurgeJob hard deletes, every interval, the accounts soft deleted for
+// longer than the retention.
+func runPurgeJob(server *handler.Server, retention time.Duration, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for ; true; <-ticker.C {
+		purged, err := server.PurgeDeletedUsers(context.Background(), time.Now().Add(-retention))
+		if purged > 0 {
+			log.Printf("purged %d deleted users", purged)
+		}
+		if err != nil {
+			log.Printf("failed to purge deleted users: %v", err)
+		}
+	}
+}
+
+func durationFromEnv(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		panic(fmt.Sprintf("%s must be a positive duration, e.g. 720h", name))
+	}
+	return duration
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/SawitProRecruitment/UserService/migrations"
+	"github.com/SawitProRecruitment/UserService/repository"
+)
+
+const migrateUsage = `usage: main migrate [-dry-run] [-to version] [-steps n] up|down|status
+
+  up      applies the pending migrations, up to -to when set
+  down    reverts the -steps latest applied migrations, 1 by default
+  status  lists the migrations and when they were applied
+`
+
+// runMigrate is the migrate subcommand, it migrates the database of DATABASE_URL.
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(flags.Output(), migrateUsage) }
+	dryRun := flags.Bool("dry-run", false, "print the SQL instead of running it")
+	to := flags.Int64("to", 0, "version to migrate up to")
+	steps := flags.Int("steps", 1, "number of migrations to revert")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	repo := repository.NewRepository(repository.NewRepositoryOptions{
+		Dsn: os.Getenv("DATABASE_URL"),
+	})
+	defer repo.Db.Close()
+	migrator, err := migrations.NewMigrator(migrations.NewMigratorOptions{
+		Db:     repo.Db,
+		DryRun: *dryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch flags.Arg(0) {
+	case "up":
+		applied, err := migrator.Up(ctx, *to)
+		return reportMigrations("applied", applied, *dryRun, err)
+	case "down":
+		reverted, err := migrator.Down(ctx, *steps)
+		return reportMigrations("reverted", reverted, *dryRun, err)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%-40s %s\n", status.Migration, appliedAt)
+		}
+		return nil
+	default:
+		flags.Usage()
+		return errors.New("unknown migrate command " + flags.Arg(0))
+	}
+}
+
+// reportMigrations prints the migrations run before err, if any.
+func reportMigrations(action string, list []migrations.Migration, dryRun bool, err error) error {
+	if dryRun {
+		action = "would have " + action
+	}
+	for _, migration := range list {
+		fmt.Printf("%s %s\n", action, migration)
+	}
+	if err == nil && len(list) == 0 {
+		fmt.Println("no migration to run")
+	}
+	return err
+}
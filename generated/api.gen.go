@@ -7,22 +7,110 @@ import (
 	"bytes"
 	"compress/gzip"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/labstack/echo/v4"
+	"github.com/oapi-codegen/runtime"
+	openapi_types "github.com/oapi-codegen/runtime/types"
 )
 
 const (
-	BearerAuthScopes = "BearerAuth.Scopes"
+	AdminApiKeyScopes = "AdminApiKey.Scopes"
+	BearerAuthScopes  = "BearerAuth.Scopes"
 )
 
-// LoginErrorResponse defines model for LoginErrorResponse.
-type LoginErrorResponse struct {
+// Defines values for LoginEventFailureReason.
+const (
+	AccountLocked    LoginEventFailureReason = "account_locked"
+	InvalidMfaCode   LoginEventFailureReason = "invalid_mfa_code"
+	InvalidPassword  LoginEventFailureReason = "invalid_password"
+	PhoneNotVerified LoginEventFailureReason = "phone_not_verified"
+)
+
+// AvatarResponse defines model for AvatarResponse.
+type AvatarResponse struct {
+	// AvatarThumbnails URLs of the square thumbnails of an uploaded avatar, keyed by their size in pixels
+	AvatarThumbnails AvatarThumbnails `json:"avatarThumbnails"`
+	AvatarUrl        string           `json:"avatarUrl"`
+}
+
+// AvatarThumbnails URLs of the square thumbnails of an uploaded avatar, keyed by their size in pixels
+type AvatarThumbnails map[string]string
+
+// ChangePasswordParam defines model for ChangePasswordParam.
+type ChangePasswordParam struct {
+	CurrentPassword string   `json:"currentPassword"`
+	NewPassword     Password `json:"newPassword"`
+}
+
+// ConfirmTotpParam defines model for ConfirmTotpParam.
+type ConfirmTotpParam struct {
+	Code OTP `json:"code"`
+}
+
+// DeleteMyProfileParam defines model for DeleteMyProfileParam.
+type DeleteMyProfileParam struct {
+	Password string `json:"password"`
+}
+
+// FieldError defines model for FieldError.
+type FieldError struct {
+	Field   string `json:"field"`
 	Message string `json:"message"`
+	Rule    string `json:"rule"`
+}
+
+// FullName defines model for FullName.
+type FullName = string
+
+// JWK defines model for JWK.
+type JWK struct {
+	Alg string  `json:"alg"`
+	Crv *string `json:"crv,omitempty"`
+	E   *string `json:"e,omitempty"`
+	Kid string  `json:"kid"`
+	Kty string  `json:"kty"`
+	N   *string `json:"n,omitempty"`
+	Use string  `json:"use"`
+	X   *string `json:"x,omitempty"`
+	Y   *string `json:"y,omitempty"`
+}
+
+// JWKSResponse defines model for JWKSResponse.
+type JWKSResponse struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoginEvent defines model for LoginEvent.
+type LoginEvent struct {
+	CreatedAt     time.Time                `json:"createdAt"`
+	FailureReason *LoginEventFailureReason `json:"failureReason,omitempty"`
+	Id            int64                    `json:"id"`
+	IpAddress     string                   `json:"ipAddress"`
+	Success       bool                     `json:"success"`
+	UserAgent     string                   `json:"userAgent"`
+}
+
+// LoginEventFailureReason defines model for LoginEvent.FailureReason.
+type LoginEventFailureReason string
+
+// LoginHistoryResponse defines model for LoginHistoryResponse.
+type LoginHistoryResponse struct {
+	Items      []LoginEvent `json:"items"`
+	NextCursor *string      `json:"nextCursor,omitempty"`
+}
+
+// LoginMfaParam defines model for LoginMfaParam.
+type LoginMfaParam struct {
+	Code     string `json:"code"`
+	MfaToken string `json:"mfaToken"`
 }
 
 // LoginParam defines model for LoginParam.
@@ -33,77 +121,439 @@ type LoginParam struct {
 
 // LoginResponse defines model for LoginResponse.
 type LoginResponse struct {
-	Id    int    `json:"id"`
-	Token string `json:"token"`
+	ExpiresIn    int    `json:"expiresIn"`
+	Id           int    `json:"id"`
+	RefreshToken string `json:"refreshToken"`
+	Token        string `json:"token"`
 }
 
-// MyProfileErrorResponse defines model for MyProfileErrorResponse.
-type MyProfileErrorResponse struct {
-	Message string `json:"message"`
+// MfaChallengeResponse defines model for MfaChallengeResponse.
+type MfaChallengeResponse struct {
+	ExpiresIn   int    `json:"expiresIn"`
+	MfaRequired bool   `json:"mfaRequired"`
+	MfaToken    string `json:"mfaToken"`
 }
 
 // MyProfileResponse defines model for MyProfileResponse.
 type MyProfileResponse struct {
-	Name        string `json:"name"`
-	PhoneNumber string `json:"phoneNumber"`
+	// AvatarThumbnails URLs of the square thumbnails of an uploaded avatar, keyed by their size in pixels
+	AvatarThumbnails *AvatarThumbnails   `json:"avatarThumbnails,omitempty"`
+	AvatarUrl        *string             `json:"avatarUrl,omitempty"`
+	CreatedAt        time.Time           `json:"createdAt"`
+	DateOfBirth      *openapi_types.Date `json:"dateOfBirth,omitempty"`
+	Email            *string             `json:"email,omitempty"`
+	EmailVerified    *bool               `json:"emailVerified,omitempty"`
+	Id               int                 `json:"id"`
+	Locale           *string             `json:"locale,omitempty"`
+
+	// LoginCount Number of successful logins
+	LoginCount    int64     `json:"loginCount"`
+	Name          string    `json:"name"`
+	PhoneNumber   string    `json:"phoneNumber"`
+	PhoneVerified bool      `json:"phoneVerified"`
+	Timezone      *string   `json:"timezone,omitempty"`
+	UpdatedAt     time.Time `json:"updatedAt"`
 }
 
-// RegistrationErrorResponse defines model for RegistrationErrorResponse.
-type RegistrationErrorResponse struct {
-	Message string `json:"message"`
+// OTP defines model for OTP.
+type OTP = string
+
+// Passkey defines model for Passkey.
+type Passkey struct {
+	CreatedAt  time.Time  `json:"createdAt"`
+	Id         int        `json:"id"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	Name       string     `json:"name"`
 }
 
-// RegistrationParam defines model for RegistrationParam.
-type RegistrationParam struct {
-	FullName    string `json:"fullName"`
-	Password    string `json:"password"`
-	PhoneNumber string `json:"phoneNumber"`
+// PasskeyListResponse defines model for PasskeyListResponse.
+type PasskeyListResponse struct {
+	Items []Passkey `json:"items"`
 }
 
-// RegistrationResponse defines model for RegistrationResponse.
-type RegistrationResponse struct {
-	Id int `json:"id"`
+// Password defines model for Password.
+type Password = string
+
+// PasswordResetConfirmParam defines model for PasswordResetConfirmParam.
+type PasswordResetConfirmParam struct {
+	NewPassword Password    `json:"newPassword"`
+	Otp         OTP         `json:"otp"`
+	PhoneNumber PhoneNumber `json:"phoneNumber"`
 }
 
-// UpdateProfileErrorResponse defines model for UpdateProfileErrorResponse.
-type UpdateProfileErrorResponse struct {
-	Message string `json:"message"`
+// PasswordResetRequestParam defines model for PasswordResetRequestParam.
+type PasswordResetRequestParam struct {
+	PhoneNumber PhoneNumber `json:"phoneNumber"`
+}
+
+// PhoneNumber defines model for PhoneNumber.
+type PhoneNumber = string
+
+// PhoneNumberChangeConfirmParam defines model for PhoneNumberChangeConfirmParam.
+type PhoneNumberChangeConfirmParam struct {
+	Otp OTP `json:"otp"`
+}
+
+// PhoneVerificationConfirmParam defines model for PhoneVerificationConfirmParam.
+type PhoneVerificationConfirmParam struct {
+	Otp         OTP         `json:"otp"`
+	PhoneNumber PhoneNumber `json:"phoneNumber"`
+}
+
+// PhoneVerificationRequestParam defines model for PhoneVerificationRequestParam.
+type PhoneVerificationRequestParam struct {
+	PhoneNumber PhoneNumber `json:"phoneNumber"`
+}
+
+// Problem defines model for Problem.
+type Problem struct {
+	Code      string        `json:"code"`
+	Detail    *string       `json:"detail,omitempty"`
+	Errors    *[]FieldError `json:"errors,omitempty"`
+	Instance  *string       `json:"instance,omitempty"`
+	RequestId string        `json:"requestId"`
+	Status    int           `json:"status"`
+	Title     string        `json:"title"`
+	Type      string        `json:"type"`
+}
+
+// RecoveryCodesResponse defines model for RecoveryCodesResponse.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// RefreshTokenParam defines model for RefreshTokenParam.
+type RefreshTokenParam struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// RefreshTokenResponse defines model for RefreshTokenResponse.
+type RefreshTokenResponse struct {
+	ExpiresIn    int    `json:"expiresIn"`
+	RefreshToken string `json:"refreshToken"`
+	Token        string `json:"token"`
+}
+
+// RegistrationParam defines model for RegistrationParam.
+type RegistrationParam struct {
+	FullName    FullName    `json:"fullName"`
+	Password    Password    `json:"password"`
+	PhoneNumber PhoneNumber `json:"phoneNumber"`
+}
+
+// TotpEnrollmentResponse defines model for TotpEnrollmentResponse.
+type TotpEnrollmentResponse struct {
+	OtpauthUri string `json:"otpauthUri"`
+	Secret     string `json:"secret"`
 }
 
 // UpdateProfileParam defines model for UpdateProfileParam.
 type UpdateProfileParam struct {
-	FullName    *string `json:"fullName,omitempty"`
-	PhoneNumber *string `json:"phoneNumber,omitempty"`
+	AvatarUrl   *string             `json:"avatarUrl,omitempty"`
+	DateOfBirth *openapi_types.Date `json:"dateOfBirth,omitempty"`
+	Email       *string             `json:"email,omitempty"`
+	FullName    *FullName           `json:"fullName,omitempty"`
+	Locale      *string             `json:"locale,omitempty"`
+	PhoneNumber *PhoneNumber        `json:"phoneNumber,omitempty"`
+	Timezone    *string             `json:"timezone,omitempty"`
 }
 
 // UpdateProfileResponse defines model for UpdateProfileResponse.
 type UpdateProfileResponse struct {
-	Id int `json:"id"`
+	Id                 int     `json:"id"`
+	PendingPhoneNumber *string `json:"pendingPhoneNumber,omitempty"`
+}
+
+// WebauthnCredential defines model for WebauthnCredential.
+type WebauthnCredential struct {
+	Id                   string                 `json:"id"`
+	RawId                string                 `json:"rawId"`
+	Response             map[string]interface{} `json:"response"`
+	Type                 string                 `json:"type"`
+	AdditionalProperties map[string]interface{} `json:"-"`
+}
+
+// WebauthnOptions defines model for WebauthnOptions.
+type WebauthnOptions struct {
+	PublicKey map[string]interface{} `json:"publicKey"`
+}
+
+// WebauthnRegistrationParam defines model for WebauthnRegistrationParam.
+type WebauthnRegistrationParam struct {
+	Credential WebauthnCredential `json:"credential"`
+	Name       string             `json:"name"`
+}
+
+// BadRequest defines model for BadRequest.
+type BadRequest = Problem
+
+// Conflict defines model for Conflict.
+type Conflict = Problem
+
+// Forbidden defines model for Forbidden.
+type Forbidden = Problem
+
+// InternalServerError defines model for InternalServerError.
+type InternalServerError = Problem
+
+// NotFound defines model for NotFound.
+type NotFound = Problem
+
+// TooManyRequests defines model for TooManyRequests.
+type TooManyRequests = Problem
+
+// Unauthorized defines model for Unauthorized.
+type Unauthorized = Problem
+
+// UploadMyAvatarMultipartBody defines parameters for UploadMyAvatar.
+type UploadMyAvatarMultipartBody struct {
+	File openapi_types.File `json:"file"`
+}
+
+// ListMyLoginsParams defines parameters for ListMyLogins.
+type ListMyLoginsParams struct {
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// Cursor nextCursor of the previous page
+	Cursor *string `form:"cursor,omitempty" json:"cursor,omitempty"`
 }
 
 // LoginJSONRequestBody defines body for Login for application/json ContentType.
 type LoginJSONRequestBody = LoginParam
 
+// LoginMfaJSONRequestBody defines body for LoginMfa for application/json ContentType.
+type LoginMfaJSONRequestBody = LoginMfaParam
+
+// DeleteMyProfileJSONRequestBody defines body for DeleteMyProfile for application/json ContentType.
+type DeleteMyProfileJSONRequestBody = DeleteMyProfileParam
+
+// ConfirmTotpJSONRequestBody defines body for ConfirmTotp for application/json ContentType.
+type ConfirmTotpJSONRequestBody = ConfirmTotpParam
+
+// UploadMyAvatarMultipartRequestBody defines body for UploadMyAvatar for multipart/form-data ContentType.
+type UploadMyAvatarMultipartRequestBody UploadMyAvatarMultipartBody
+
+// ChangePasswordJSONRequestBody defines body for ChangePassword for application/json ContentType.
+type ChangePasswordJSONRequestBody = ChangePasswordParam
+
+// ConfirmPhoneNumberChangeJSONRequestBody defines body for ConfirmPhoneNumberChange for application/json ContentType.
+type ConfirmPhoneNumberChangeJSONRequestBody = PhoneNumberChangeConfirmParam
+
+// ConfirmPasswordResetJSONRequestBody defines body for ConfirmPasswordReset for application/json ContentType.
+type ConfirmPasswordResetJSONRequestBody = PasswordResetConfirmParam
+
+// RequestPasswordResetJSONRequestBody defines body for RequestPasswordReset for application/json ContentType.
+type RequestPasswordResetJSONRequestBody = PasswordResetRequestParam
+
+// ConfirmPhoneVerificationJSONRequestBody defines body for ConfirmPhoneVerification for application/json ContentType.
+type ConfirmPhoneVerificationJSONRequestBody = PhoneVerificationConfirmParam
+
+// RequestPhoneVerificationJSONRequestBody defines body for RequestPhoneVerification for application/json ContentType.
+type RequestPhoneVerificationJSONRequestBody = PhoneVerificationRequestParam
+
 // RegistrationJSONRequestBody defines body for Registration for application/json ContentType.
 type RegistrationJSONRequestBody = RegistrationParam
 
+// RefreshTokenJSONRequestBody defines body for RefreshToken for application/json ContentType.
+type RefreshTokenJSONRequestBody = RefreshTokenParam
+
 // UpdateProfileJSONRequestBody defines body for UpdateProfile for application/json ContentType.
 type UpdateProfileJSONRequestBody = UpdateProfileParam
 
+// FinishWebauthnLoginJSONRequestBody defines body for FinishWebauthnLogin for application/json ContentType.
+type FinishWebauthnLoginJSONRequestBody = WebauthnCredential
+
+// FinishWebauthnRegistrationJSONRequestBody defines body for FinishWebauthnRegistration for application/json ContentType.
+type FinishWebauthnRegistrationJSONRequestBody = WebauthnRegistrationParam
+
+// Getter for additional properties for WebauthnCredential. Returns the specified
+// element and whether it was found
+func (a WebauthnCredential) Get(fieldName string) (value interface{}, found bool) {
+	if a.AdditionalProperties != nil {
+		value, found = a.AdditionalProperties[fieldName]
+	}
+	return
+}
+
+// Setter for additional properties for WebauthnCredential
+func (a *WebauthnCredential) Set(fieldName string, value interface{}) {
+	if a.AdditionalProperties == nil {
+		a.AdditionalProperties = make(map[string]interface{})
+	}
+	a.AdditionalProperties[fieldName] = value
+}
+
+// Override default JSON handling for WebauthnCredential to handle AdditionalProperties
+func (a *WebauthnCredential) UnmarshalJSON(b []byte) error {
+	object := make(map[string]json.RawMessage)
+	err := json.Unmarshal(b, &object)
+	if err != nil {
+		return err
+	}
+
+	if raw, found := object["id"]; found {
+		err = json.Unmarshal(raw, &a.Id)
+		if err != nil {
+			return fmt.Errorf("error reading 'id': %w", err)
+		}
+		delete(object, "id")
+	}
+
+	if raw, found := object["rawId"]; found {
+		err = json.Unmarshal(raw, &a.RawId)
+		if err != nil {
+			return fmt.Errorf("error reading 'rawId': %w", err)
+		}
+		delete(object, "rawId")
+	}
+
+	if raw, found := object["response"]; found {
+		err = json.Unmarshal(raw, &a.Response)
+		if err != nil {
+			return fmt.Errorf("error reading 'response': %w", err)
+		}
+		delete(object, "response")
+	}
+
+	if raw, found := object["type"]; found {
+		err = json.Unmarshal(raw, &a.Type)
+		if err != nil {
+			return fmt.Errorf("error reading 'type': %w", err)
+		}
+		delete(object, "type")
+	}
+
+	if len(object) != 0 {
+		a.AdditionalProperties = make(map[string]interface{})
+		for fieldName, fieldBuf := range object {
+			var fieldVal interface{}
+			err := json.Unmarshal(fieldBuf, &fieldVal)
+			if err != nil {
+				return fmt.Errorf("error unmarshaling field %s: %w", fieldName, err)
+			}
+			a.AdditionalProperties[fieldName] = fieldVal
+		}
+	}
+	return nil
+}
+
+// Override default JSON handling for WebauthnCredential to handle AdditionalProperties
+func (a WebauthnCredential) MarshalJSON() ([]byte, error) {
+	var err error
+	object := make(map[string]json.RawMessage)
+
+	object["id"], err = json.Marshal(a.Id)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling 'id': %w", err)
+	}
+
+	object["rawId"], err = json.Marshal(a.RawId)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling 'rawId': %w", err)
+	}
+
+	object["response"], err = json.Marshal(a.Response)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling 'response': %w", err)
+	}
+
+	object["type"], err = json.Marshal(a.Type)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling 'type': %w", err)
+	}
+
+	for fieldName, field := range a.AdditionalProperties {
+		object[fieldName], err = json.Marshal(field)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling '%s': %w", fieldName, err)
+		}
+	}
+	return json.Marshal(object)
+}
+
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// This is the public keys endpoint, used to verify the issued tokens.
+	// (GET /.well-known/jwks.json)
+	Jwks(ctx echo.Context) error
+	// This is restore account endpoint, it undoes the deletion of a not yet purged account.
+	// (POST /admin/users/{id}/restore)
+	RestoreUser(ctx echo.Context, id int) error
 	// This is login endpoint.
 	// (POST /login)
 	Login(ctx echo.Context) error
+	// This is second login step endpoint, it exchanges the MFA challenge and a TOTP or recovery code for the tokens.
+	// (POST /login/mfa)
+	LoginMfa(ctx echo.Context) error
+	// This is logout endpoint, it revokes the current session.
+	// (POST /logout)
+	Logout(ctx echo.Context) error
+	// This is logout endpoint, it revokes every session of the user.
+	// (POST /logout-all)
+	LogoutAll(ctx echo.Context) error
+	// This is delete account endpoint, the password must be confirmed.
+	// (DELETE /my-profile)
+	DeleteMyProfile(ctx echo.Context) error
 	// This is my profile endpoint.
 	// (GET /my-profile)
 	MyProfile(ctx echo.Context) error
+	// This is enroll authenticator app endpoint, it generates the TOTP secret to confirm with /my-profile/2fa/totp/confirm.
+	// (POST /my-profile/2fa/totp)
+	EnrollTotp(ctx echo.Context) error
+	// This is enable two-factor authentication endpoint, it confirms the enrollment with a first TOTP code.
+	// (POST /my-profile/2fa/totp/confirm)
+	ConfirmTotp(ctx echo.Context) error
+	// This is upload avatar endpoint, a JPEG or PNG image replaces the avatar of the user.
+	// (PUT /my-profile/avatar)
+	UploadMyAvatar(ctx echo.Context) error
+	// This is login history endpoint, most recent first.
+	// (GET /my-profile/logins)
+	ListMyLogins(ctx echo.Context, params ListMyLoginsParams) error
+	// This is list passkeys endpoint.
+	// (GET /my-profile/passkeys)
+	ListMyPasskeys(ctx echo.Context) error
+	// This is delete passkey endpoint.
+	// (DELETE /my-profile/passkeys/{id})
+	DeleteMyPasskey(ctx echo.Context, id int) error
+	// This is change password endpoint.
+	// (PUT /my-profile/password)
+	ChangePassword(ctx echo.Context) error
+	// This is confirm phone number change endpoint, the pending phone number takes effect given its one time password.
+	// (POST /my-profile/phone-number/confirm)
+	ConfirmPhoneNumberChange(ctx echo.Context) error
+	// This is reset password endpoint, it sets a new password given the one time password.
+	// (POST /password-reset/confirm)
+	ConfirmPasswordReset(ctx echo.Context) error
+	// This is forgot password endpoint, it sends a one time password by SMS.
+	// (POST /password-reset/request)
+	RequestPasswordReset(ctx echo.Context) error
+	// This is verify phone number endpoint, it verifies the phone number of a new account given the one time password.
+	// (POST /phone-verification/confirm)
+	ConfirmPhoneVerification(ctx echo.Context) error
+	// This is resend verification endpoint, it sends a new one time password by SMS.
+	// (POST /phone-verification/request)
+	RequestPhoneVerification(ctx echo.Context) error
 	// This is registration endpoint.
 	// (POST /registration)
 	Registration(ctx echo.Context) error
+	// This is refresh token endpoint.
+	// (POST /token/refresh)
+	RefreshToken(ctx echo.Context) error
 	// This is update profile endpoint.
 	// (PATCH /update-profile)
 	UpdateProfile(ctx echo.Context) error
+	// This is begin passkey login endpoint, it returns the options to pass to navigator.credentials.get().
+	// (POST /webauthn/login/begin)
+	BeginWebauthnLogin(ctx echo.Context) error
+	// This is finish passkey login endpoint, it exchanges the assertion of the authenticator for the tokens.
+	// (POST /webauthn/login/finish)
+	FinishWebauthnLogin(ctx echo.Context) error
+	// This is begin passkey registration endpoint, it returns the options to pass to navigator.credentials.create().
+	// (POST /webauthn/register/begin)
+	BeginWebauthnRegistration(ctx echo.Context) error
+	// This is finish passkey registration endpoint, it stores the credential created by the authenticator.
+	// (POST /webauthn/register/finish)
+	FinishWebauthnRegistration(ctx echo.Context) error
 }
 
 // ServerInterfaceWrapper converts echo contexts to parameters.
@@ -111,6 +561,33 @@ type ServerInterfaceWrapper struct {
 	Handler ServerInterface
 }
 
+// Jwks converts echo context to params.
+func (w *ServerInterfaceWrapper) Jwks(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.Jwks(ctx)
+	return err
+}
+
+// RestoreUser converts echo context to params.
+func (w *ServerInterfaceWrapper) RestoreUser(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id int
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	ctx.Set(AdminApiKeyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.RestoreUser(ctx, id)
+	return err
+}
+
 // Login converts echo context to params.
 func (w *ServerInterfaceWrapper) Login(ctx echo.Context) error {
 	var err error
@@ -120,6 +597,48 @@ func (w *ServerInterfaceWrapper) Login(ctx echo.Context) error {
 	return err
 }
 
+// LoginMfa converts echo context to params.
+func (w *ServerInterfaceWrapper) LoginMfa(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.LoginMfa(ctx)
+	return err
+}
+
+// Logout converts echo context to params.
+func (w *ServerInterfaceWrapper) Logout(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.Logout(ctx)
+	return err
+}
+
+// LogoutAll converts echo context to params.
+func (w *ServerInterfaceWrapper) LogoutAll(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.LogoutAll(ctx)
+	return err
+}
+
+// DeleteMyProfile converts echo context to params.
+func (w *ServerInterfaceWrapper) DeleteMyProfile(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.DeleteMyProfile(ctx)
+	return err
+}
+
 // MyProfile converts echo context to params.
 func (w *ServerInterfaceWrapper) MyProfile(ctx echo.Context) error {
 	var err error
@@ -131,6 +650,153 @@ func (w *ServerInterfaceWrapper) MyProfile(ctx echo.Context) error {
 	return err
 }
 
+// EnrollTotp converts echo context to params.
+func (w *ServerInterfaceWrapper) EnrollTotp(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.EnrollTotp(ctx)
+	return err
+}
+
+// ConfirmTotp converts echo context to params.
+func (w *ServerInterfaceWrapper) ConfirmTotp(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ConfirmTotp(ctx)
+	return err
+}
+
+// UploadMyAvatar converts echo context to params.
+func (w *ServerInterfaceWrapper) UploadMyAvatar(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.UploadMyAvatar(ctx)
+	return err
+}
+
+// ListMyLogins converts echo context to params.
+func (w *ServerInterfaceWrapper) ListMyLogins(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListMyLoginsParams
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// ------------- Optional query parameter "cursor" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "cursor", ctx.QueryParams(), &params.Cursor)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter cursor: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ListMyLogins(ctx, params)
+	return err
+}
+
+// ListMyPasskeys converts echo context to params.
+func (w *ServerInterfaceWrapper) ListMyPasskeys(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ListMyPasskeys(ctx)
+	return err
+}
+
+// DeleteMyPasskey converts echo context to params.
+func (w *ServerInterfaceWrapper) DeleteMyPasskey(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id int
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.DeleteMyPasskey(ctx, id)
+	return err
+}
+
+// ChangePassword converts echo context to params.
+func (w *ServerInterfaceWrapper) ChangePassword(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ChangePassword(ctx)
+	return err
+}
+
+// ConfirmPhoneNumberChange converts echo context to params.
+func (w *ServerInterfaceWrapper) ConfirmPhoneNumberChange(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ConfirmPhoneNumberChange(ctx)
+	return err
+}
+
+// ConfirmPasswordReset converts echo context to params.
+func (w *ServerInterfaceWrapper) ConfirmPasswordReset(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ConfirmPasswordReset(ctx)
+	return err
+}
+
+// RequestPasswordReset converts echo context to params.
+func (w *ServerInterfaceWrapper) RequestPasswordReset(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.RequestPasswordReset(ctx)
+	return err
+}
+
+// ConfirmPhoneVerification converts echo context to params.
+func (w *ServerInterfaceWrapper) ConfirmPhoneVerification(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ConfirmPhoneVerification(ctx)
+	return err
+}
+
+// RequestPhoneVerification converts echo context to params.
+func (w *ServerInterfaceWrapper) RequestPhoneVerification(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.RequestPhoneVerification(ctx)
+	return err
+}
+
 // Registration converts echo context to params.
 func (w *ServerInterfaceWrapper) Registration(ctx echo.Context) error {
 	var err error
@@ -140,6 +806,15 @@ func (w *ServerInterfaceWrapper) Registration(ctx echo.Context) error {
 	return err
 }
 
+// RefreshToken converts echo context to params.
+func (w *ServerInterfaceWrapper) RefreshToken(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.RefreshToken(ctx)
+	return err
+}
+
 // UpdateProfile converts echo context to params.
 func (w *ServerInterfaceWrapper) UpdateProfile(ctx echo.Context) error {
 	var err error
@@ -151,6 +826,46 @@ func (w *ServerInterfaceWrapper) UpdateProfile(ctx echo.Context) error {
 	return err
 }
 
+// BeginWebauthnLogin converts echo context to params.
+func (w *ServerInterfaceWrapper) BeginWebauthnLogin(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.BeginWebauthnLogin(ctx)
+	return err
+}
+
+// FinishWebauthnLogin converts echo context to params.
+func (w *ServerInterfaceWrapper) FinishWebauthnLogin(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.FinishWebauthnLogin(ctx)
+	return err
+}
+
+// BeginWebauthnRegistration converts echo context to params.
+func (w *ServerInterfaceWrapper) BeginWebauthnRegistration(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.BeginWebauthnRegistration(ctx)
+	return err
+}
+
+// FinishWebauthnRegistration converts echo context to params.
+func (w *ServerInterfaceWrapper) FinishWebauthnRegistration(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.FinishWebauthnRegistration(ctx)
+	return err
+}
+
 // This is a simple interface which specifies echo.Route addition functions which
 // are present on both echo.Echo and echo.Group, since we want to allow using
 // either of them for path registration
@@ -179,30 +894,111 @@ func RegisterHandlersWithBaseURL(router EchoRouter, si ServerInterface, baseURL
 		Handler: si,
 	}
 
+	router.GET(baseURL+"/.well-known/jwks.json", wrapper.Jwks)
+	router.POST(baseURL+"/admin/users/:id/restore", wrapper.RestoreUser)
 	router.POST(baseURL+"/login", wrapper.Login)
+	router.POST(baseURL+"/login/mfa", wrapper.LoginMfa)
+	router.POST(baseURL+"/logout", wrapper.Logout)
+	router.POST(baseURL+"/logout-all", wrapper.LogoutAll)
+	router.DELETE(baseURL+"/my-profile", wrapper.DeleteMyProfile)
 	router.GET(baseURL+"/my-profile", wrapper.MyProfile)
+	router.POST(baseURL+"/my-profile/2fa/totp", wrapper.EnrollTotp)
+	router.POST(baseURL+"/my-profile/2fa/totp/confirm", wrapper.ConfirmTotp)
+	router.PUT(baseURL+"/my-profile/avatar", wrapper.UploadMyAvatar)
+	router.GET(baseURL+"/my-profile/logins", wrapper.ListMyLogins)
+	router.GET(baseURL+"/my-profile/passkeys", wrapper.ListMyPasskeys)
+	router.DELETE(baseURL+"/my-profile/passkeys/:id", wrapper.DeleteMyPasskey)
+	router.PUT(baseURL+"/my-profile/password", wrapper.ChangePassword)
+	router.POST(baseURL+"/my-profile/phone-number/confirm", wrapper.ConfirmPhoneNumberChange)
+	router.POST(baseURL+"/password-reset/confirm", wrapper.ConfirmPasswordReset)
+	router.POST(baseURL+"/password-reset/request", wrapper.RequestPasswordReset)
+	router.POST(baseURL+"/phone-verification/confirm", wrapper.ConfirmPhoneVerification)
+	router.POST(baseURL+"/phone-verification/request", wrapper.RequestPhoneVerification)
 	router.POST(baseURL+"/registration", wrapper.Registration)
+	router.POST(baseURL+"/token/refresh", wrapper.RefreshToken)
 	router.PATCH(baseURL+"/update-profile", wrapper.UpdateProfile)
+	router.POST(baseURL+"/webauthn/login/begin", wrapper.BeginWebauthnLogin)
+	router.POST(baseURL+"/webauthn/login/finish", wrapper.FinishWebauthnLogin)
+	router.POST(baseURL+"/webauthn/register/begin", wrapper.BeginWebauthnRegistration)
+	router.POST(baseURL+"/webauthn/register/finish", wrapper.FinishWebauthnRegistration)
 
 }
 
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/8RWTW+jSBD9K6h2b8saf6yiXU6bbDZSRnEU5UNziHxoQ9l0Brp7qptMkMV/H3Xj2GDj",
-	"j0S252QM1VXFq/fqMYNIZkoKFEZDOAMdJZgxd3kjp1z8TyTpHrWSQqO9q0gqJMPRxWSoNZu6B6ZQCCFo",
-	"Q1xMoSx9IPyec8IYwudF4Mh/D5TjF4wMlH5V6I4Ry9YLKKb1D0mxvcY3lqnUns2Kf+/mD3rgr5b2QSVS",
-	"4G2ejZGaB/846//d7w8Gg36/v35wped6Fn/ZycZX2AwTj2sIcWFwimSPGfkNxW7weAzvsW3Fh8UdyQlP",
-	"8RTDWhTbXEewrK3I2li2N+GyNM+09XOPU64NMcPlSchar7eBs5M8TW/nGCypd04mycm7RGHAh4y93aCY",
-	"mgTCs64PGRfvfwdtfN5HBR9MuadEall7gx1Zt+pngcoOKdUB/qii1nXTVuFJxczgCTXTKPgh0gyLqyVs",
-	"B1xz21s8BuylDxqjnLgpHqzHVPkukBHSeW75NIOx+3clKWMGQvjy9RH8ypFspurp8nUSYxSUNjEXE2nP",
-	"pzzCedvVDoLh9aPbs9w4XJ40kveA9MojC+grkuZSQAi9TrfTtZFSoWCKQwgDd8uS1SSu1yC1S95hIrWx",
-	"vxYZx9PrGMLKA6CCArW5kHFhgyIpjNV8OAOmVMojdyJ40VIsDdde/U44gRB+C5aOHMztOKhZZNmE21CO",
-	"7kY1Mtdpv9s9bOUFIVzxGHVEXJkKOhfgEZqchEXwr0MXbyq0pYMLFnv3Fei6IlqeZYwKCOEx4drj2nOj",
-	"81DESnJhOi4qyIo/VUV428cUW2a6cDs4IsTrltryksPCm0c1sB4cvoudeF9JGvM4RuFFMsZOQ9sQPjdV",
-	"/TwqR20TyQpvDv7qWKhmAJvVVreJI4lu3er30l7vKA1sG0g97khK3PyZ9TlB1oe8SoDceVFdm4qZKFnn",
-	"QMO0jkSCFu8+8QZut+YW2KvAI6+JLR9P+6wK28w/v6iZ/6SYpDwy3iUz7HNbq6Jm6+Zy6ch+UrhsOaXz",
-	"b5QwCFIZsTSxe6wclT8DAAD//8WT++9zDwAA",
+	"H4sIAAAAAAAC/+xceZPbuHL/KijmVSWppUZz2VnPX0++XnyMPRmP41Ttc1wQ2ZKwQwJcABxZu6XvnsJB",
+	"EiBBStaO5HFl/7JHwtFo/LobfemPKGF5wShQKaKLPyIOomBUgP7jKU6v4bcShFR/JYxKoPq/uCgykmBJ",
+	"GB0XnE0zyH/6VTCqvhPJAnKs/vc3DrPoIvqXcbPF2HwrxldmVrRer+MoBZFwUqjlogu1K7LbimgdR88Y",
+	"nWUkOSgN1Z7oOZZYEfGS8SlJU6CHpKLZdB1Hr6gETnH2Afgd8BecM35IWqrtkdkfGQLWcfSOyZespOkh",
+	"iXnHJDKbruPohrFLTFc1ZA5Ixw1jSO1d4zVGHCRfITyTwJFcALpWf48m+u8UMryK4mgBOAWuKXW+Vn/6",
+	"y3+AhNFUIMnQEhOJpjBjHPSyFL5KhKWEvJBR7JxErgqILiJCJcyBK6LXcfSR4lIuGCe/w0Hvyd0XJSyF",
+	"I//0nz59Gk1KuQAqFQ3gb2qPIiQndK5Psq5OqmdP7rDE/NpqLPVJwVkBXBKjvrD+/mZR5lOKSSY2HWTS",
+	"Hr+O7RofeRYiKI44/FYSrpj6izM07m79Oa5ms+mvkEi19iRAH05TopiHsyvvLK2tO4y+fisQm2loiN9K",
+	"rFFSrau+wBSVRcZwCikyxMXoFlaQoulKzSIcCfI7IEJRQb5CJqIAwc8WmM7hCguxZDy9whznXa4nJedA",
+	"ZTVKfZQT+hboXC6ii5O4exQKS3f0INaqcW3mt3f1Vw1xX6l4wvMbJou+k7AUNhH0/uaqS4uaF9ryOWQg",
+	"4XJ1xdmMZNCzbbEt51rbFkOnfUkgS2uj4W84U9+p/8BXnBeZmlgsGIV3ZT4FHgVuLAch8ByCwORlBv5i",
+	"zSE2HcFQYtdotgkeqMyydzhvbTXhclFy9ByoVPPx14p7j49jl5lngUO9/vQmoEOyub/Di/T5h0mIJwm/",
+	"a488ffTo5ElobJhztyQNfy5X/srv31yFVqXB2aVo8UiQeWj21+Ds1Wa9p8gzxJvNYs200J29/vTmQ7+6",
+	"voWV/pdIyDfqanVZ63oLzDledQlTC4boeMvmhL64sxawJfQcsIR0or+aMZ5jGV1EKZYwkiSHEOdmmGQl",
+	"h2vA1m4CLXNFAKF3OCPpl6LRSjhJWEnll4wlt6A+0JL2hTL55Q44mRH9YTUxn+EvLW3SbEtSj0RC5ePz",
+	"KO48AOKIFJM05SDChkSUSeJ/N2UsA0wtePhkbhk1DAN9/9Vi7qbuKrHD396b+U8iJOOrfqTUCNkKKs5t",
+	"dxATR+oh9azkwijGDUfU2/XSfTnDGyyJo5DOTj2F9DikZWf4ht0al+NbDEE9L+43RZrgLQyQo8VXf6/s",
+	"6UlIClyT4U386fHpz6enZ2dnp6enGy2Ab3gGTZo+Qj9I4GtBOIhXNPQqrqSn+zmHGQexqBnfOafs+SYk",
+	"DtJeg7do7JAWOtflDD9b4CwDOoedj5fP8HVNTUiyXXBthFO9VOyCa8MxqmfO93ugx7toc/Xl+9lTwuWi",
+	"Myk0HnJMwpvrb/670urBW+hDYcYSnIVfCZmC/TNlRLoOoxEc9d63inhWZkhPEFG8jamg9kW1SbrD3w+f",
+	"VTH8d0bD65dF+m0XFRI3TX3c1iEeZR7/XHy4JITgrB76nlo7OT07f/RYKykpgSv2/+8vx6Mnn/94vP5b",
+	"CChKe97C6l6eHL24wUJ+FN+2Vs+dDzB42Ibbc74lQt6XCa9Yt+nF12+gr3ayadWX1yBAWoexx2ju5MTG",
+	"EZPFVi5mRwIHN3CGDhtYtf1mV9ljgw119b0d7p/KIEVbPjacB9fJmffgOjn2JPef//zp8akW35/Cwtts",
+	"aKIgw2jY9lpbx1bTeo9rdJgJ1d3P7nsE1VaneBhQsuHL3kd7Ay7tlGnKvyifT5uTQGBO9j4IOGd8e63n",
+	"xGwCjguhQmKatEgcc5gTIbmmMkQeNzx/FQ41CIllKbwlz4+PQy8FSWQ7zuMkbkI7mw88Ym3AWYy34Gzr",
+	"WvW3FRk14dbjcY8ZuvNrSNgd8NUzloLoN1LcHeZdW8/hesySv06YoMZB6BGHDY5JZ0tn9KYdd/Yy7stZ",
+	"2slPunaQ3sOzmRMkHJS0atw69rzfba34XtRoTfwGP1hFsF9QzrIsBzrw5GKyUNmYj5yERR8SDlvEeey4",
+	"2F0vRNZH/Zxuh7pzQt3ExkkcdAmtO9doioWUhbgYj+0nRwnLx2boUUHnvqE/PT7/ebNf5zzkn/zHz6Pj",
+	"s9Hxz66PtNHdaxbAOuj8d4e2FkGPzgML7YLNxi1sdifp6NVzf8OzR5vjM1tj1HfcnFC7IHj8Gt9iLnEr",
+	"1n4eUt7DABnwFHpcnQJoSuj8atAx7ToxIah+gqlCMn3GIQUqCc76U3GSlxAPkeiYWrzsMbPcOe3QNh1K",
+	"Kxu6ha9mdo8rQ1lvOcSA9zqQIAJvsXKakeQNrL6R4raCq5cZomILxZ54NzWE5cDdOj5vf4ZoY5S18YWr",
+	"dT+Hji8gKTmRqw+KHkP8JM0JnRTEspPQ6MImxSsX+yL6n5EeNZoUZKTG1UtjM28dR08Bc+Aqfa5Wmeq/",
+	"Xlbq6/Wnm6osQM0y3zarKH1qEvaEzpian5EELCItCZevbpyHXvRRANfFHyRR574DLkzQ6eTo+OhYjWQF",
+	"UFyQ6CI60x9pB2uhjzw+WkKWjW4pW9Lxr8tbcVTVGMyNzVGXqy9cSUz0enkrHMzqJU6PjwdKF76tZMFL",
+	"RAXqFl5/eP8OfYIpegMr9AEk4iBLrqNYj46P+1avyR2HanU0GMo8x3wVXUQ3CyIQETphb6RCpeMFApoW",
+	"jFAZo1JAiiRDOi2kc/SICFHqD2+BiiO94hgrmIxLAVyM/yDpWlEhGTeKlIkAc6/NAHWf+oo4zkECF9HF",
+	"LxaM6toaKBptUiPfyHnD55xQkpe5KzFN8cnnziWed6OWE5MTQ5Zy/aI6Pz7ZzGavrEVPOt88qa5X0hOe",
+	"bJ5Q15/96du3qkAz2lMCv3xefw6hw3IE2ayhgw4iUUlTBgZBKWSgmKkrPRBlEq1AoqLkc0iryRYvOvzp",
+	"gsO/i08LoEgu2WiGE8k4wk11jlqfKITiaQZpjDBFly8nKKlyFYZgJSeQIkKFBJxWNSkGsjESAMiQMM5n",
+	"+AhNKCqp1gpIv08QNRFsTFOE0ZIzOkfV4xdhDmjK5AJhKpbAIUVLIhfo/PgEVQnTRhuLGAGRC+CKLJNt",
+	"tcNPnyA/C1tVazGGclXMZfO5QhUr+aKjU06Nd/mUpat700lORm69Xrclbv0ntSGj8H6mgbeRhkYxxsOj",
+	"g4mq9eeAPtXrOkr0fBsxcqpPd1cIZ5sneVWW56dbaIR2xeH9mwUtIrW4u6Kr5KZft1d56H1itE5z7wGm",
+	"3wDOh4Oye757oas+LQSEhMJX+/A10QFoo/l9DWzU5s37myvEOKrCTrryEs0Yd1RxAylWykE8qe+3seJm",
+	"qEk8QvqdmOlaWPd93Gdgzfl9BnO4Y7eWvbasEAkQgjDqcW2Es2wT5yZZ9v+TeaCBZ9lWvQJKAdyyMF+N",
+	"CuP5G2ZkIKHLlptF8/ZRksFm0rx11PuDSJRgiqZQPxxVLSumSD+JUUklydQgIuxL6AhN2k8KvbRAWCCM",
+	"TODZyF3X9LdqN/ekYIMVolvp2YF3dc0x/1KIsLeVHtQkfxcD+63QNiwLvLu1v1ahJy+FVPhLTDYO0iNF",
+	"adCj9YGzJwvZLbkJWMnLFbKjPFP54LVNvkJWY7TfRY0uGZ/O8Fja9GfYwZkgGzhEUMfMjSgUGU6sX2N9",
+	"HMQoKB3zr+qWuyrBBN1vTA59b5faE94PdaMou2/C8zGSDAmlHrVq+69r8wawethG7tHH61d/4mn93Vzn",
+	"bdBiLtd1X5UzW7TeUnOg6kKtsXf4p9hnpdp4jSGQje2IfhhWI/rhqCyc904T2s2t/WhGE4gRo1nVpbHA",
+	"YqEOeAuF7ELSaWrYk4XqtE0c2AsIp25D0tAbxLDSfVhH9IFLi+LIQNzHkxoLaiM0jhbVgoLRjHAhjTCZ",
+	"lq+2dJjEnUkr9IgEyXEVUBoBVcuY6I16bxIpUA4Sp1hiY5DNgtr7UV92G6BckeoKzUfdGHW5MuWlg3KT",
+	"l5kkBeZyrBKFI0WCD912Z41539ZZxSmhivOb+2CyUKLmsJLWaq8LiJgZUTeWHVSgTs4O2ujpYjLDfK67",
+	"O5V5zTK2rEh6dGiS9GuICB3xxej11Yt/KL//6t0/DLGHVyQGCpVANlojQFz14hKuCA85imNbyNyXNFIF",
+	"p5ert1W1cyix8VsJfNVkNjKSE797NoUZLjOpigh0SttmNlQJ1GCeI24rsaatpDpTweGOsFKgQt1MHCQo",
+	"0TNC/by1ovi87whbu/umN9C2MAN/uIDbDmEO57QNpnMmpHq7AZXG5nURW5i65U2YvaqG7fFuQ1XZgaut",
+	"SHEl0RjZDEsQ9qQ/ys0RIVF1BwNeYzVEJ1D9eFRPEMhMeBjpU0tMFeY5XPb0u4Rk7F1tuM2qgC/4wHyh",
+	"nS2mk4OBCKUTGIu9IDCjgITEK4F0srEJJ1YDdggr+i31+/LZAn37uwYVr+oz6kX/ih12gGoY04BhAKkL",
+	"RmFkUt6bQwUv+kLqHcCaFA9aAq9rRqrsD8tSL9PeG0LoNDzsCZvDjRU7o9Q5YoXUWMkgIhThOSb0L++/",
+	"AawNdBVdprUj3zZ06o2UWCd8ZjNIJJqTO6DaC1cjJMkbQbD4r/4ccRAg7wf2/Sh2O5f2heDeJrE/rWM1",
+	"i3ZE6j1npTUpXaWmA0IClLFDFJbNAAMErXK2AwJvfmlsKFRqKK9K5wTOAS0XoG25+sBDpiZb1ZLqYiHG",
+	"EWWBmGndhXRgqHjdT1tB5TSUzuiw19QQUInIbANLdtWBD6NGZsb4nPVDkqYKk13uTFfow+WHCoPaAt85",
+	"PWkhhdRvIN1utn0ayN7ev3sxkPUvjzwITWMLXj3YendryRVddJvyR1jWidsttFAXAfvWRLGOVFdVmhXz",
+	"440K6nsB7vsrqiDDfmztxUGpKOQCL6zBFJw3aDGv53Mg891/A2a5Vrm55yegnUCPMw44XXXNcGzeiEtq",
+	"xknll1gibOlwSA681tZ9YL/berIr3g27mtU0N5IECll5afoNm7G5UD4JowkEOfiwdLN3opZjq73OsW3h",
+	"HGo/8Ho893OL7W7agyepA821gWirHYf0wB+/ctXevYk/tOFhfl7FrforsEwWIT2lHYmWHFSup1vWV9dd",
+	"mdRz0Mwb/SaZmmKVJpr4qzvKSRcQmsigU3dY9xvghg7JmK3L6CiyUIbZaXXcE+gD/bYHRn24oTP0s7B6",
+	"YLAM7VABmX03Cv0YoUojk311dUvbOmkbD6bQ6hzyMf5UfV11WzaNMnvCWrtjNZTKqppNm9ZPy2k7KUZL",
+	"jotCdyuhpjX1/vWi5lydufB7O2zVtJIA86ZihjilstQM9S/Fd2SOJeNHTn/T0Rzkv/17+KpmhBLfBneK",
+	"IC0xSrtVHQe29IfRSqXFiLL+Hq+umnupt+1i4P6VXair969ulB17nu47LqNRMAR3v4UFCwFcOsFdv2gz",
+	"2LdS472y3d+knTqOxINSUs846M02aqkfIAPva76g97C7AjQ/Q9fVgTUmumpwSF8dwL3s/4WDrbTXyX1X",
+	"gwxUgNxDmPjhw7OlqvrxKSTjVUtYjUBkfwfR/oS+r7eODG+FJtEUh5Q8sz+9cDEe659yWTDFqs/r/xsA",
+	"t1RuOYZlAAA=",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
@@ -0,0 +1,359 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// DefaultAvatarMaxBytes is the largest avatar file accepted when not configured
+	DefaultAvatarMaxBytes = 5 << 20
+	// larger images are rejected before being decoded, a small file can hold a huge image
+	maxAvatarDimension = 4096
+	// the stored avatar is scaled down to fit in this square
+	avatarSize = 1024
+	// room for the multipart headers around the file
+	multipartOverhead = 64 << 10
+)
+
+// the square thumbnails made of every avatar
+var avatarThumbnailSizes = []int{64, 128, 256}
+
+var (
+	errUnsupportedImage = errors.New("the avatar must be a JPEG or PNG image")
+	errImageTooLarge    = fmt.Errorf("the avatar must be at most %dx%d pixels", maxAvatarDimension, maxAvatarDimension)
+)
+
+// processedAvatar is the re-encoded avatar and its thumbnails keyed by size,
+// in the format of the upload. Re-encoding drops the metadata, EXIF included.
+type processedAvatar struct {
+	contentType string
+	original    []byte
+	thumbnails  map[int][]byte
+}
+
+// (PUT /my-profile/avatar)
+func (s *Server) UploadMyAvatar(ctx echo.Context) error {
+	principal, ok := principalFromContext(ctx)
+	if !ok {
+		return unauthorized(errMissingToken)
+	}
+	if s.BlobStore == nil {
+		return internalError(errors.New("no blob store is configured for the avatars"))
+	}
+
+	maxBytes := s.avatarMaxBytes()
+	req := ctx.Request()
+	req.Body = http.MaxBytesReader(ctx.Response(), req.Body, maxBytes+multipartOverhead)
+
+	file, header, err := req.FormFile("file")
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return avatarTooLarge(maxBytes)
+	}
+	if err != nil {
+		return validationProblem([]generated.FieldError{{Field: "file", Rule: "required", Message: "a multipart file is required"}})
+	}
+	defer file.Close()
+	if header.Size > maxBytes {
+		return avatarTooLarge(maxBytes)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		return internalError(err)
+	}
+	if int64(len(content)) > maxBytes {
+		return avatarTooLarge(maxBytes)
+	}
+
+	avatar, err := processAvatar(content)
+	if errors.Is(err, errUnsupportedImage) {
+		return newProblem(http.StatusUnsupportedMediaType, ErrCodeUnsupportedMediaType, err.Error())
+	}
+	if errors.Is(err, errImageTooLarge) {
+		return validationProblem([]generated.FieldError{{Field: "file", Rule: "dimensions", Message: err.Error()}})
+	}
+	if err != nil {
+		return validationProblem([]generated.FieldError{{Field: "file", Rule: "format", Message: "the image can't be decoded"}})
+	}
+
+	// the keys are reused by every upload, the version in the URLs busts the caches
+	reqCtx := req.Context()
+	version := strconv.FormatInt(time.Now().Unix(), 10)
+
+	thumbnails := make(map[string]string, len(avatar.thumbnails))
+	for size, thumbnail := range avatar.thumbnails {
+		key := avatarKey(principal.UserId, strconv.Itoa(size))
+		if err := s.BlobStore.Put(reqCtx, key, thumbnail, avatar.contentType); err != nil {
+			return internalError(err)
+		}
+		thumbnails[strconv.Itoa(size)] = s.BlobStore.URL(key) + "?v=" + version
+	}
+	key := avatarKey(principal.UserId, "original")
+	if err := s.BlobStore.Put(reqCtx, key, avatar.original, avatar.contentType); err != nil {
+		return internalError(err)
+	}
+	avatarUrl := s.BlobStore.URL(key) + "?v=" + version
+
+	res, err := s.Repository.UpdateUserById(reqCtx, repository.UpdateUserByIdInput{
+		Id:               principal.UserId,
+		AvatarUrl:        &avatarUrl,
+		AvatarThumbnails: thumbnails,
+	})
+	if err != nil {
+		return internalError(err)
+	}
+	if !res.Updated {
+		return unauthorized(errors.New("user no longer exists"))
+	}
+
+	return ctx.JSON(http.StatusOK, generated.AvatarResponse{
+		AvatarUrl:        avatarUrl,
+		AvatarThumbnails: thumbnails,
+	})
+}
+
+// avatarKey is the key of the original avatar of the user, or of the thumbnail of the size
+func avatarKey(userId int, name string) string {
+	return fmt.Sprintf("avatars/%d/%s", userId, name)
+}
+
+// deleteAvatar deletes the files of the avatar of the user, whether there is one or not.
+func (s *Server) deleteAvatar(ctx context.Context, userId int) error {
+	names := []string{"original"}
+	for _, size := range avatarThumbnailSizes {
+		names = append(names, strconv.Itoa(size))
+	}
+	for _, name := range names {
+		if err := s.BlobStore.Delete(ctx, avatarKey(userId, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) avatarMaxBytes() int64 {
+	if s.AvatarMaxBytes > 0 {
+		return s.AvatarMaxBytes
+	}
+	return DefaultAvatarMaxBytes
+}
+
+func avatarTooLarge(maxBytes int64) *Problem {
+	return newProblem(http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge, fmt.Sprintf("the avatar must be at most %d bytes", maxBytes))
+}
+
+// processAvatar decodes a JPEG or PNG image, turns it upright and re-encodes
+// it scaled down with its square thumbnails.
+func processAvatar(content []byte) (*processedAvatar, error) {
+	contentType := http.DetectContentType(content)
+	if contentType != "image/jpeg" && contentType != "image/png" {
+		return nil, errUnsupportedImage
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width > maxAvatarDimension || config.Height > maxAvatarDimension {
+		return nil, errImageTooLarge
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewRGBA(image.Rect(0, 0, decoded.Bounds().Dx(), decoded.Bounds().Dy()))
+	draw.Draw(img, img.Bounds(), decoded, decoded.Bounds().Min, draw.Src)
+	if contentType == "image/jpeg" {
+		img = applyOrientation(img, jpegOrientation(content))
+	}
+
+	avatar := &processedAvatar{contentType: contentType, thumbnails: make(map[int][]byte, len(avatarThumbnailSizes))}
+
+	width, height := fitWithin(img.Bounds().Dx(), img.Bounds().Dy(), avatarSize)
+	avatar.original, err = encodeImage(resize(img, img.Bounds(), width, height), contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	square := centeredSquare(img.Bounds())
+	for _, size := range avatarThumbnailSizes {
+		avatar.thumbnails[size], err = encodeImage(resize(img, square, size, size), contentType)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return avatar, nil
+}
+
+func encodeImage(img image.Image, contentType string) ([]byte, error) {
+	var buffer bytes.Buffer
+	var err error
+	if contentType == "image/png" {
+		err = png.Encode(&buffer, img)
+	} else {
+		err = jpeg.Encode(&buffer, img, &jpeg.Options{Quality: 85})
+	}
+	return buffer.Bytes(), err
+}
+
+// fitWithin scales the size down to fit in a square of the given side,
+// keeping its ratio. Smaller sizes are kept.
+func fitWithin(width int, height int, side int) (int, int) {
+	if width <= side && height <= side {
+		return width, height
+	}
+	if width >= height {
+		return side, max(1, height*side/width)
+	}
+	return max(1, width*side/height), side
+}
+
+// centeredSquare is the largest square in the middle of the bounds
+func centeredSquare(bounds image.Rectangle) image.Rectangle {
+	side := min(bounds.Dx(), bounds.Dy())
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+	return image.Rect(x, y, x+side, y+side)
+}
+
+// resize scales the area of the source to the given size, every destination
+// pixel is the average of the source pixels it covers.
+func resize(src *image.RGBA, area image.Rectangle, width int, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	areaWidth, areaHeight := area.Dx(), area.Dy()
+
+	for y := 0; y < height; y++ {
+		y0 := area.Min.Y + y*areaHeight/height
+		y1 := max(area.Min.Y+(y+1)*areaHeight/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := area.Min.X + x*areaWidth/width
+			x1 := max(area.Min.X+(x+1)*areaWidth/width, x0+1)
+
+			var r, g, b, a, count int
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					offset := src.PixOffset(sx, sy)
+					r += int(src.Pix[offset])
+					g += int(src.Pix[offset+1])
+					b += int(src.Pix[offset+2])
+					a += int(src.Pix[offset+3])
+					count++
+				}
+			}
+			offset := dst.PixOffset(x, y)
+			dst.Pix[offset] = uint8(r / count)
+			dst.Pix[offset+1] = uint8(g / count)
+			dst.Pix[offset+2] = uint8(b / count)
+			dst.Pix[offset+3] = uint8(a / count)
+		}
+	}
+	return dst
+}
+
+// applyOrientation turns the image upright according to its EXIF orientation,
+// which is lost with the rest of the metadata once re-encoded.
+func applyOrientation(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored
+				dx, dy = width-1-x, y
+			case 3: // rotated 180°
+				dx, dy = width-1-x, height-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, height-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise
+				dx, dy = height-1-y, x
+			case 7: // transversed
+				dx, dy = height-1-y, width-1-x
+			case 8: // rotated 90° counterclockwise
+				dx, dy = y, width-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+	return dst
+}
+
+// jpegOrientation reads the orientation tag of the EXIF segment of a JPEG, 1
+// (upright) when there is none.
+func jpegOrientation(content []byte) int {
+	// the segments follow the SOI marker until the image data starts
+	for offset := 2; offset+4 <= len(content) && content[offset] == 0xFF; {
+		marker := content[offset+1]
+		length := int(binary.BigEndian.Uint16(content[offset+2:]))
+		if marker == 0xDA || length < 2 || offset+2+length > len(content) {
+			break
+		}
+		segment := content[offset+4 : offset+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		offset += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation looks the orientation tag (0x0112) up in the first IFD
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 0 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 1
+}
@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/blobstore"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func testImage(width int, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+// withExifOrientation inserts an EXIF segment holding the orientation right after the SOI marker
+func withExifOrientation(jpegContent []byte, orientation byte) []byte {
+	tiff := []byte{'M', 'M', 0, 42, 0, 0, 0, 8, 0, 1, 0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, orientation, 0, 0, 0, 0, 0, 0, 0, 0}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := append([]byte{0xFF, 0xE1, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}, payload...)
+	return append(append(append([]byte{}, jpegContent[:2]...), segment...), jpegContent[2:]...)
+}
+
+func newAvatarRequest(t *testing.T, content []byte) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "avatar")
+	assert.NoError(t, err)
+	_, err = part.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPut, "/my-profile/avatar", &body)
+	req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+	return req
+}
+
+func TestUploadMyAvatar_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	dir := t.TempDir()
+	server := &Server{
+		Repository:      mockRepo,
+		RevocationStore: repository.NewMemoryRevocationStore(),
+		KeyManager:      testKeyManager,
+		BlobStore:       blobstore.NewLocalStore(dir, "http://localhost:8080/blobs"),
+	}
+	e := newTestValidationEcho(t, server, ValidationOptions{ValidateResponses: true})
+
+	var buffer bytes.Buffer
+	assert.NoError(t, png.Encode(&buffer, testImage(300, 200)))
+	req := newAvatarRequest(t, buffer.Bytes())
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+generateTestToken(t))
+	rec := httptest.NewRecorder()
+
+	var update repository.UpdateUserByIdInput
+	mockRepo.EXPECT().UpdateUserById(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ any, input repository.UpdateUserByIdInput) (repository.UpdateUserByIdOutput, error) {
+			update = input
+			return repository.UpdateUserByIdOutput{Updated: true}, nil
+		},
+	)
+
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp generated.AvatarResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.True(t, strings.HasPrefix(resp.AvatarUrl, "http://localhost:8080/blobs/avatars/1/original?v="))
+	assert.Len(t, resp.AvatarThumbnails, 3)
+	assert.Equal(t, resp.AvatarUrl, *update.AvatarUrl)
+	assert.Equal(t, map[string]string(resp.AvatarThumbnails), update.AvatarThumbnails)
+
+	// the thumbnails are square PNGs like the upload
+	thumbnail, err := os.ReadFile(filepath.Join(dir, "avatars", "1", "64"))
+	assert.NoError(t, err)
+	config, format, err := image.DecodeConfig(bytes.NewReader(thumbnail))
+	assert.NoError(t, err)
+	assert.Equal(t, "png", format)
+	assert.Equal(t, 64, config.Width)
+	assert.Equal(t, 64, config.Height)
+}
+
+func TestUploadMyAvatar_Error_UnsupportedType(t *testing.T) {
+	e := echo.New()
+	server := &Server{BlobStore: blobstore.NewLocalStore(t.TempDir(), "http://localhost:8080/blobs")}
+
+	req := newAvatarRequest(t, []byte("GIF89a not quite an image"))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(principalContextKey, newTestPrincipal())
+
+	err := server.UploadMyAvatar(c)
+
+	assertProblem(t, err, http.StatusUnsupportedMediaType, ErrCodeUnsupportedMediaType)
+}
+
+func TestUploadMyAvatar_Error_TooLarge(t *testing.T) {
+	e := echo.New()
+	server := &Server{
+		BlobStore:      blobstore.NewLocalStore(t.TempDir(), "http://localhost:8080/blobs"),
+		AvatarMaxBytes: 1024,
+	}
+
+	// the size is checked before the content
+	req := newAvatarRequest(t, make([]byte, 1025))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(principalContextKey, newTestPrincipal())
+
+	err := server.UploadMyAvatar(c)
+
+	assertProblem(t, err, http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge)
+}
+
+func TestPurgeDeletedUsers_DeletesAvatar(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	dir := t.TempDir()
+	store := blobstore.NewLocalStore(dir, "http://localhost:8080/blobs")
+	server := &Server{
+		Repository: mockRepo,
+		BlobStore:  store,
+	}
+
+	ctx := context.Background()
+	for _, userId := range []int{1, 2} {
+		for _, name := range []string{"original", "64", "128", "256"} {
+			assert.NoError(t, store.Put(ctx, avatarKey(userId, name), []byte("image"), "image/png"))
+		}
+	}
+	deletedBefore := time.Now().Add(-time.Hour)
+	mockRepo.EXPECT().PurgeDeletedUsers(gomock.Any(), repository.PurgeDeletedUsersInput{DeletedBefore: deletedBefore}).Return(
+		repository.PurgeDeletedUsersOutput{Purged: 2, Ids: []int{1, 3}},
+		nil,
+	)
+
+	purged, err := server.PurgeDeletedUsers(ctx, deletedBefore)
+
+	// a user without avatar is no error
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), purged)
+	files, err := os.ReadDir(filepath.Join(dir, "avatars", "1"))
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+	files, err = os.ReadDir(filepath.Join(dir, "avatars", "2"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 4)
+}
+
+func TestDeleteMyProfile_DeletesAvatar(t *testing.T) {
+	e := echo.New()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	dir := t.TempDir()
+	store := blobstore.NewLocalStore(dir, "http://localhost:8080/blobs")
+	server := &Server{
+		Repository:      mockRepo,
+		RevocationStore: repository.NewMemoryRevocationStore(),
+		BlobStore:       store,
+	}
+
+	ctx := context.Background()
+	for _, name := range []string{"original", "64", "128", "256"} {
+		assert.NoError(t, store.Put(ctx, avatarKey(1, name), []byte("image"), "image/png"))
+	}
+	req := httptest.NewRequest(http.MethodDelete, "/my-profile", strings.NewReader(`{"password":"my@Password1"}`))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(principalContextKey, newTestPrincipal())
+
+	mockRepo.EXPECT().GetUserCredentialsById(gomock.Any(), repository.GetUserCredentialsByIdInput{Id: 1}).Return(
+		repository.GetUserCredentialsByIdOutput{Id: 1, Password: "$2a$04$BN7qD4ROTQKOoagz6Ez5xucaSFNkKWYhT9UJF7pd4jgKvaRsLBKFW"},
+		nil,
+	)
+	mockRepo.EXPECT().SoftDeleteUser(gomock.Any(), repository.SoftDeleteUserInput{Id: 1}).Return(
+		repository.SoftDeleteUserOutput{Deleted: true},
+		nil,
+	)
+	mockRepo.EXPECT().RevokeUserRefreshTokens(gomock.Any(), gomock.Any()).Return(
+		nil,
+	)
+
+	err := server.DeleteMyProfile(c)
+
+	// the files are no longer served, they don't wait for the purge
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	files, err := os.ReadDir(filepath.Join(dir, "avatars", "1"))
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestProcessAvatar_Orientation(t *testing.T) {
+	var buffer bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buffer, testImage(40, 20), nil))
+	// rotated 90° clockwise, the upright image is taller than wide
+	content := withExifOrientation(buffer.Bytes(), 6)
+	assert.Equal(t, 6, jpegOrientation(content))
+
+	avatar, err := processAvatar(content)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "image/jpeg", avatar.contentType)
+	config, err := jpeg.DecodeConfig(bytes.NewReader(avatar.original))
+	assert.NoError(t, err)
+	assert.Equal(t, 20, config.Width)
+	assert.Equal(t, 40, config.Height)
+	// the EXIF segment is gone
+	assert.Equal(t, 1, jpegOrientation(avatar.original))
+	assert.NotContains(t, string(avatar.original), "Exif")
+}
+
+func TestProcessAvatar_Error_Dimensions(t *testing.T) {
+	var buffer bytes.Buffer
+	assert.NoError(t, png.Encode(&buffer, image.NewGray(image.Rect(0, 0, maxAvatarDimension+1, 1))))
+
+	_, err := processAvatar(buffer.Bytes())
+
+	assert.ErrorIs(t, err, errImageTooLarge)
+}
@@ -1,26 +1,39 @@
 package handler
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
-	"regexp"
+	"strconv"
 	"time"
 
 	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/passwordhash"
 	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
-	"golang.org/x/crypto/bcrypt"
+	openapi_types "github.com/oapi-codegen/runtime/types"
 )
 
+// JwtCustomClaims only identify the user by the subject claim, the profile is
+// read from the database so it is never stale.
 type JwtCustomClaims struct {
-	Name        string `json:"name"`
-	PhoneNumber string `json:"phoneNumber"`
+	// SessionId is the refresh token family issued together with the token
+	SessionId string `json:"sid"`
+	// RegisteredClaims.ID carries the jti used for revocation
 	jwt.RegisteredClaims
 }
 
+// UserId returns the user id stored in the subject claim.
+func (c *JwtCustomClaims) UserId() (int, error) {
+	return strconv.Atoi(c.Subject)
+}
+
 // Valid implements jwt.Claims.
 func (*JwtCustomClaims) Valid() error {
 	panic("unimplemented")
@@ -28,266 +41,1451 @@ func (*JwtCustomClaims) Valid() error {
 
 // // (POST /registration)
 func (s *Server) Registration(ctx echo.Context) error {
-	var resp generated.RegistrationResponse
 	var params generated.RegistrationParam
-	var errors []string
 
 	if err := json.NewDecoder(ctx.Request().Body).Decode(&params); err != nil {
-		return ctx.JSON(http.StatusBadRequest, "invalid JSON format")
+		return newProblem(http.StatusBadRequest, ErrCodeInvalidRequest, "invalid JSON format")
+	}
+
+	// required fields, lengths and phone number format are validated against api.yml by ValidationMiddleware,
+	// the password rules are those of the password policy.
+	if err := s.validatePassword("password", params.Password, PasswordOwner{FullName: params.FullName, PhoneNumber: params.PhoneNumber}); err != nil {
+		return err
 	}
 
-	// Checking Request Body
-	if params.PhoneNumber == "" {
-		errors = append(errors, "PhoneNumber is required")
+	// hashing & salt password
+	hashedPassword, err := s.hashPassword(params.Password)
+	if err != nil {
+		return internalError(err)
 	}
-	if params.FullName == "" {
-		errors = append(errors, "FullName is required")
+	// map to type
+	registTypeInput := repository.GetRegistrationInput{
+		FullName:    params.FullName,
+		PhoneNumber: params.PhoneNumber,
+		Password:    hashedPassword,
 	}
 
-	if params.Password == "" {
-		errors = append(errors, "Password is required")
+	res, err := s.Repository.CreateNewUser(ctx.Request().Context(), registTypeInput)
+	// answered as a registration, only the owner of the phone number learns it is registered
+	if repository.IsConstraintViolation(err, repository.ConstraintUserPhoneNumber) {
+		// the notices share the limit of the codes, past it they are silently skipped
+		if s.OTPRateLimiter != nil {
+			if ok, _ := s.OTPRateLimiter.Allow(params.PhoneNumber); !ok {
+				return ctx.NoContent(http.StatusAccepted)
+			}
+		}
+		if err := s.sendRegistrationNotice(ctx.Request().Context(), params.PhoneNumber); err != nil {
+			ctx.Logger().Errorf("request %s: registration notice: %v", requestIdFromContext(ctx), err)
+		}
+		return ctx.NoContent(http.StatusAccepted)
+	}
+	if err != nil {
+		return internalError(err)
 	}
 
-	// Phone numbers must be at minimum 10 characters and maximum 13 characters.
-	if len(params.PhoneNumber) < 10 || len(params.PhoneNumber) > 13 {
-		errors = append(errors, "PhoneNumber cannot less than 10 or more than 13 characters")
+	// the account exists already, a code that failed to be sent can be requested again
+	if err := s.sendPhoneVerification(ctx.Request().Context(), res.Id, params.PhoneNumber); err != nil {
+		ctx.Logger().Errorf("request %s: phone verification of user %d: %v", requestIdFromContext(ctx), res.Id, err)
 	}
 
-	// Phone numbers must start with the Indonesia country code “+62”.
-	patternFormatPhoneNumber := `^\+62\d{3,13}$`
-	re := regexp.MustCompile(patternFormatPhoneNumber)
-	if !re.MatchString(params.PhoneNumber) {
-		errors = append(errors, "PhoneNumber must start with the Indonesia country code “+62”")
+	return ctx.NoContent(http.StatusAccepted)
+}
+
+func (s *Server) Login(ctx echo.Context) error {
+	var params generated.LoginParam
+
+	// per IP limit, the account lock alone would let an attacker try one password on every account
+	if s.LoginRateLimiter != nil {
+		if ok, retryAfter := s.LoginRateLimiter.Allow(ctx.RealIP()); !ok {
+			return tooManyRequests(ErrCodeRateLimited, "too many login attempts, try again later", retryAfter)
+		}
 	}
 
-	// Full name must be at minimum 3 characters and maximum 60 characters.
-	if len(params.FullName) < 3 || len(params.FullName) > 60 {
-		errors = append(errors, "FullName cannot less than 3 or more than 60 characters")
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&params); err != nil {
+		return newProblem(http.StatusBadRequest, ErrCodeInvalidRequest, "invalid JSON format")
 	}
 
-	// Passwords must be minimum 6 characters and maximum 64 characters,
-	if len(params.Password) < 6 || len(params.Password) > 64 {
-		errors = append(errors, "Passwords cannot less than 6 or more than 64 characters")
+	// mapping login input
+	loginInput := repository.GetLoginInput{
+		PhoneNumber: params.PhoneNumber,
 	}
 
-	// containing at least 1 capital characters AND 1 number AND 1 special (nonalpha-numeric) characters.
-	if !validatepatternFormatPassword(params.Password) {
-		errors = append(errors, "Password containing at least 1 capital characters AND 1 number AND 1 special (nonalpha-numeric) characters")
+	// an unknown phone number and a wrong password are answered alike, in about the same time
+	invalidCredentials := newProblem(http.StatusUnauthorized, ErrCodeInvalidCredentials, "invalid phone number or password")
+
+	// get user by phone number
+	res, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), loginInput)
+	// unknown phone numbers are locked after as many failures as accounts are
+	if errors.Is(err, repository.ErrNotFound) {
+		if s.UnknownPhoneLockout != nil {
+			if lockedUntil := s.UnknownPhoneLockout.LockedUntil(params.PhoneNumber); !lockedUntil.IsZero() {
+				return accountLocked(lockedUntil)
+			}
+		}
+		s.verifyDummyPassword(params.Password)
+		if s.UnknownPhoneLockout != nil {
+			s.UnknownPhoneLockout.RecordFailure(params.PhoneNumber)
+		}
+		return invalidCredentials
+	}
+	if err != nil {
+		return internalError(err)
 	}
 
-	if len(errors) > 0 {
-		return ctx.JSON(http.StatusBadRequest, errors)
+	// a locked account is rejected before the password is even checked
+	if res.LockedUntil != nil && time.Now().Before(*res.LockedUntil) {
+		if err := s.recordLoginEvent(ctx, res.Id, loginFailureAccountLocked); err != nil {
+			return internalError(err)
+		}
+		return accountLocked(*res.LockedUntil)
 	}
 
-	// hasing & salt password
-	hashedPassword, err := hashAndSaltPassword(params.Password)
+	// Compare password, the hash may use outdated parameters
+	match, needsRehash, err := s.passwordHasher().Verify(res.Password, params.Password)
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest, err.Error())
+		return internalError(err)
 	}
-	// map to type
-	registTypeInput := repository.GetRegistrationInput{
-		FullName:    params.FullName,
-		PhoneNumber: params.PhoneNumber,
-		Password:    hashedPassword,
+	if !match {
+		if err := s.recordLoginEvent(ctx, res.Id, loginFailureInvalidPassword); err != nil {
+			return internalError(err)
+		}
+		if err := s.recordFailedLogin(ctx, res.Id); err != nil {
+			return internalError(err)
+		}
+		return invalidCredentials
+	}
+	if needsRehash {
+		s.rehashPassword(ctx, res.Id, res.Password, params.Password)
 	}
 
-	res, err := s.Repository.CreateNewUser(ctx.Request().Context(), registTypeInput)
-	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	// checked once the password is, so it tells nothing to whoever doesn't know it
+	if s.RequirePhoneVerification && res.PhoneVerifiedAt == nil {
+		if err := s.recordLoginEvent(ctx, res.Id, loginFailurePhoneNotVerified); err != nil {
+			return internalError(err)
+		}
+		return newProblem(http.StatusForbidden, ErrCodePhoneNotVerified, "phone number is not verified")
 	}
 
-	resp.Id = res.Id
-	return ctx.JSON(http.StatusCreated, resp)
+	// the tokens are only issued once the second factor is checked by /login/mfa
+	if res.TotpEnabled {
+		mfaToken, err := s.startMFAChallenge(ctx.Request().Context(), res.Id)
+		if err != nil {
+			return internalError(err)
+		}
+		return ctx.JSON(http.StatusOK, generated.MfaChallengeResponse{
+			MfaRequired: true,
+			MfaToken:    mfaToken,
+			ExpiresIn:   int(mfaChallengeTTL.Seconds()),
+		})
+	}
+
+	return s.completeLogin(ctx, res.Id)
 }
 
-func (s *Server) Login(ctx echo.Context) error {
-	var resp generated.LoginResponse
-	var params generated.LoginParam
+// (POST /login/mfa)
+func (s *Server) LoginMfa(ctx echo.Context) error {
+	var params generated.LoginMfaParam
 
 	if err := json.NewDecoder(ctx.Request().Body).Decode(&params); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid JSON format")
+		return newProblem(http.StatusBadRequest, ErrCodeInvalidRequest, "invalid JSON format")
 	}
 
-	// mapping login input
-	loginInput := repository.GetLoginInput{
-		PhoneNumber: params.PhoneNumber,
+	invalidToken := newProblem(http.StatusUnauthorized, ErrCodeInvalidMFAToken, "invalid or expired MFA token, log in again")
+
+	res, err := s.Repository.GetMfaChallengeByHash(ctx.Request().Context(), repository.GetMfaChallengeInput{
+		TokenHash: hashRefreshToken(params.MfaToken),
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return invalidToken
+	}
+	if err != nil {
+		return internalError(err)
+	}
+	if res.UsedAt != nil || time.Now().After(res.ExpiresAt) {
+		return invalidToken
 	}
 
-	// get user by phone number
-	res, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), loginInput)
+	// the password was right, guessing the code is bounded per challenge
+	attempts, err := s.Repository.IncrementMfaChallengeAttempts(ctx.Request().Context(), repository.IncrementMfaChallengeAttemptsInput{
+		Id: res.Id,
+	})
+	if err != nil {
+		return internalError(err)
+	}
+	if attempts.Attempts > mfaMaxAttempts {
+		return invalidToken
+	}
+
+	ok, err := s.verifySecondFactor(ctx.Request().Context(), res.UserId, res.TotpSecret, params.Code)
+	if err != nil {
+		return internalError(err)
+	}
+	if !ok {
+		if err := s.recordLoginEvent(ctx, res.UserId, loginFailureInvalidMFACode); err != nil {
+			return internalError(err)
+		}
+		return newProblem(http.StatusBadRequest, ErrCodeInvalidMFACode, "invalid authentication code")
+	}
+
+	// single use, a concurrent request may have won
+	consumed, err := s.Repository.ConsumeMfaChallenge(ctx.Request().Context(), repository.ConsumeMfaChallengeInput{
+		Id: res.Id,
+	})
+	if err != nil {
+		return internalError(err)
+	}
+	if !consumed.Consumed {
+		return invalidToken
+	}
+
+	return s.completeLogin(ctx, res.UserId)
+}
+
+// (POST /webauthn/login/begin)
+func (s *Server) BeginWebauthnLogin(ctx echo.Context) error {
+	// discoverable, the passkey tells who the user is
+	assertion, session, err := s.WebAuthn.BeginDiscoverableLogin()
+	if err != nil {
+		return internalError(err)
+	}
+
+	if err := s.saveWebauthnSession(ctx.Request().Context(), 0, session); err != nil {
+		return internalError(err)
+	}
+
+	return ctx.JSON(http.StatusOK, assertion)
+}
+
+// (POST /webauthn/login/finish)
+func (s *Server) FinishWebauthnLogin(ctx echo.Context) error {
+	parsed, err := protocol.ParseCredentialRequestResponseBody(ctx.Request().Body)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, err.Error())
+		return newProblem(http.StatusBadRequest, ErrCodeInvalidWebauthn, "invalid passkey assertion")
+	}
+
+	invalidPasskey := newProblem(http.StatusUnauthorized, ErrCodeInvalidCredentials, "passkey not recognized")
+
+	userId, session, err := s.consumeWebauthnSession(ctx.Request().Context(), parsed.Response.CollectedClientData.Challenge)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && userId != 0) {
+		return invalidPasskey
+	}
+	if err != nil {
+		return internalError(err)
+	}
+
+	// the library hides the errors of the lookup, they are kept aside
+	var user *webauthnUser
+	var loadErr error
+	findUser := func(rawId []byte, userHandle []byte) (webauthn.User, error) {
+		id, err := strconv.Atoi(string(userHandle))
+		if err != nil {
+			return nil, err
+		}
+		user, loadErr = s.loadWebauthnUser(ctx.Request().Context(), id)
+		return user, loadErr
 	}
 
-	// Compare password
-	if !comparePasswords(res.Password, []byte(params.Password)) {
-		return ctx.JSON(http.StatusBadRequest, "Invalid Password")
+	credential, err := s.WebAuthn.ValidateDiscoverableLogin(findUser, session, parsed)
+	if loadErr != nil && !errors.Is(loadErr, repository.ErrNotFound) {
+		return internalError(loadErr)
+	}
+	// a sign count going backwards means the passkey was cloned
+	if err != nil || credential.Authenticator.CloneWarning {
+		return invalidPasskey
+	}
+
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return internalError(err)
+	}
+	err = s.Repository.UpdateWebauthnCredential(ctx.Request().Context(), repository.UpdateWebauthnCredentialInput{
+		Id:   user.passkeyId(credential.ID),
+		Data: string(data),
+	})
+	if err != nil {
+		return internalError(err)
 	}
 
-	// Set custom claims
-	claims := &JwtCustomClaims{
-		res.FullName,
-		res.PhoneNumber,
-		jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 72)),
-		},
+	if s.RequirePhoneVerification && user.phoneVerifiedAt == nil {
+		if err := s.recordLoginEvent(ctx, user.id, loginFailurePhoneNotVerified); err != nil {
+			return internalError(err)
+		}
+		return newProblem(http.StatusForbidden, ErrCodePhoneNotVerified, "phone number is not verified")
 	}
 
-	// Create token with claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return s.completeLogin(ctx, user.id)
+}
+
+// completeLogin issues the tokens of a new session once every factor is checked.
+func (s *Server) completeLogin(ctx echo.Context, userId int) error {
+	// every login starts a new refresh token family
+	familyId, err := generateRandomString(16)
+	if err != nil {
+		return err
+	}
 
-	t, err := token.SignedString([]byte("secret"))
+	// Create access token
+	t, err := s.generateAccessToken(userId, familyId)
 	if err != nil {
 		return err
 	}
 
+	refreshToken, err := s.issueRefreshToken(ctx.Request().Context(), userId, familyId)
+	if err != nil {
+		return internalError(err)
+	}
+
 	// update flag user successful_login
 	updateParam := repository.PostUpdateUserSuccesLoginInput{
-		Id: res.Id,
+		Id: userId,
 	}
 
 	err = s.Repository.UpdateUserSuccesLogin(ctx.Request().Context(), updateParam)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, err.Error())
+		return internalError(err)
+	}
+
+	if err := s.recordLoginEvent(ctx, userId, ""); err != nil {
+		return internalError(err)
 	}
 
 	// map response
-	resp = generated.LoginResponse{
-		Id:    res.Id,
-		Token: t,
+	resp := generated.LoginResponse{
+		Id:           userId,
+		Token:        t,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(accessTokenTTL.Seconds()),
 	}
 
 	return ctx.JSON(http.StatusOK, resp)
 }
 
-func (s *Server) MyProfile(ctx echo.Context) error {
-	claims, err := extractJWTClaims(ctx)
+// (POST /token/refresh)
+func (s *Server) RefreshToken(ctx echo.Context) error {
+	var resp generated.RefreshTokenResponse
+	var params generated.RefreshTokenParam
+
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&params); err != nil {
+		return newProblem(http.StatusBadRequest, ErrCodeInvalidRequest, "invalid JSON format")
+	}
+
+	if params.RefreshToken == "" {
+		return validationProblem([]generated.FieldError{{
+			Field:   "refreshToken",
+			Rule:    "required",
+			Message: "value is required",
+		}})
+	}
 
+	// get refresh token by its hash
+	res, err := s.Repository.GetRefreshTokenByHash(ctx.Request().Context(), repository.GetRefreshTokenInput{
+		TokenHash: hashRefreshToken(params.RefreshToken),
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return newProblem(http.StatusUnauthorized, ErrCodeInvalidRefreshToken, "invalid refresh token")
+	}
 	if err != nil {
-		return ctx.JSON(http.StatusForbidden, err.Error())
+		return internalError(err)
 	}
 
-	resp := generated.MyProfileResponse{
-		Name:        claims["name"].(string),
-		PhoneNumber: claims["phoneNumber"].(string),
+	// a rotated token presented again means it leaked, kill the whole family
+	if res.RevokedAt != nil {
+		return s.revokeRefreshTokenFamily(ctx, res.FamilyId)
+	}
+
+	if time.Now().After(res.ExpiresAt) {
+		return newProblem(http.StatusUnauthorized, ErrCodeInvalidRefreshToken, "refresh token expired")
+	}
+
+	// rotate, only one concurrent request can win the revocation
+	revokeRes, err := s.Repository.RevokeRefreshToken(ctx.Request().Context(), repository.RevokeRefreshTokenInput{
+		Id: res.Id,
+	})
+	if err != nil {
+		return internalError(err)
+	}
+	if !revokeRes.Revoked {
+		return s.revokeRefreshTokenFamily(ctx, res.FamilyId)
+	}
+
+	refreshToken, err := s.issueRefreshToken(ctx.Request().Context(), res.UserId, res.FamilyId)
+	if err != nil {
+		return internalError(err)
+	}
+
+	t, err := s.generateAccessToken(res.UserId, res.FamilyId)
+	if err != nil {
+		return err
+	}
+
+	// map response
+	resp = generated.RefreshTokenResponse{
+		Token:        t,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(accessTokenTTL.Seconds()),
 	}
 
 	return ctx.JSON(http.StatusOK, resp)
 }
 
-func (s *Server) UpdateProfile(ctx echo.Context) error {
-	var params generated.UpdateProfileParam
-	var resp generated.UpdateProfileResponse
+// recordLoginEvent adds the attempt to the login history of the user, an
+// empty failureReason records a successful login.
+func (s *Server) recordLoginEvent(ctx echo.Context, userId int, failureReason string) error {
+	userAgent := ctx.Request().UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
 
-	claims, err := extractJWTClaims(ctx)
-	oldPhoneNumber := claims["phoneNumber"].(string)
+	return s.Repository.CreateLoginEvent(ctx.Request().Context(), repository.CreateLoginEventInput{
+		UserId:        userId,
+		Success:       failureReason == "",
+		FailureReason: failureReason,
+		IpAddress:     ctx.RealIP(),
+		UserAgent:     userAgent,
+	})
+}
 
+// recordFailedLogin counts the failure and locks the account once there are too many in a row.
+func (s *Server) recordFailedLogin(ctx echo.Context, userId int) error {
+	res, err := s.Repository.RecordFailedLogin(ctx.Request().Context(), repository.RecordFailedLoginInput{
+		Id: userId,
+	})
 	if err != nil {
-		return ctx.JSON(http.StatusForbidden, err.Error())
+		return err
 	}
 
-	if err := json.NewDecoder(ctx.Request().Body).Decode(&params); err != nil {
-		return ctx.JSON(http.StatusBadRequest, "invalid JSON format")
+	lockout := s.LoginProtection.lockoutDuration(res.FailedLoginCount)
+	if lockout == 0 {
+		return nil
 	}
+	return s.Repository.LockUser(ctx.Request().Context(), repository.LockUserInput{
+		Id:          userId,
+		LockedUntil: time.Now().Add(lockout),
+	})
+}
 
-	// mapping update profile input
-	loginInput := repository.UpdateUserInput{
-		OldPhoneNumber: oldPhoneNumber,
-		Name:           params.FullName,
-		PhoneNumber:    params.PhoneNumber,
+// verifyCurrentPassword checks the password a logged in user confirms, its
+// failures count like those of Login so a stolen access token can't be used to
+// guess it.
+func (s *Server) verifyCurrentPassword(ctx echo.Context, user repository.GetUserCredentialsByIdOutput, password string, detail string) error {
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		return accountLocked(*user.LockedUntil)
 	}
 
-	// get user by phone number
-	res, err := s.Repository.UpdateUserByPhoneNumber(ctx.Request().Context(), loginInput)
-	if err != nil && err.Error() == "pq: duplicate key value violates unique constraint \"users_phone_number_key\"" {
-		return ctx.JSON(http.StatusConflict, errors.New("duplicate phoneNumber"))
+	match, err := s.comparePasswords(user.Password, password)
+	if err != nil {
+		return internalError(err)
+	}
+	if !match {
+		if err := s.recordFailedLogin(ctx, user.Id); err != nil {
+			return internalError(err)
+		}
+		return newProblem(http.StatusBadRequest, ErrCodeInvalidCredentials, detail)
 	}
+	return nil
+}
 
+func (s *Server) revokeRefreshTokenFamily(ctx echo.Context, familyId string) error {
+	err := s.Repository.RevokeRefreshTokenFamily(ctx.Request().Context(), repository.RevokeRefreshTokenFamilyInput{
+		FamilyId: familyId,
+	})
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, err.Error())
+		return internalError(err)
 	}
 
-	// mapping response
-	resp = generated.UpdateProfileResponse{
-		Id: res.Id,
+	return newProblem(http.StatusUnauthorized, ErrCodeRefreshTokenReused, "refresh token reuse detected")
+}
+
+func (s *Server) MyProfile(ctx echo.Context) error {
+	principal, ok := principalFromContext(ctx)
+	if !ok {
+		return unauthorized(errMissingToken)
+	}
+
+	user, err := s.Repository.GetUserById(ctx.Request().Context(), repository.GetUserByIdInput{
+		Id: principal.UserId,
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return unauthorized(errors.New("user no longer exists"))
+	}
+	if err != nil {
+		return internalError(err)
+	}
+
+	resp := generated.MyProfileResponse{
+		Id:            user.Id,
+		Name:          user.FullName,
+		PhoneNumber:   user.PhoneNumber,
+		PhoneVerified: user.PhoneVerifiedAt != nil,
+		LoginCount:    user.LoginCount,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		Locale:        user.Locale,
+		Timezone:      user.Timezone,
+		AvatarUrl:     user.AvatarUrl,
+	}
+	if user.Email != nil {
+		emailVerified := user.EmailVerifiedAt != nil
+		resp.EmailVerified = &emailVerified
+	}
+	if user.DateOfBirth != nil {
+		resp.DateOfBirth = &openapi_types.Date{Time: *user.DateOfBirth}
+	}
+	if user.AvatarThumbnails != nil {
+		thumbnails := generated.AvatarThumbnails(user.AvatarThumbnails)
+		resp.AvatarThumbnails = &thumbnails
 	}
 
 	return ctx.JSON(http.StatusOK, resp)
 }
 
-// this function for validate pattern format password
-// containing at least 1 capital characters AND 1 number AND 1 special (nonalpha-numeric) characters.
-func validatepatternFormatPassword(password string) bool {
-	hasUppercase := false
-	hasDigit := false
-	hasSpecial := false
+// (POST /phone-verification/request)
+func (s *Server) RequestPhoneVerification(ctx echo.Context) error {
+	var params generated.PhoneVerificationRequestParam
+
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&params); err != nil {
+		return newProblem(http.StatusBadRequest, ErrCodeInvalidRequest, "invalid JSON format")
+	}
 
-	for _, char := range password {
-		switch {
-		case 'A' <= char && char <= 'Z':
-			hasUppercase = true
-		case '0' <= char && char <= '9':
-			hasDigit = true
-		case char >= 32 && char <= 126: // ASCII printable characters excluding alphanumerics
-			hasSpecial = true
+	// limited whether the phone number is registered or not, so it reveals nothing
+	if s.OTPRateLimiter != nil {
+		if ok, retryAfter := s.OTPRateLimiter.Allow(params.PhoneNumber); !ok {
+			return tooManyRequests(ErrCodeRateLimited, "too many codes requested for this phone number, try again later", retryAfter)
 		}
 	}
 
-	return hasUppercase && hasDigit && hasSpecial
+	res, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), repository.GetLoginInput{
+		PhoneNumber: params.PhoneNumber,
+	})
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && res.PhoneVerifiedAt != nil) {
+		return ctx.NoContent(http.StatusAccepted)
+	}
+	if err != nil {
+		return internalError(err)
+	}
+
+	// neither the time taken nor a failure may tell the phone number is registered
+	s.runInBackground(ctx, fmt.Sprintf("phone verification of user %d", res.Id), func(ctx context.Context) error {
+		return s.sendPhoneVerification(ctx, res.Id, res.PhoneNumber)
+	})
+
+	return ctx.NoContent(http.StatusAccepted)
 }
 
-func hashAndSaltPassword(password string) (string, error) {
-	// Convert password string to byte slice
-	var passwordBytes = []byte(password)
+// (POST /phone-verification/confirm)
+func (s *Server) ConfirmPhoneVerification(ctx echo.Context) error {
+	var params generated.PhoneVerificationConfirmParam
+
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&params); err != nil {
+		return newProblem(http.StatusBadRequest, ErrCodeInvalidRequest, "invalid JSON format")
+	}
+
+	// every failure gets the same answer, it must not tell which phone numbers are registered
+	invalidOTP := newProblem(http.StatusBadRequest, ErrCodeInvalidOTP, "invalid or expired code")
+
+	user, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), repository.GetLoginInput{
+		PhoneNumber: params.PhoneNumber,
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return invalidOTP
+	}
+	if err != nil {
+		return internalError(err)
+	}
+	if user.PhoneVerifiedAt != nil {
+		return invalidOTP
+	}
+
+	res, err := s.Repository.GetPhoneVerificationByUserId(ctx.Request().Context(), repository.GetPhoneVerificationInput{
+		UserId: user.Id,
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return invalidOTP
+	}
+	if err != nil {
+		return internalError(err)
+	}
+	// the code of a pending change is confirmed by the signed in user only
+	if res.PhoneNumber != user.PhoneNumber {
+		return invalidOTP
+	}
+
+	ok, err := s.consumePhoneVerification(ctx.Request().Context(), res, params.Otp)
+	if err != nil {
+		return internalError(err)
+	}
+	if !ok {
+		return invalidOTP
+	}
 
-	// Hash password with bcrypt's min cost
-	hashedPasswordBytes, err := bcrypt.GenerateFromPassword(passwordBytes, bcrypt.MinCost)
+	err = s.Repository.VerifyUserPhoneNumber(ctx.Request().Context(), repository.VerifyUserPhoneNumberInput{
+		Id:          user.Id,
+		PhoneNumber: user.PhoneNumber,
+	})
+	if err != nil {
+		return internalError(err)
+	}
 
-	return string(hashedPasswordBytes), err
+	return ctx.NoContent(http.StatusNoContent)
 }
 
-func comparePasswords(hashedPwd string, plainPwd []byte) bool {
-	// Since we'll be getting the hashed password from the DB it
-	// will be a string so we'll need to convert it to a byte slice
-	byteHash := []byte(hashedPwd)
-	err := bcrypt.CompareHashAndPassword(byteHash, plainPwd)
+// (POST /password-reset/request)
+func (s *Server) RequestPasswordReset(ctx echo.Context) error {
+	var params generated.PasswordResetRequestParam
+
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&params); err != nil {
+		return newProblem(http.StatusBadRequest, ErrCodeInvalidRequest, "invalid JSON format")
+	}
+
+	// limited whether the phone number is registered or not, so it reveals nothing
+	if s.OTPRateLimiter != nil {
+		if ok, retryAfter := s.OTPRateLimiter.Allow(params.PhoneNumber); !ok {
+			return tooManyRequests(ErrCodeRateLimited, "too many codes requested for this phone number, try again later", retryAfter)
+		}
+	}
+
+	res, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), repository.GetLoginInput{
+		PhoneNumber: params.PhoneNumber,
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return ctx.NoContent(http.StatusAccepted)
+	}
 	if err != nil {
-		return false
+		return internalError(err)
 	}
 
-	return true
+	// neither the time taken nor a failure may tell the phone number is registered
+	s.runInBackground(ctx, fmt.Sprintf("password reset of user %d", res.Id), func(ctx context.Context) error {
+		return s.sendPasswordReset(ctx, res.Id, res.PhoneNumber)
+	})
+
+	return ctx.NoContent(http.StatusAccepted)
 }
 
-func extractJWTClaims(c echo.Context) (jwt.MapClaims, error) {
-	tokenString := c.Request().Header.Get("Authorization")
-	if tokenString == "" {
-		return nil, fmt.Errorf("authorization token not provided")
+// (POST /password-reset/confirm)
+func (s *Server) ConfirmPasswordReset(ctx echo.Context) error {
+	var params generated.PasswordResetConfirmParam
+
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&params); err != nil {
+		return newProblem(http.StatusBadRequest, ErrCodeInvalidRequest, "invalid JSON format")
 	}
 
-	// Extract the token from the "Bearer" prefix
-	tokenString = tokenString[len("Bearer "):]
+	// the rules that don't tell whether the phone number is registered are checked first
+	if err := s.validatePassword("newPassword", params.NewPassword, PasswordOwner{PhoneNumber: params.PhoneNumber}); err != nil {
+		return err
+	}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Validate the signing method and provide the secret key
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte("secret"), nil
+	// every failure gets the same answer, it must not tell which phone numbers are registered
+	invalidOTP := newProblem(http.StatusBadRequest, ErrCodeInvalidOTP, "invalid or expired code")
+
+	res, err := s.Repository.GetPasswordResetByPhoneNumber(ctx.Request().Context(), repository.GetPasswordResetInput{
+		PhoneNumber: params.PhoneNumber,
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return invalidOTP
+	}
+	if err != nil {
+		return internalError(err)
+	}
+	if res.UsedAt != nil || time.Now().After(res.ExpiresAt) {
+		return invalidOTP
+	}
+
+	attempts, err := s.Repository.IncrementPasswordResetAttempts(ctx.Request().Context(), repository.IncrementPasswordResetAttemptsInput{
+		Id: res.Id,
+	})
+	if err != nil {
+		return internalError(err)
+	}
+	if attempts.Attempts > otpMaxAttempts || !compareOTP(res.OtpHash, res.OtpSalt, params.Otp) {
+		return invalidOTP
+	}
+
+	// the code stays valid for a password without the name of the user
+	if err := s.validatePassword("newPassword", params.NewPassword, PasswordOwner{FullName: res.FullName, PhoneNumber: params.PhoneNumber}); err != nil {
+		return err
+	}
+
+	// single use, a concurrent confirmation may have won
+	consumed, err := s.Repository.ConsumePasswordReset(ctx.Request().Context(), repository.ConsumePasswordResetInput{
+		Id: res.Id,
 	})
+	if err != nil {
+		return internalError(err)
+	}
+	if !consumed.Consumed {
+		return invalidOTP
+	}
+
+	hashedPassword, err := s.hashPassword(params.NewPassword)
+	if err != nil {
+		return internalError(err)
+	}
 
+	err = s.Repository.UpdateUserPassword(ctx.Request().Context(), repository.UpdateUserPasswordInput{
+		Id:       res.UserId,
+		Password: hashedPassword,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse JWT: %v", err)
+		return internalError(err)
 	}
 
-	// Check if the token is valid
-	if !token.Valid {
-		return nil, fmt.Errorf("invalid token")
+	if err := s.revokeAllUserSessions(ctx.Request().Context(), res.UserId); err != nil {
+		return internalError(err)
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// (GET /my-profile/logins)
+func (s *Server) ListMyLogins(ctx echo.Context, params generated.ListMyLoginsParams) error {
+	principal, ok := principalFromContext(ctx)
 	if !ok {
-		return nil, fmt.Errorf("failed to extract claims from token")
+		return unauthorized(errMissingToken)
 	}
 
-	return claims, nil
+	// checked here too, the page is cut at limit-1
+	limit := defaultLoginHistoryLimit
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+	if limit < 1 || limit > maxLoginHistoryLimit {
+		return validationProblem([]generated.FieldError{{
+			Field:   "limit",
+			Rule:    "range",
+			Message: fmt.Sprintf("limit must be between 1 and %d", maxLoginHistoryLimit),
+		}})
+	}
+
+	var beforeId int64
+	if params.Cursor != nil {
+		id, err := decodeCursor(*params.Cursor)
+		if err != nil {
+			return validationProblem([]generated.FieldError{{
+				Field:   "cursor",
+				Rule:    "format",
+				Message: "cursor must be the nextCursor of a previous page",
+			}})
+		}
+		beforeId = id
+	}
+
+	// one more event than asked tells whether there is a next page
+	res, err := s.Repository.ListLoginEvents(ctx.Request().Context(), repository.ListLoginEventsInput{
+		UserId:   principal.UserId,
+		BeforeId: beforeId,
+		Limit:    limit + 1,
+	})
+	if err != nil {
+		return internalError(err)
+	}
+
+	events := res.Events
+	resp := generated.LoginHistoryResponse{
+		Items: make([]generated.LoginEvent, 0, limit),
+	}
+	if len(events) > limit {
+		events = events[:limit]
+		nextCursor := encodeCursor(events[limit-1].Id)
+		resp.NextCursor = &nextCursor
+	}
+	for _, event := range events {
+		resp.Items = append(resp.Items, generated.LoginEvent{
+			Id:            event.Id,
+			Success:       event.Success,
+			FailureReason: (*generated.LoginEventFailureReason)(event.FailureReason),
+			IpAddress:     event.IpAddress,
+			UserAgent:     event.UserAgent,
+			CreatedAt:     event.CreatedAt,
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) UpdateProfile(ctx echo.Context) error {
+	var params generated.UpdateProfileParam
+	var resp generated.UpdateProfileResponse
+
+	principal, ok := principalFromContext(ctx)
+	if !ok {
+		return unauthorized(errMissingToken)
+	}
+
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&params); err != nil {
+		return newProblem(http.StatusBadRequest, ErrCodeInvalidRequest, "invalid JSON format")
+	}
+
+	if problems := validateProfile(&params, time.Now()); len(problems) > 0 {
+		return validationProblem(problems)
+	}
+
+	// the user is identified by the id of the token, never by its phone number
+	// which may have changed since, or belong to another account by now
+	userNotFound := newProblem(http.StatusNotFound, ErrCodeNotFound, "user not found")
+
+	resp.Id = principal.UserId
+
+	// a new phone number is pending until the code sent to it is confirmed
+	phoneNumberChanged := false
+	if params.PhoneNumber != nil {
+		user, err := s.Repository.GetUserById(ctx.Request().Context(), repository.GetUserByIdInput{
+			Id: principal.UserId,
+		})
+		if errors.Is(err, repository.ErrNotFound) {
+			return userNotFound
+		}
+		if err != nil {
+			return internalError(err)
+		}
+		phoneNumberChanged = *params.PhoneNumber != user.PhoneNumber
+	}
+	// a phone number registered by another account is answered as a pending change,
+	// only its owner is told, and the limit comes first so it can't be probed freely
+	phoneNumberTaken := false
+	if phoneNumberChanged {
+		if s.OTPRateLimiter != nil {
+			if ok, retryAfter := s.OTPRateLimiter.Allow(*params.PhoneNumber); !ok {
+				return tooManyRequests(ErrCodeRateLimited, "too many codes requested for this phone number, try again later", retryAfter)
+			}
+		}
+
+		_, err := s.Repository.GetUserByPhoneNumber(ctx.Request().Context(), repository.GetLoginInput{
+			PhoneNumber: *params.PhoneNumber,
+		})
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return internalError(err)
+		}
+		phoneNumberTaken = err == nil
+	}
+
+	// the phone number is only set once confirmed, the other fields at once
+	if params.FullName != nil || hasProfileFields(params) {
+		input := repository.UpdateUserByIdInput{
+			Id:        principal.UserId,
+			FullName:  params.FullName,
+			Email:     params.Email,
+			Locale:    params.Locale,
+			Timezone:  params.Timezone,
+			AvatarUrl: params.AvatarUrl,
+		}
+		if params.DateOfBirth != nil {
+			input.DateOfBirth = &params.DateOfBirth.Time
+		}
+
+		res, err := s.Repository.UpdateUserById(ctx.Request().Context(), input)
+		if repository.IsConstraintViolation(err, repository.ConstraintUserEmail) {
+			return newProblem(http.StatusConflict, ErrCodeDuplicateEmail, "email is already registered")
+		}
+		if err != nil {
+			return internalError(err)
+		}
+		if !res.Updated {
+			return userNotFound
+		}
+	}
+
+	// the pending verification is replaced on both paths, but the code of a taken
+	// phone number is never sent, and a failed SMS is only logged on both
+	if phoneNumberChanged {
+		otp, err := s.createPhoneVerification(ctx.Request().Context(), principal.UserId, *params.PhoneNumber)
+		if err != nil {
+			return internalError(err)
+		}
+		if phoneNumberTaken {
+			err = s.sendPhoneNumberChangeNotice(ctx.Request().Context(), *params.PhoneNumber)
+		} else {
+			err = s.sendPhoneVerificationCode(ctx.Request().Context(), *params.PhoneNumber, otp)
+		}
+		if err != nil {
+			ctx.Logger().Errorf("request %s: phone number change of user %d: %v", requestIdFromContext(ctx), principal.UserId, err)
+		}
+		resp.PendingPhoneNumber = params.PhoneNumber
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// (POST /webauthn/register/begin)
+func (s *Server) BeginWebauthnRegistration(ctx echo.Context) error {
+	principal, ok := principalFromContext(ctx)
+	if !ok {
+		return unauthorized(errMissingToken)
+	}
+
+	user, err := s.loadWebauthnUser(ctx.Request().Context(), principal.UserId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return unauthorized(errors.New("user no longer exists"))
+	}
+	if err != nil {
+		return internalError(err)
+	}
+
+	// a passkey is discoverable, and an authenticator holds at most one per user
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(user.credentials))
+	for _, credential := range user.credentials {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+	creation, session, err := s.WebAuthn.BeginRegistration(user,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithExclusions(exclusions),
+	)
+	if err != nil {
+		return internalError(err)
+	}
+
+	if err := s.saveWebauthnSession(ctx.Request().Context(), principal.UserId, session); err != nil {
+		return internalError(err)
+	}
+
+	return ctx.JSON(http.StatusOK, creation)
+}
+
+// (POST /webauthn/register/finish)
+func (s *Server) FinishWebauthnRegistration(ctx echo.Context) error {
+	// the credential is kept raw, the webauthn library parses it
+	var params struct {
+		Name       string          `json:"name"`
+		Credential json.RawMessage `json:"credential"`
+	}
+
+	principal, ok := principalFromContext(ctx)
+	if !ok {
+		return unauthorized(errMissingToken)
+	}
+
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&params); err != nil {
+		return newProblem(http.StatusBadRequest, ErrCodeInvalidRequest, "invalid JSON format")
+	}
+
+	invalidCredential := newProblem(http.StatusBadRequest, ErrCodeInvalidWebauthn, "invalid passkey credential")
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(params.Credential))
+	if err != nil {
+		return invalidCredential
+	}
+
+	userId, session, err := s.consumeWebauthnSession(ctx.Request().Context(), parsed.Response.CollectedClientData.Challenge)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && userId != principal.UserId) {
+		return invalidCredential
+	}
+	if err != nil {
+		return internalError(err)
+	}
+
+	user, err := s.loadWebauthnUser(ctx.Request().Context(), principal.UserId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return unauthorized(errors.New("user no longer exists"))
+	}
+	if err != nil {
+		return internalError(err)
+	}
+
+	credential, err := s.WebAuthn.CreateCredential(user, session, parsed)
+	if err != nil {
+		return invalidCredential
+	}
+
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return internalError(err)
+	}
+	res, err := s.Repository.CreateWebauthnCredential(ctx.Request().Context(), repository.CreateWebauthnCredentialInput{
+		UserId:       principal.UserId,
+		CredentialId: credential.ID,
+		Name:         params.Name,
+		Data:         string(data),
+	})
+	if err != nil {
+		return internalError(err)
+	}
+
+	resp := generated.Passkey{
+		Id:        res.Id,
+		Name:      params.Name,
+		CreatedAt: res.CreatedAt,
+	}
+
+	return ctx.JSON(http.StatusCreated, resp)
+}
+
+// (GET /my-profile/passkeys)
+func (s *Server) ListMyPasskeys(ctx echo.Context) error {
+	principal, ok := principalFromContext(ctx)
+	if !ok {
+		return unauthorized(errMissingToken)
+	}
+
+	res, err := s.Repository.ListWebauthnCredentials(ctx.Request().Context(), repository.ListWebauthnCredentialsInput{
+		UserId: principal.UserId,
+	})
+	if err != nil {
+		return internalError(err)
+	}
+
+	resp := generated.PasskeyListResponse{
+		Items: make([]generated.Passkey, 0, len(res.Credentials)),
+	}
+	for _, passkey := range res.Credentials {
+		resp.Items = append(resp.Items, generated.Passkey{
+			Id:         passkey.Id,
+			Name:       passkey.Name,
+			CreatedAt:  passkey.CreatedAt,
+			LastUsedAt: passkey.LastUsedAt,
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// (DELETE /my-profile/passkeys/{id})
+func (s *Server) DeleteMyPasskey(ctx echo.Context, id int) error {
+	principal, ok := principalFromContext(ctx)
+	if !ok {
+		return unauthorized(errMissingToken)
+	}
+
+	res, err := s.Repository.DeleteWebauthnCredential(ctx.Request().Context(), repository.DeleteWebauthnCredentialInput{
+		Id:     id,
+		UserId: principal.UserId,
+	})
+	if err != nil {
+		return internalError(err)
+	}
+	if !res.Deleted {
+		return newProblem(http.StatusNotFound, ErrCodeNotFound, "no passkey with this id")
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// (POST /my-profile/2fa/totp)
+func (s *Server) EnrollTotp(ctx echo.Context) error {
+	principal, ok := principalFromContext(ctx)
+	if !ok {
+		return unauthorized(errMissingToken)
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return internalError(err)
+	}
+
+	res, err := s.Repository.CreateTotpSecret(ctx.Request().Context(), repository.CreateTotpSecretInput{
+		UserId: principal.UserId,
+		Secret: secret,
+	})
+	if err != nil {
+		return internalError(err)
+	}
+	if !res.Created {
+		return newProblem(http.StatusConflict, ErrCodeTOTPAlreadyEnabled, "two-factor authentication is already enabled")
+	}
+
+	user, err := s.Repository.GetUserById(ctx.Request().Context(), repository.GetUserByIdInput{
+		Id: principal.UserId,
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return unauthorized(errors.New("user no longer exists"))
+	}
+	if err != nil {
+		return internalError(err)
+	}
+
+	resp := generated.TotpEnrollmentResponse{
+		Secret:     secret,
+		OtpauthUri: totpURI(secret, user.PhoneNumber),
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// (POST /my-profile/2fa/totp/confirm)
+func (s *Server) ConfirmTotp(ctx echo.Context) error {
+	var params generated.ConfirmTotpParam
+
+	principal, ok := principalFromContext(ctx)
+	if !ok {
+		return unauthorized(errMissingToken)
+	}
+
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&params); err != nil {
+		return newProblem(http.StatusBadRequest, ErrCodeInvalidRequest, "invalid JSON format")
+	}
+
+	invalidCode := newProblem(http.StatusBadRequest, ErrCodeInvalidMFACode, "invalid authentication code")
+
+	totp, err := s.Repository.GetTotpByUserId(ctx.Request().Context(), repository.GetTotpInput{
+		UserId: principal.UserId,
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return newProblem(http.StatusBadRequest, ErrCodeInvalidMFACode, "no pending enrollment, start with POST /my-profile/2fa/totp")
+	}
+	if err != nil {
+		return internalError(err)
+	}
+	if totp.EnabledAt != nil {
+		return newProblem(http.StatusConflict, ErrCodeTOTPAlreadyEnabled, "two-factor authentication is already enabled")
+	}
+
+	// proves the authenticator app holds the secret, a recovery code can't do it
+	ok, err = s.acceptTOTPCode(ctx.Request().Context(), principal.UserId, totp.Secret, params.Code)
+	if err != nil {
+		return internalError(err)
+	}
+	if !ok {
+		return invalidCode
+	}
+
+	resp := generated.RecoveryCodesResponse{
+		RecoveryCodes: make([]string, 0, recoveryCodeCount),
+	}
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return internalError(err)
+		}
+		resp.RecoveryCodes = append(resp.RecoveryCodes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	res, err := s.Repository.EnableTotp(ctx.Request().Context(), repository.EnableTotpInput{
+		UserId:             principal.UserId,
+		RecoveryCodeHashes: hashes,
+	})
+	if err != nil {
+		return internalError(err)
+	}
+	if !res.Enabled {
+		return newProblem(http.StatusConflict, ErrCodeTOTPAlreadyEnabled, "two-factor authentication is already enabled")
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// (POST /my-profile/phone-number/confirm)
+func (s *Server) ConfirmPhoneNumberChange(ctx echo.Context) error {
+	var params generated.PhoneNumberChangeConfirmParam
+
+	principal, ok := principalFromContext(ctx)
+	if !ok {
+		return unauthorized(errMissingToken)
+	}
+
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&params); err != nil {
+		return newProblem(http.StatusBadRequest, ErrCodeInvalidRequest, "invalid JSON format")
+	}
+
+	invalidOTP := newProblem(http.StatusBadRequest, ErrCodeInvalidOTP, "invalid or expired code")
+
+	res, err := s.Repository.GetPhoneVerificationByUserId(ctx.Request().Context(), repository.GetPhoneVerificationInput{
+		UserId: principal.UserId,
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return invalidOTP
+	}
+	if err != nil {
+		return internalError(err)
+	}
+
+	ok, err = s.consumePhoneVerification(ctx.Request().Context(), res, params.Otp)
+	if err != nil {
+		return internalError(err)
+	}
+	if !ok {
+		return invalidOTP
+	}
+
+	err = s.Repository.VerifyUserPhoneNumber(ctx.Request().Context(), repository.VerifyUserPhoneNumberInput{
+		Id:          principal.UserId,
+		PhoneNumber: res.PhoneNumber,
+	})
+	if repository.IsConstraintViolation(err, repository.ConstraintUserPhoneNumber) {
+		return newProblem(http.StatusConflict, ErrCodeDuplicatePhoneNumber, "phone number has been registered by another account meanwhile")
+	}
+	if err != nil {
+		return internalError(err)
+	}
+
+	// the tokens issued for the old phone number are not valid anymore
+	if err := s.revokeAllUserSessions(ctx.Request().Context(), principal.UserId); err != nil {
+		return internalError(err)
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// (PUT /my-profile/password)
+func (s *Server) ChangePassword(ctx echo.Context) error {
+	var params generated.ChangePasswordParam
+
+	principal, ok := principalFromContext(ctx)
+	if !ok {
+		return unauthorized(errMissingToken)
+	}
+
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&params); err != nil {
+		return newProblem(http.StatusBadRequest, ErrCodeInvalidRequest, "invalid JSON format")
+	}
+
+	res, err := s.Repository.GetUserCredentialsById(ctx.Request().Context(), repository.GetUserCredentialsByIdInput{
+		Id: principal.UserId,
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return unauthorized(errors.New("user no longer exists"))
+	}
+	if err != nil {
+		return internalError(err)
+	}
+
+	if err := s.validatePassword("newPassword", params.NewPassword, PasswordOwner{FullName: res.FullName, PhoneNumber: res.PhoneNumber}); err != nil {
+		return err
+	}
+
+	if err := s.verifyCurrentPassword(ctx, res, params.CurrentPassword, "invalid current password"); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.hashPassword(params.NewPassword)
+	if err != nil {
+		return internalError(err)
+	}
+
+	err = s.Repository.UpdateUserPassword(ctx.Request().Context(), repository.UpdateUserPasswordInput{
+		Id:       principal.UserId,
+		Password: hashedPassword,
+	})
+	if err != nil {
+		return internalError(err)
+	}
+
+	// whoever else holds a session is logged out, the current one goes on
+	if err := s.revokeUserSessions(ctx.Request().Context(), principal.UserId, principal.SessionId); err != nil {
+		return internalError(err)
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// (DELETE /my-profile)
+func (s *Server) DeleteMyProfile(ctx echo.Context) error {
+	var params generated.DeleteMyProfileParam
+
+	principal, ok := principalFromContext(ctx)
+	if !ok {
+		return unauthorized(errMissingToken)
+	}
+
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&params); err != nil {
+		return newProblem(http.StatusBadRequest, ErrCodeInvalidRequest, "invalid JSON format")
+	}
+
+	// a stolen access token alone must not be enough to delete the account
+	res, err := s.Repository.GetUserCredentialsById(ctx.Request().Context(), repository.GetUserCredentialsByIdInput{
+		Id: principal.UserId,
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return unauthorized(errors.New("user no longer exists"))
+	}
+	if err != nil {
+		return internalError(err)
+	}
+
+	if err := s.verifyCurrentPassword(ctx, res, params.Password, "invalid password"); err != nil {
+		return err
+	}
+
+	_, err = s.Repository.SoftDeleteUser(ctx.Request().Context(), repository.SoftDeleteUserInput{
+		Id: principal.UserId,
+	})
+	if err != nil {
+		return internalError(err)
+	}
+
+	if err := s.revokeAllUserSessions(ctx.Request().Context(), principal.UserId); err != nil {
+		return internalError(err)
+	}
+
+	// the avatar files are public, they go with the account, the purge job
+	// deletes them again when this fails
+	if s.BlobStore != nil {
+		if err := s.deleteAvatar(ctx.Request().Context(), principal.UserId); err != nil {
+			ctx.Logger().Errorf("request %s: avatar of user %d: %v", requestIdFromContext(ctx), principal.UserId, err)
+		}
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// (POST /admin/users/{id}/restore)
+func (s *Server) RestoreUser(ctx echo.Context, id int) error {
+	res, err := s.Repository.RestoreUser(ctx.Request().Context(), repository.RestoreUserInput{
+		Id: id,
+	})
+	if repository.IsConstraintViolation(err, repository.ConstraintUserPhoneNumber) {
+		return newProblem(http.StatusConflict, ErrCodeDuplicatePhoneNumber, "the phone number has been registered by another account")
+	}
+	if err != nil {
+		return internalError(err)
+	}
+
+	if !res.Restored {
+		return newProblem(http.StatusNotFound, ErrCodeNotFound, "no deleted user with this id, it may have been purged")
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// PurgeDeletedUsers hard deletes the users soft deleted before deletedBefore
+// along with their avatar, it is run periodically. The users are purged even
+// when the files of their avatar fail to be deleted, those errors are returned
+// together with the number of purged users.
+func (s *Server) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	res, err := s.Repository.PurgeDeletedUsers(ctx, repository.PurgeDeletedUsersInput{
+		DeletedBefore: deletedBefore,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if s.BlobStore == nil {
+		return res.Purged, nil
+	}
+
+	var errs []error
+	for _, id := range res.Ids {
+		if err := s.deleteAvatar(ctx, id); err != nil {
+			errs = append(errs, fmt.Errorf("delete the avatar of purged user %d: %w", id, err))
+		}
+	}
+	return res.Purged, errors.Join(errs...)
+}
+
+// (POST /logout)
+func (s *Server) Logout(ctx echo.Context) error {
+	principal, ok := principalFromContext(ctx)
+	if !ok {
+		return unauthorized(errMissingToken)
+	}
+
+	// revoke the access token itself
+	err := s.RevocationStore.RevokeToken(ctx.Request().Context(), repository.RevokeTokenInput{
+		Jti:       principal.TokenId,
+		UserId:    principal.UserId,
+		ExpiresAt: principal.ExpiresAt,
+	})
+	if err != nil {
+		return internalError(err)
+	}
+
+	// and the refresh tokens of the same session
+	err = s.Repository.RevokeRefreshTokenFamily(ctx.Request().Context(), repository.RevokeRefreshTokenFamilyInput{
+		FamilyId: principal.SessionId,
+	})
+	if err != nil {
+		return internalError(err)
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// (POST /logout-all)
+func (s *Server) LogoutAll(ctx echo.Context) error {
+	principal, ok := principalFromContext(ctx)
+	if !ok {
+		return unauthorized(errMissingToken)
+	}
+
+	if err := s.revokeAllUserSessions(ctx.Request().Context(), principal.UserId); err != nil {
+		return internalError(err)
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// (GET /.well-known/jwks.json)
+func (s *Server) Jwks(ctx echo.Context) error {
+	set := s.KeyManager.JWKS()
+
+	resp := generated.JWKSResponse{
+		Keys: make([]generated.JWK, 0, len(set.Keys)),
+	}
+	for _, key := range set.Keys {
+		resp.Keys = append(resp.Keys, generated.JWK{
+			Kty: key.Kty,
+			Kid: key.Kid,
+			Use: key.Use,
+			Alg: key.Alg,
+			N:   optionalString(key.N),
+			E:   optionalString(key.E),
+			Crv: optionalString(key.Crv),
+			X:   optionalString(key.X),
+			Y:   optionalString(key.Y),
+		})
+	}
+
+	// verifiers cache the keys, a new signing key must be published as a
+	// verification key for at least jwksMaxAge before it signs anything
+	ctx.Response().Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(jwksMaxAge.Seconds())))
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// passwordHasher is the configured hasher, argon2id when not configured
+func (s *Server) passwordHasher() passwordhash.PasswordHasher {
+	if s.PasswordHasher == nil {
+		return passwordhash.Default()
+	}
+	return s.PasswordHasher
+}
+
+func (s *Server) hashPassword(password string) (string, error) {
+	return s.passwordHasher().Hash(password)
+}
+
+// dummyPassword is hashed once, no user can log in with it since it is never stored
+const dummyPassword = "dummy password of unknown users"
+
+// verifyDummyPassword verifies the password against a hash made with the
+// configured hasher, so a login with an unknown phone number takes as long as
+// one with a wrong password.
+func (s *Server) verifyDummyPassword(password string) {
+	s.dummyHashOnce.Do(func() {
+		hash, err := s.passwordHasher().Hash(dummyPassword)
+		if err == nil {
+			s.dummyHash = hash
+		}
+	})
+	if s.dummyHash != "" {
+		_, _, _ = s.passwordHasher().Verify(s.dummyHash, password)
+	}
+}
+
+// comparePasswords leaves an outdated hash as is, only the login upgrades it
+func (s *Server) comparePasswords(hashedPwd string, plainPwd string) (bool, error) {
+	match, _, err := s.passwordHasher().Verify(hashedPwd, plainPwd)
+	return match, err
+}
+
+// rehashPassword upgrades the hash of the password the user just logged in
+// with, a failure only keeps the outdated hash.
+func (s *Server) rehashPassword(ctx echo.Context, userId int, currentHash string, password string) {
+	newHash, err := s.passwordHasher().Hash(password)
+	if err == nil {
+		err = s.Repository.RehashUserPassword(ctx.Request().Context(), repository.RehashUserPasswordInput{
+			Id:          userId,
+			CurrentHash: currentHash,
+			NewHash:     newHash,
+		})
+	}
+	if err != nil {
+		ctx.Logger().Errorf("request %s: password rehash of user %d: %v", requestIdFromContext(ctx), userId, err)
+	}
 }
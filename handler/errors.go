@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/labstack/echo/v4"
+)
+
+// MIMEApplicationProblemJSON is the content type of RFC 7807 error responses
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// Error codes are part of the API contract, clients rely on them instead of
+// the human readable detail. Never rename one.
+const (
+	ErrCodeInvalidRequest       = "invalid_request"
+	ErrCodeValidationFailed     = "validation_failed"
+	ErrCodeUnauthorized         = "unauthorized"
+	ErrCodeInvalidToken         = "invalid_token"
+	ErrCodeInvalidCredentials   = "invalid_credentials"
+	ErrCodeInvalidRefreshToken  = "invalid_refresh_token"
+	ErrCodeRefreshTokenReused   = "refresh_token_reused"
+	ErrCodeDuplicatePhoneNumber = "duplicate_phone_number"
+	ErrCodeDuplicateEmail       = "duplicate_email"
+	ErrCodeAccountLocked        = "account_locked"
+	ErrCodeRateLimited          = "rate_limited"
+	ErrCodeInvalidOTP           = "invalid_otp"
+	ErrCodePhoneNotVerified     = "phone_not_verified"
+	ErrCodeInvalidMFAToken      = "invalid_mfa_token"
+	ErrCodeInvalidMFACode       = "invalid_mfa_code"
+	ErrCodeTOTPAlreadyEnabled   = "totp_already_enabled"
+	ErrCodeInvalidWebauthn      = "invalid_webauthn_response"
+	ErrCodeNotFound             = "not_found"
+	ErrCodeConflict             = "conflict"
+	ErrCodePayloadTooLarge      = "payload_too_large"
+	ErrCodeUnsupportedMediaType = "unsupported_media_type"
+	ErrCodeMethodNotAllowed     = "method_not_allowed"
+	ErrCodeInternal             = "internal_error"
+)
+
+// Problem is an error answered to the client as a RFC 7807 problem by
+// HTTPErrorHandler. Handlers return it instead of writing error responses.
+type Problem struct {
+	Status int
+	Code   string
+	Detail string
+	Errors []generated.FieldError
+	// Header is added to the response, e.g. WWW-Authenticate
+	Header http.Header
+	// Err is the underlying cause, it is logged but never sent to the client
+	Err error
+}
+
+func newProblem(status int, code string, detail string) *Problem {
+	return &Problem{
+		Status: status,
+		Code:   code,
+		Detail: detail,
+	}
+}
+
+// internalError hides err from the client, only the request id allows to find it in the logs.
+func internalError(err error) *Problem {
+	return &Problem{
+		Status: http.StatusInternalServerError,
+		Code:   ErrCodeInternal,
+		Detail: "an unexpected error occurred",
+		Err:    err,
+	}
+}
+
+func validationProblem(errors []generated.FieldError) *Problem {
+	problem := newProblem(http.StatusBadRequest, ErrCodeValidationFailed, "request validation failed")
+	problem.Errors = errors
+	return problem
+}
+
+func (p *Problem) Error() string {
+	if p.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", p.Code, p.Detail, p.Err)
+	}
+	return fmt.Sprintf("%s: %s", p.Code, p.Detail)
+}
+
+func (p *Problem) Unwrap() error {
+	return p.Err
+}
+
+// HTTPErrorHandler writes every error returned by handlers and middlewares
+// as a problem+json response.
+func HTTPErrorHandler(err error, ctx echo.Context) {
+	if ctx.Response().Committed {
+		return
+	}
+
+	problem := toProblem(err)
+	if problem.Err != nil || problem.Status >= http.StatusInternalServerError {
+		ctx.Logger().Errorf("request %s: %v", requestIdFromContext(ctx), err)
+	}
+
+	for key, values := range problem.Header {
+		for _, value := range values {
+			ctx.Response().Header().Add(key, value)
+		}
+	}
+
+	if ctx.Request().Method == http.MethodHead {
+		_ = ctx.NoContent(problem.Status)
+		return
+	}
+
+	resp := generated.Problem{
+		Type:      "/problems/" + problem.Code,
+		Title:     http.StatusText(problem.Status),
+		Status:    problem.Status,
+		Code:      problem.Code,
+		Instance:  optionalString(ctx.Request().URL.Path),
+		Detail:    optionalString(problem.Detail),
+		RequestId: requestIdFromContext(ctx),
+	}
+	if len(problem.Errors) > 0 {
+		resp.Errors = &problem.Errors
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return
+	}
+	if err := ctx.Blob(problem.Status, MIMEApplicationProblemJSON, body); err != nil {
+		ctx.Logger().Error(err)
+	}
+}
+
+func toProblem(err error) *Problem {
+	var problem *Problem
+	if errors.As(err, &problem) {
+		// the repository errors no handler expected keep their meaning
+		if problem.Status == http.StatusInternalServerError {
+			if repositoryProblem := toRepositoryProblem(problem.Err); repositoryProblem != nil {
+				return repositoryProblem
+			}
+		}
+		return problem
+	}
+
+	// errors raised by echo itself, e.g. unknown route
+	var httpError *echo.HTTPError
+	if errors.As(err, &httpError) {
+		code := ErrCodeInvalidRequest
+		switch httpError.Code {
+		case http.StatusNotFound:
+			code = ErrCodeNotFound
+		case http.StatusMethodNotAllowed:
+			code = ErrCodeMethodNotAllowed
+		case http.StatusUnauthorized:
+			code = ErrCodeUnauthorized
+		case http.StatusRequestEntityTooLarge:
+			code = ErrCodePayloadTooLarge
+		}
+		if httpError.Code >= http.StatusInternalServerError {
+			return internalError(err)
+		}
+		return newProblem(httpError.Code, code, fmt.Sprint(httpError.Message))
+	}
+
+	return internalError(err)
+}
+
+// toRepositoryProblem answers the errors of the repository: a row gone in the
+// meantime is a 404, a conflict with another request a 409. It returns nil
+// for any other error.
+func toRepositoryProblem(err error) *Problem {
+	var problem *Problem
+	switch {
+	case errors.Is(err, repository.ErrNotFound), errors.Is(err, repository.ErrForeignKeyViolation):
+		problem = newProblem(http.StatusNotFound, ErrCodeNotFound, "resource not found")
+	case errors.Is(err, repository.ErrUniqueViolation):
+		problem = newProblem(http.StatusConflict, ErrCodeConflict, "resource already exists")
+	case errors.Is(err, repository.ErrSerialization):
+		problem = newProblem(http.StatusConflict, ErrCodeConflict, "the request conflicted with a concurrent one, try again")
+	default:
+		return nil
+	}
+	problem.Err = err
+	return problem
+}
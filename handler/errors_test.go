@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// assertProblem checks the error returned by a handler called directly
+func assertProblem(t *testing.T, err error, status int, code string) {
+	t.Helper()
+
+	var problem *Problem
+	if assert.ErrorAs(t, err, &problem) {
+		assert.Equal(t, status, problem.Status)
+		assert.Equal(t, code, problem.Code)
+	}
+}
+
+func TestHTTPErrorHandler_Problem(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(RequestIdMiddleware())
+	e.GET("/test", func(ctx echo.Context) error {
+		return validationProblem([]generated.FieldError{{Field: "name", Rule: "required", Message: "value is required"}})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set(echo.HeaderXRequestID, "my-request-id")
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, "my-request-id", rec.Header().Get(echo.HeaderXRequestID))
+	assert.JSONEq(t, `{
+		"type": "/problems/validation_failed",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "request validation failed",
+		"instance": "/test",
+		"code": "validation_failed",
+		"requestId": "my-request-id",
+		"errors": [{"field": "name", "rule": "required", "message": "value is required"}]
+	}`, rec.Body.String())
+}
+
+func TestHTTPErrorHandler_InternalError(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(RequestIdMiddleware())
+	e.GET("/test", func(ctx echo.Context) error {
+		return errors.New("pq: connection refused")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	// the cause is never leaked to the client
+	assert.NotContains(t, rec.Body.String(), "connection refused")
+
+	var resp generated.Problem
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, ErrCodeInternal, resp.Code)
+	assert.NotEmpty(t, resp.RequestId)
+	assert.Equal(t, rec.Header().Get(echo.HeaderXRequestID), resp.RequestId)
+}
+
+func TestHTTPErrorHandler_RepositoryError(t *testing.T) {
+	testCases := map[string]struct {
+		err    error
+		status int
+		code   string
+	}{
+		"not found":     {err: repository.ErrNotFound, status: http.StatusNotFound, code: ErrCodeNotFound},
+		"foreign key":   {err: &repository.ConstraintError{Kind: repository.ErrForeignKeyViolation, Constraint: "refresh_tokens_user_id_fkey"}, status: http.StatusNotFound, code: ErrCodeNotFound},
+		"unique":        {err: &repository.ConstraintError{Kind: repository.ErrUniqueViolation, Constraint: "refresh_tokens_token_hash_key"}, status: http.StatusConflict, code: ErrCodeConflict},
+		"serialization": {err: fmt.Errorf("%w: pq: could not serialize access", repository.ErrSerialization), status: http.StatusConflict, code: ErrCodeConflict},
+		"other":         {err: errors.New("pq: connection refused"), status: http.StatusInternalServerError, code: ErrCodeInternal},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			e := echo.New()
+			e.HTTPErrorHandler = HTTPErrorHandler
+			// the handler didn't expect the error
+			e.GET("/test", func(ctx echo.Context) error {
+				return internalError(testCase.err)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			rec := httptest.NewRecorder()
+
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, testCase.status, rec.Code)
+			var resp generated.Problem
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, testCase.code, resp.Code)
+			assert.NotContains(t, rec.Body.String(), "pq:")
+		})
+	}
+}
+
+func TestHTTPErrorHandler_NotFound(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+
+	req := httptest.NewRequest(http.MethodGet, "/unknown", nil)
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	var resp generated.Problem
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, ErrCodeNotFound, resp.Code)
+}
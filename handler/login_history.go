@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+)
+
+const (
+	defaultLoginHistoryLimit = 20
+	// maxLoginHistoryLimit is the maximum of the limit parameter in api.yml
+	maxLoginHistoryLimit = 100
+	maxUserAgentLength   = 512
+)
+
+// failure reasons of the login history, part of the API contract as the LoginEvent.failureReason enum
+const (
+	loginFailureInvalidPassword  = "invalid_password"
+	loginFailureAccountLocked    = "account_locked"
+	loginFailurePhoneNotVerified = "phone_not_verified"
+	loginFailureInvalidMFACode   = "invalid_mfa_code"
+)
+
+// the cursor is opaque to clients, it only wraps the id of the last event of the page
+func encodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeCursor(cursor string) (int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if id <= 0 {
+		return 0, errors.New("cursor id must be positive")
+	}
+	return id, nil
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListMyLogins_Success(t *testing.T) {
+	e := echo.New()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// Mock the Server struct
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	server := &Server{
+		Repository: mockRepo,
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/my-profile/logins", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(principalContextKey, newTestPrincipal())
+
+	// a third event is returned, so there is a next page
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	failureReason := loginFailureInvalidPassword
+	limit := 2
+	mockRepo.EXPECT().ListLoginEvents(gomock.Any(), repository.ListLoginEventsInput{UserId: 1, BeforeId: 0, Limit: 3}).Return(
+		repository.ListLoginEventsOutput{Events: []repository.LoginEvent{
+			{Id: 30, Success: true, IpAddress: "192.0.2.1", UserAgent: "curl/8.0", CreatedAt: createdAt},
+			{Id: 20, Success: false, FailureReason: &failureReason, IpAddress: "192.0.2.1", UserAgent: "curl/8.0", CreatedAt: createdAt},
+			{Id: 10, Success: true, IpAddress: "192.0.2.1", UserAgent: "curl/8.0", CreatedAt: createdAt},
+		}},
+		nil,
+	)
+
+	err := server.ListMyLogins(c, generated.ListMyLoginsParams{Limit: &limit})
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp generated.LoginHistoryResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Len(t, resp.Items, 2)
+	assert.Equal(t, generated.InvalidPassword, *resp.Items[1].FailureReason)
+	if assert.NotNil(t, resp.NextCursor) {
+		beforeId, err := decodeCursor(*resp.NextCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(20), beforeId)
+	}
+}
+
+func TestListMyLogins_Success_LastPage(t *testing.T) {
+	e := echo.New()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// Mock the Server struct
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	server := &Server{
+		Repository: mockRepo,
+	}
+
+	cursor := encodeCursor(20)
+	req := httptest.NewRequest(http.MethodGet, "/my-profile/logins?cursor="+cursor, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(principalContextKey, newTestPrincipal())
+
+	mockRepo.EXPECT().ListLoginEvents(gomock.Any(), repository.ListLoginEventsInput{UserId: 1, BeforeId: 20, Limit: defaultLoginHistoryLimit + 1}).Return(
+		repository.ListLoginEventsOutput{Events: []repository.LoginEvent{
+			{Id: 10, Success: true, IpAddress: "192.0.2.1", UserAgent: "curl/8.0", CreatedAt: time.Now()},
+		}},
+		nil,
+	)
+
+	err := server.ListMyLogins(c, generated.ListMyLoginsParams{Cursor: &cursor})
+
+	assert.NoError(t, err)
+
+	var resp generated.LoginHistoryResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Len(t, resp.Items, 1)
+	assert.Nil(t, resp.NextCursor)
+}
+
+func TestListMyLogins_Error_InvalidCursor(t *testing.T) {
+	e := echo.New()
+	server := &Server{}
+
+	req := httptest.NewRequest(http.MethodGet, "/my-profile/logins", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(principalContextKey, newTestPrincipal())
+
+	cursor := "not a cursor"
+	err := server.ListMyLogins(c, generated.ListMyLoginsParams{Cursor: &cursor})
+
+	assertProblem(t, err, http.StatusBadRequest, ErrCodeValidationFailed)
+}
+
+func TestListMyLogins_Error_InvalidLimit(t *testing.T) {
+	for _, limit := range []int{0, -1, maxLoginHistoryLimit + 1} {
+		t.Run(strconv.Itoa(limit), func(t *testing.T) {
+			e := echo.New()
+			server := &Server{}
+
+			req := httptest.NewRequest(http.MethodGet, "/my-profile/logins", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set(principalContextKey, newTestPrincipal())
+
+			// the repository is never queried
+			err := server.ListMyLogins(c, generated.ListMyLoginsParams{Limit: &limit})
+
+			assertProblem(t, err, http.StatusBadRequest, ErrCodeValidationFailed)
+		})
+	}
+}
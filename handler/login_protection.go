@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LoginProtectionOptions configures the brute-force protection of the login.
+type LoginProtectionOptions struct {
+	// MaxFailedAttempts is the number of consecutive failed logins locking the account
+	MaxFailedAttempts int
+	// LockoutDuration is the first lock, it doubles on every further failure up to MaxLockoutDuration
+	LockoutDuration    time.Duration
+	MaxLockoutDuration time.Duration
+	// IPRateLimit is the number of login attempts allowed per client IP in every IPRateWindow
+	IPRateLimit  int
+	IPRateWindow time.Duration
+}
+
+// DefaultLoginProtectionOptions locks an account for 1 minute after 5 failures
+// and allows 20 login attempts per IP and minute.
+func DefaultLoginProtectionOptions() LoginProtectionOptions {
+	return LoginProtectionOptions{
+		MaxFailedAttempts:  5,
+		LockoutDuration:    time.Minute,
+		MaxLockoutDuration: time.Hour,
+		IPRateLimit:        20,
+		IPRateWindow:       time.Minute,
+	}
+}
+
+// LoginProtectionOptionsFromEnv reads the options from the environment, unset
+// variables keep their default value.
+func LoginProtectionOptionsFromEnv() (LoginProtectionOptions, error) {
+	opts := DefaultLoginProtectionOptions()
+
+	ints := map[string]*int{
+		"LOGIN_MAX_FAILED_ATTEMPTS": &opts.MaxFailedAttempts,
+		"LOGIN_IP_RATE_LIMIT":       &opts.IPRateLimit,
+	}
+	for name, target := range ints {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 {
+				return opts, fmt.Errorf("%s must be a positive integer", name)
+			}
+			*target = parsed
+		}
+	}
+
+	durations := map[string]*time.Duration{
+		"LOGIN_LOCKOUT_DURATION":     &opts.LockoutDuration,
+		"LOGIN_MAX_LOCKOUT_DURATION": &opts.MaxLockoutDuration,
+		"LOGIN_IP_RATE_WINDOW":       &opts.IPRateWindow,
+	}
+	for name, target := range durations {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil || parsed <= 0 {
+				return opts, fmt.Errorf("%s must be a positive duration, e.g. 30s", name)
+			}
+			*target = parsed
+		}
+	}
+
+	return opts, nil
+}
+
+// lockoutDuration returns how long the account is locked after the given
+// number of consecutive failures, 0 when it is not locked.
+func (o LoginProtectionOptions) lockoutDuration(failedLoginCount int) time.Duration {
+	if o.MaxFailedAttempts <= 0 || failedLoginCount < o.MaxFailedAttempts {
+		return 0
+	}
+
+	lockout := o.LockoutDuration
+	for i := o.MaxFailedAttempts; i < failedLoginCount && lockout < o.MaxLockoutDuration; i++ {
+		lockout *= 2
+	}
+	if o.MaxLockoutDuration > 0 && lockout > o.MaxLockoutDuration {
+		lockout = o.MaxLockoutDuration
+	}
+	return lockout
+}
+
+// RateLimiter is a fixed window rate limiter kept in memory, each instance of
+// the service limits on its own.
+type RateLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	windows   map[string]*rateWindow
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+type rateWindow struct {
+	count   int
+	resetAt time.Time
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[string]*rateWindow),
+		now:     time.Now,
+	}
+}
+
+// Allow counts an attempt for key, when the limit is reached it returns false
+// and the time left before the next attempt is allowed.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	w, ok := l.windows[key]
+	if !ok || !now.Before(w.resetAt) {
+		w = &rateWindow{resetAt: now.Add(l.window)}
+		l.windows[key] = w
+	}
+
+	if w.count >= l.limit {
+		return false, w.resetAt.Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// prune drops the elapsed windows, at most once per window.
+func (l *RateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < l.window {
+		return
+	}
+	l.lastPrune = now
+
+	for key, w := range l.windows {
+		if !now.Before(w.resetAt) {
+			delete(l.windows, key)
+		}
+	}
+}
+
+// FailedLoginTracker counts, in memory, the consecutive failed logins of the
+// phone numbers that are not registered, so they are locked the way accounts
+// are and the lock doesn't tell a registered phone number. Each instance of
+// the service counts on its own.
+type FailedLoginTracker struct {
+	mu        sync.Mutex
+	options   LoginProtectionOptions
+	entries   map[string]*failedLogins
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+type failedLogins struct {
+	count       int
+	lockedUntil time.Time
+	lastFailure time.Time
+}
+
+func NewFailedLoginTracker(options LoginProtectionOptions) *FailedLoginTracker {
+	return &FailedLoginTracker{
+		options: options,
+		entries: make(map[string]*failedLogins),
+		now:     time.Now,
+	}
+}
+
+// LockedUntil returns when the lock of key ends, the zero time when it is not locked.
+func (t *FailedLoginTracker) LockedUntil(key string) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.entries[key]
+	if !ok || !t.now().Before(entry.lockedUntil) {
+		return time.Time{}
+	}
+	return entry.lockedUntil
+}
+
+// RecordFailure counts the failure and locks key once there are too many in a row.
+func (t *FailedLoginTracker) RecordFailure(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.prune(now)
+
+	entry, ok := t.entries[key]
+	if !ok {
+		entry = &failedLogins{}
+		t.entries[key] = entry
+	}
+	entry.count++
+	entry.lastFailure = now
+	if lockout := t.options.lockoutDuration(entry.count); lockout > 0 {
+		entry.lockedUntil = now.Add(lockout)
+	}
+}
+
+// prune drops the unlocked entries without failure for the longest lock, at
+// most once per longest lock, the caller must hold the lock.
+func (t *FailedLoginTracker) prune(now time.Time) {
+	if now.Sub(t.lastPrune) < t.options.MaxLockoutDuration {
+		return
+	}
+	t.lastPrune = now
+
+	for key, entry := range t.entries {
+		if !now.Before(entry.lockedUntil) && now.Sub(entry.lastFailure) >= t.options.MaxLockoutDuration {
+			delete(t.entries, key)
+		}
+	}
+}
+
+// accountLocked builds the 429 problem of a locked account.
+func accountLocked(lockedUntil time.Time) *Problem {
+	return tooManyRequests(ErrCodeAccountLocked, "account is temporarily locked after too many failed logins", time.Until(lockedUntil))
+}
+
+// tooManyRequests builds the 429 problem, the client may retry after retryAfter.
+func tooManyRequests(code string, detail string, retryAfter time.Duration) *Problem {
+	problem := newProblem(http.StatusTooManyRequests, code, detail)
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	problem.Header = http.Header{"Retry-After": []string{strconv.Itoa(seconds)}}
+	return problem
+}
@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginProtectionOptions_lockoutDuration(t *testing.T) {
+	opts := LoginProtectionOptions{
+		MaxFailedAttempts:  3,
+		LockoutDuration:    time.Minute,
+		MaxLockoutDuration: 5 * time.Minute,
+	}
+
+	testCases := map[int]time.Duration{
+		1: 0,
+		2: 0,
+		3: time.Minute,
+		4: 2 * time.Minute,
+		5: 4 * time.Minute,
+		6: 5 * time.Minute,
+		// the cap must hold whatever the count
+		1000: 5 * time.Minute,
+	}
+	for failedLoginCount, expected := range testCases {
+		assert.Equal(t, expected, opts.lockoutDuration(failedLoginCount), "failedLoginCount %d", failedLoginCount)
+	}
+}
+
+func TestLoginProtectionOptionsFromEnv(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILED_ATTEMPTS", "10")
+	t.Setenv("LOGIN_LOCKOUT_DURATION", "30s")
+
+	opts, err := LoginProtectionOptionsFromEnv()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 10, opts.MaxFailedAttempts)
+	assert.Equal(t, 30*time.Second, opts.LockoutDuration)
+	assert.Equal(t, DefaultLoginProtectionOptions().IPRateLimit, opts.IPRateLimit)
+
+	t.Setenv("LOGIN_IP_RATE_WINDOW", "1 minute")
+	_, err = LoginProtectionOptionsFromEnv()
+	assert.Error(t, err)
+}
+
+func TestRateLimiter_Allow(t *testing.T) {
+	now := time.Now()
+	limiter := NewRateLimiter(2, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	ok, _ := limiter.Allow("10.0.0.1")
+	assert.True(t, ok)
+	ok, _ = limiter.Allow("10.0.0.1")
+	assert.True(t, ok)
+
+	ok, retryAfter := limiter.Allow("10.0.0.1")
+	assert.False(t, ok)
+	assert.Equal(t, time.Minute, retryAfter)
+
+	// every key has its own window
+	ok, _ = limiter.Allow("10.0.0.2")
+	assert.True(t, ok)
+
+	now = now.Add(time.Minute)
+	ok, _ = limiter.Allow("10.0.0.1")
+	assert.True(t, ok)
+}
+
+func TestFailedLoginTracker(t *testing.T) {
+	now := time.Now()
+	tracker := NewFailedLoginTracker(LoginProtectionOptions{MaxFailedAttempts: 2, LockoutDuration: time.Minute, MaxLockoutDuration: time.Hour})
+	tracker.now = func() time.Time { return now }
+
+	tracker.RecordFailure("+6282222222")
+	assert.True(t, tracker.LockedUntil("+6282222222").IsZero())
+	tracker.RecordFailure("+6282222222")
+	assert.Equal(t, now.Add(time.Minute), tracker.LockedUntil("+6282222222"))
+	assert.True(t, tracker.LockedUntil("+6283333333").IsZero())
+
+	// the lock doubles on the next failure, as it does for accounts
+	now = now.Add(time.Minute)
+	assert.True(t, tracker.LockedUntil("+6282222222").IsZero())
+	tracker.RecordFailure("+6282222222")
+	assert.Equal(t, now.Add(2*time.Minute), tracker.LockedUntil("+6282222222"))
+}
+
+func newTestLoginContext(e *echo.Echo) (echo.Context, *httptest.ResponseRecorder) {
+	body := generated.LoginParam{
+		Password:    "my@Password11",
+		PhoneNumber: "+6282222222",
+	}
+
+	jsonBytes, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(jsonBytes))
+	rec := httptest.NewRecorder()
+	return e.NewContext(req, rec), rec
+}
+
+func TestLogin_Error_LocksAccount(t *testing.T) {
+	e := echo.New()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// Mock the Server struct
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	server := &Server{
+		Repository:      mockRepo,
+		KeyManager:      testKeyManager,
+		LoginProtection: DefaultLoginProtectionOptions(),
+	}
+	c, _ := newTestLoginContext(e)
+
+	// Set up the expected behavior of the mock
+	mockRepo.EXPECT().GetUserByPhoneNumber(gomock.Any(), gomock.Any()).Return(
+		repository.GetLoginOutput{Id: 1, Password: "$2a$04$BN7qD4ROTQKOoagz6Ez5xucaSFNkKWYhT9UJF7pd4jgKvaRsLBKFW", FailedLoginCount: 4},
+		nil,
+	)
+	mockRepo.EXPECT().CreateLoginEvent(gomock.Any(), repository.CreateLoginEventInput{UserId: 1, Success: false, FailureReason: "invalid_password", IpAddress: "192.0.2.1"}).Return(
+		nil,
+	)
+	mockRepo.EXPECT().RecordFailedLogin(gomock.Any(), repository.RecordFailedLoginInput{Id: 1}).Return(
+		repository.RecordFailedLoginOutput{FailedLoginCount: 5},
+		nil,
+	)
+	mockRepo.EXPECT().LockUser(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, input repository.LockUserInput) error {
+			assert.Equal(t, 1, input.Id)
+			assert.WithinDuration(t, time.Now().Add(time.Minute), input.LockedUntil, 5*time.Second)
+			return nil
+		},
+	)
+
+	err := server.Login(c)
+
+	assertProblem(t, err, http.StatusUnauthorized, ErrCodeInvalidCredentials)
+}
+
+func TestLogin_Error_AccountLocked(t *testing.T) {
+	e := echo.New()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// Mock the Server struct
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	server := &Server{
+		Repository:      mockRepo,
+		KeyManager:      testKeyManager,
+		LoginProtection: DefaultLoginProtectionOptions(),
+	}
+	c, _ := newTestLoginContext(e)
+
+	// the password is not even checked, neither is the failure counted
+	lockedUntil := time.Now().Add(90 * time.Second)
+	mockRepo.EXPECT().GetUserByPhoneNumber(gomock.Any(), gomock.Any()).Return(
+		repository.GetLoginOutput{Id: 1, Password: "$2a$04$BN7qD4ROTQKOoagz6Ez5xucaSFNkKWYhT9UJF7pd4jgKvaRsLBKFW", LockedUntil: &lockedUntil},
+		nil,
+	)
+	mockRepo.EXPECT().CreateLoginEvent(gomock.Any(), repository.CreateLoginEventInput{UserId: 1, Success: false, FailureReason: "account_locked", IpAddress: "192.0.2.1"}).Return(
+		nil,
+	)
+
+	err := server.Login(c)
+
+	assertProblem(t, err, http.StatusTooManyRequests, ErrCodeAccountLocked)
+	assert.Equal(t, "90", err.(*Problem).Header.Get("Retry-After"))
+}
+
+func TestLogin_Error_UnknownPhoneNumberLocked(t *testing.T) {
+	e := echo.New()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// Mock the Server struct
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	server := &Server{
+		Repository:          mockRepo,
+		LoginProtection:     DefaultLoginProtectionOptions(),
+		UnknownPhoneLockout: NewFailedLoginTracker(DefaultLoginProtectionOptions()),
+	}
+
+	mockRepo.EXPECT().GetUserByPhoneNumber(gomock.Any(), gomock.Any()).Return(
+		repository.GetLoginOutput{},
+		repository.ErrNotFound,
+	).Times(DefaultLoginProtectionOptions().MaxFailedAttempts + 1)
+
+	for i := 0; i < DefaultLoginProtectionOptions().MaxFailedAttempts; i++ {
+		c, _ := newTestLoginContext(e)
+		assertProblem(t, server.Login(c), http.StatusUnauthorized, ErrCodeInvalidCredentials)
+	}
+
+	// locked after as many failures as an account, so the lock tells nothing
+	c, _ := newTestLoginContext(e)
+	err := server.Login(c)
+
+	assertProblem(t, err, http.StatusTooManyRequests, ErrCodeAccountLocked)
+	assert.Equal(t, "60", err.(*Problem).Header.Get("Retry-After"))
+}
+
+func TestLogin_Error_RateLimited(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// Mock the Server struct
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	server := &Server{
+		Repository:       mockRepo,
+		KeyManager:       testKeyManager,
+		LoginProtection:  DefaultLoginProtectionOptions(),
+		LoginRateLimiter: NewRateLimiter(1, time.Minute),
+	}
+	e := newTestEcho(t, server)
+
+	mockRepo.EXPECT().GetUserByPhoneNumber(gomock.Any(), gomock.Any()).Return(
+		repository.GetLoginOutput{},
+		assert.AnError,
+	)
+
+	for _, expected := range []int{http.StatusInternalServerError, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader([]byte(`{"phoneNumber":"+6282222222","password":"my@Password1"}`)))
+		rec := httptest.NewRecorder()
+
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, expected, rec.Code)
+	}
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+)
+
+const (
+	// TOTP parameters of RFC 6238, the defaults every authenticator app supports
+	totpPeriod     = 30 * time.Second
+	totpDigits     = 6
+	totpSecretSize = 20
+	// codes of the previous and next period are accepted too, for clock drift
+	totpSkew   = 1
+	totpIssuer = "UserService"
+
+	mfaChallengeTTL   = 5 * time.Minute
+	mfaMaxAttempts    = 5
+	recoveryCodeCount = 10
+)
+
+var (
+	totpCodePattern = regexp.MustCompile(`^[0-9]{6}$`)
+	base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+)
+
+// this function for generate the shared secret of an authenticator app, base32 encoded
+func generateTOTPSecret() (string, error) {
+	b := make([]byte, totpSecretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32NoPadding.EncodeToString(b), nil
+}
+
+// totpCode computes the HOTP value (RFC 4226) of the time step.
+func totpCode(secret []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// validateTOTP checks the code against the steps around now and returns the
+// matching step, the caller must make sure a step is never accepted twice.
+func validateTOTP(secret string, code string, now time.Time) (int64, bool) {
+	key, err := base32NoPadding.DecodeString(strings.ToUpper(secret))
+	if err != nil || !totpCodePattern.MatchString(code) {
+		return 0, false
+	}
+
+	current := now.Unix() / int64(totpPeriod.Seconds())
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpURI is the otpauth URI authenticator apps scan as a QR code
+func totpURI(secret string, accountName string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", totpIssuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(int(totpPeriod.Seconds())))
+
+	uri := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + totpIssuer + ":" + accountName,
+		RawQuery: query.Encode(),
+	}
+	return uri.String()
+}
+
+// this function for generate a recovery code, formatted xxxxx-xxxxx to be written down
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32NoPadding.EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// only the sha256 of the recovery codes is stored, they are random enough not
+// to need a salt. Case and separators don't matter.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// startMFAChallenge creates the challenge a user with two-factor
+// authentication gets instead of the tokens, it returns the plain token.
+func (s *Server) startMFAChallenge(ctx context.Context, userId int) (string, error) {
+	mfaToken, err := generateRandomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.Repository.CreateMfaChallenge(ctx, repository.CreateMfaChallengeInput{
+		UserId:    userId,
+		TokenHash: hashRefreshToken(mfaToken),
+		ExpiresAt: time.Now().Add(mfaChallengeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return mfaToken, nil
+}
+
+// verifySecondFactor accepts either a TOTP code or an unused recovery code.
+func (s *Server) verifySecondFactor(ctx context.Context, userId int, secret string, code string) (bool, error) {
+	if totpCodePattern.MatchString(code) {
+		return s.acceptTOTPCode(ctx, userId, secret, code)
+	}
+
+	res, err := s.Repository.ConsumeRecoveryCode(ctx, repository.ConsumeRecoveryCodeInput{
+		UserId:   userId,
+		CodeHash: hashRecoveryCode(code),
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.Consumed, nil
+}
+
+// acceptTOTPCode checks the code and records its step, so it can't be replayed.
+func (s *Server) acceptTOTPCode(ctx context.Context, userId int, secret string, code string) (bool, error) {
+	step, ok := validateTOTP(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	res, err := s.Repository.UseTotpStep(ctx, repository.UseTotpStepInput{
+		UserId: userId,
+		Step:   step,
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.Used, nil
+}
@@ -0,0 +1,395 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// secret of the RFC 6238 test vectors
+var testTOTPSecret = base32NoPadding.EncodeToString([]byte("12345678901234567890"))
+
+func TestValidateTOTP(t *testing.T) {
+	// RFC 6238 appendix B, truncated to 6 digits
+	testCases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, code := range testCases {
+		step, ok := validateTOTP(testTOTPSecret, code, time.Unix(unix, 0))
+		assert.True(t, ok, "time %d", unix)
+		assert.Equal(t, unix/30, step)
+	}
+
+	// one period of clock drift is accepted, two are not
+	_, ok := validateTOTP(testTOTPSecret, "287082", time.Unix(59+30, 0))
+	assert.True(t, ok)
+	_, ok = validateTOTP(testTOTPSecret, "287082", time.Unix(59+60, 0))
+	assert.False(t, ok)
+
+	_, ok = validateTOTP(testTOTPSecret, "287083", time.Unix(59, 0))
+	assert.False(t, ok)
+	_, ok = validateTOTP("not base32!", "287082", time.Unix(59, 0))
+	assert.False(t, ok)
+}
+
+func TestTotpURI(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	assert.NoError(t, err)
+	key, err := base32NoPadding.DecodeString(secret)
+	assert.NoError(t, err)
+	assert.Len(t, key, totpSecretSize)
+
+	uri, err := url.Parse(totpURI(secret, "+6282222222"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/UserService:+6282222222", uri.Path)
+	assert.Equal(t, secret, uri.Query().Get("secret"))
+	assert.Equal(t, "UserService", uri.Query().Get("issuer"))
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	code, err := generateRecoveryCode()
+
+	assert.NoError(t, err)
+	assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, code)
+	// typed back without the dash or in upper case, it is still the same code
+	assert.Equal(t, hashRecoveryCode(code), hashRecoveryCode(code[:5]+code[6:]))
+	assert.Equal(t, hashRecoveryCode("abcde-fghij"), hashRecoveryCode("ABCDE FGHIJ"))
+	assert.NotEqual(t, hashRecoveryCode("abcde-fghij"), hashRecoveryCode("abcde-fghik"))
+}
+
+func TestLogin_Success_MfaRequired(t *testing.T) {
+	e := echo.New()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// Mock the Server struct
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	server := &Server{
+		Repository:     mockRepo,
+		KeyManager:     testKeyManager,
+		PasswordHasher: testPasswordHasher,
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader([]byte(`{"phoneNumber":"+6282222222","password":"my@Password1"}`)))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// no token is issued and the login is not recorded yet
+	var created repository.CreateMfaChallengeInput
+	mockRepo.EXPECT().GetUserByPhoneNumber(gomock.Any(), gomock.Any()).Return(
+		repository.GetLoginOutput{Id: 1, Password: "$2a$04$BN7qD4ROTQKOoagz6Ez5xucaSFNkKWYhT9UJF7pd4jgKvaRsLBKFW", TotpEnabled: true},
+		nil,
+	)
+	mockRepo.EXPECT().CreateMfaChallenge(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, input repository.CreateMfaChallengeInput) error {
+			created = input
+			return nil
+		},
+	)
+
+	err := server.Login(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp generated.MfaChallengeResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.True(t, resp.MfaRequired)
+	assert.Equal(t, int(mfaChallengeTTL.Seconds()), resp.ExpiresIn)
+	assert.Equal(t, 1, created.UserId)
+	assert.Equal(t, hashRefreshToken(resp.MfaToken), created.TokenHash)
+	assert.NotContains(t, rec.Body.String(), "refreshToken")
+}
+
+func newTestLoginMfaContext(e *echo.Echo, code string) (echo.Context, *httptest.ResponseRecorder) {
+	body := generated.LoginMfaParam{
+		MfaToken: "mfa-token",
+		Code:     code,
+	}
+
+	jsonBytes, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/login/mfa", bytes.NewReader(jsonBytes))
+	rec := httptest.NewRecorder()
+	return e.NewContext(req, rec), rec
+}
+
+func newTestMfaChallenge() repository.GetMfaChallengeOutput {
+	return repository.GetMfaChallengeOutput{
+		Id:         3,
+		UserId:     1,
+		ExpiresAt:  time.Now().Add(time.Minute),
+		TotpSecret: testTOTPSecret,
+	}
+}
+
+func TestLoginMfa_Success(t *testing.T) {
+	e := echo.New()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// Mock the Server struct
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	server := &Server{
+		Repository: mockRepo,
+		KeyManager: testKeyManager,
+	}
+	step := time.Now().Unix() / 30
+	c, rec := newTestLoginMfaContext(e, totpCode([]byte("12345678901234567890"), step))
+
+	// Set up the expected behavior of the mock
+	mockRepo.EXPECT().GetMfaChallengeByHash(gomock.Any(), repository.GetMfaChallengeInput{TokenHash: hashRefreshToken("mfa-token")}).Return(
+		newTestMfaChallenge(),
+		nil,
+	)
+	mockRepo.EXPECT().IncrementMfaChallengeAttempts(gomock.Any(), repository.IncrementMfaChallengeAttemptsInput{Id: 3}).Return(
+		repository.IncrementMfaChallengeAttemptsOutput{Attempts: 1},
+		nil,
+	)
+	mockRepo.EXPECT().UseTotpStep(gomock.Any(), repository.UseTotpStepInput{UserId: 1, Step: step}).Return(
+		repository.UseTotpStepOutput{Used: true},
+		nil,
+	)
+	mockRepo.EXPECT().ConsumeMfaChallenge(gomock.Any(), repository.ConsumeMfaChallengeInput{Id: 3}).Return(
+		repository.ConsumeMfaChallengeOutput{Consumed: true},
+		nil,
+	)
+	mockRepo.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).Return(
+		nil,
+	)
+	mockRepo.EXPECT().UpdateUserSuccesLogin(gomock.Any(), repository.PostUpdateUserSuccesLoginInput{Id: 1}).Return(
+		nil,
+	)
+	mockRepo.EXPECT().CreateLoginEvent(gomock.Any(), repository.CreateLoginEventInput{UserId: 1, Success: true, IpAddress: "192.0.2.1"}).Return(
+		nil,
+	)
+
+	err := server.LoginMfa(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp generated.LoginResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Id)
+	assert.NotEmpty(t, resp.Token)
+	assert.NotEmpty(t, resp.RefreshToken)
+}
+
+func TestLoginMfa_Success_RecoveryCode(t *testing.T) {
+	e := echo.New()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// Mock the Server struct
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	server := &Server{
+		Repository: mockRepo,
+		KeyManager: testKeyManager,
+	}
+	c, rec := newTestLoginMfaContext(e, "abcde-fghij")
+
+	// Set up the expected behavior of the mock
+	mockRepo.EXPECT().GetMfaChallengeByHash(gomock.Any(), gomock.Any()).Return(
+		newTestMfaChallenge(),
+		nil,
+	)
+	mockRepo.EXPECT().IncrementMfaChallengeAttempts(gomock.Any(), gomock.Any()).Return(
+		repository.IncrementMfaChallengeAttemptsOutput{Attempts: 1},
+		nil,
+	)
+	mockRepo.EXPECT().ConsumeRecoveryCode(gomock.Any(), repository.ConsumeRecoveryCodeInput{UserId: 1, CodeHash: hashRecoveryCode("abcde-fghij")}).Return(
+		repository.ConsumeRecoveryCodeOutput{Consumed: true},
+		nil,
+	)
+	mockRepo.EXPECT().ConsumeMfaChallenge(gomock.Any(), gomock.Any()).Return(
+		repository.ConsumeMfaChallengeOutput{Consumed: true},
+		nil,
+	)
+	mockRepo.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).Return(
+		nil,
+	)
+	mockRepo.EXPECT().UpdateUserSuccesLogin(gomock.Any(), gomock.Any()).Return(
+		nil,
+	)
+	mockRepo.EXPECT().CreateLoginEvent(gomock.Any(), gomock.Any()).Return(
+		nil,
+	)
+
+	err := server.LoginMfa(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestLoginMfa_Error_InvalidCode(t *testing.T) {
+	e := echo.New()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// Mock the Server struct
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	server := &Server{
+		Repository: mockRepo,
+		KeyManager: testKeyManager,
+	}
+	step := time.Now().Unix() / 30
+	c, _ := newTestLoginMfaContext(e, totpCode([]byte("12345678901234567890"), step))
+
+	// the code is right but was already used, it can't be replayed
+	mockRepo.EXPECT().GetMfaChallengeByHash(gomock.Any(), gomock.Any()).Return(
+		newTestMfaChallenge(),
+		nil,
+	)
+	mockRepo.EXPECT().IncrementMfaChallengeAttempts(gomock.Any(), gomock.Any()).Return(
+		repository.IncrementMfaChallengeAttemptsOutput{Attempts: 2},
+		nil,
+	)
+	mockRepo.EXPECT().UseTotpStep(gomock.Any(), gomock.Any()).Return(
+		repository.UseTotpStepOutput{Used: false},
+		nil,
+	)
+	mockRepo.EXPECT().CreateLoginEvent(gomock.Any(), repository.CreateLoginEventInput{UserId: 1, Success: false, FailureReason: "invalid_mfa_code", IpAddress: "192.0.2.1"}).Return(
+		nil,
+	)
+
+	err := server.LoginMfa(c)
+
+	assertProblem(t, err, http.StatusBadRequest, ErrCodeInvalidMFACode)
+}
+
+func TestLoginMfa_Error_InvalidToken(t *testing.T) {
+	expired := newTestMfaChallenge()
+	expired.ExpiresAt = time.Now().Add(-time.Second)
+	usedAt := time.Now()
+	used := newTestMfaChallenge()
+	used.UsedAt = &usedAt
+
+	testCases := map[string]struct {
+		challenge repository.GetMfaChallengeOutput
+		err       error
+		attempts  int
+	}{
+		"unknown":           {err: repository.ErrNotFound},
+		"expired":           {challenge: expired},
+		"already used":      {challenge: used},
+		"too many attempts": {challenge: newTestMfaChallenge(), attempts: mfaMaxAttempts + 1},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			e := echo.New()
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			// Mock the Server struct
+			mockRepo := repository.NewMockRepositoryInterface(ctrl)
+			server := &Server{
+				Repository: mockRepo,
+			}
+			c, _ := newTestLoginMfaContext(e, "123456")
+
+			// the code is never checked
+			mockRepo.EXPECT().GetMfaChallengeByHash(gomock.Any(), gomock.Any()).Return(
+				testCase.challenge,
+				testCase.err,
+			)
+			if testCase.attempts > 0 {
+				mockRepo.EXPECT().IncrementMfaChallengeAttempts(gomock.Any(), gomock.Any()).Return(
+					repository.IncrementMfaChallengeAttemptsOutput{Attempts: testCase.attempts},
+					nil,
+				)
+			}
+
+			err := server.LoginMfa(c)
+
+			assertProblem(t, err, http.StatusUnauthorized, ErrCodeInvalidMFAToken)
+		})
+	}
+}
+
+func TestEnrollTotp_Error_AlreadyEnabled(t *testing.T) {
+	e := echo.New()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// Mock the Server struct
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	server := &Server{
+		Repository: mockRepo,
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/my-profile/2fa/totp", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(principalContextKey, newTestPrincipal())
+
+	mockRepo.EXPECT().CreateTotpSecret(gomock.Any(), gomock.Any()).Return(
+		repository.CreateTotpSecretOutput{Created: false},
+		nil,
+	)
+
+	err := server.EnrollTotp(c)
+
+	assertProblem(t, err, http.StatusConflict, ErrCodeTOTPAlreadyEnabled)
+}
+
+func TestConfirmTotp_Success(t *testing.T) {
+	e := echo.New()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// Mock the Server struct
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	server := &Server{
+		Repository: mockRepo,
+	}
+
+	step := time.Now().Unix() / 30
+	body := `{"code":"` + totpCode([]byte("12345678901234567890"), step) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/my-profile/2fa/totp/confirm", bytes.NewReader([]byte(body)))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(principalContextKey, newTestPrincipal())
+
+	// Set up the expected behavior of the mock
+	var enabled repository.EnableTotpInput
+	mockRepo.EXPECT().GetTotpByUserId(gomock.Any(), repository.GetTotpInput{UserId: 1}).Return(
+		repository.GetTotpOutput{Secret: testTOTPSecret},
+		nil,
+	)
+	mockRepo.EXPECT().UseTotpStep(gomock.Any(), repository.UseTotpStepInput{UserId: 1, Step: step}).Return(
+		repository.UseTotpStepOutput{Used: true},
+		nil,
+	)
+	mockRepo.EXPECT().EnableTotp(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, input repository.EnableTotpInput) (repository.EnableTotpOutput, error) {
+			enabled = input
+			return repository.EnableTotpOutput{Enabled: true}, nil
+		},
+	)
+
+	err := server.ConfirmTotp(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// only the hash of the recovery codes is stored
+	var resp generated.RecoveryCodesResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	if assert.Len(t, resp.RecoveryCodes, recoveryCodeCount) {
+		assert.Len(t, enabled.RecoveryCodeHashes, recoveryCodeCount)
+		for i, code := range resp.RecoveryCodes {
+			assert.Equal(t, hashRecoveryCode(code), enabled.RecoveryCodeHashes[i])
+		}
+	}
+}
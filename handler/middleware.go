@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// bearerAuthScheme and adminApiKeyScheme are the names of the security schemes in api.yml
+	bearerAuthScheme  = "BearerAuth"
+	adminApiKeyScheme = "AdminApiKey"
+	adminApiKeyHeader = "X-Admin-Api-Key"
+	// principalContextKey is where the authenticated Principal is stored on the echo.Context
+	principalContextKey = "principal"
+	// requestIdContextKey is where RequestIdMiddleware stores the request id
+	requestIdContextKey = "requestId"
+	maxRequestIdLength  = 128
+	authRealm           = "user-service"
+)
+
+var (
+	errMissingToken = errors.New("authorization token not provided")
+	// errRevocationCheck is not the client fault, it is answered with a 500
+	errRevocationCheck = errors.New("failed to check token revocation")
+)
+
+// Principal is the authenticated user of the request, taken from the access
+// token. The profile of the user is read from the database.
+type Principal struct {
+	UserId    int
+	SessionId string
+	TokenId   string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// principalFromContext returns the Principal set by the auth middleware.
+func principalFromContext(ctx echo.Context) (*Principal, bool) {
+	principal, ok := ctx.Get(principalContextKey).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// AuthMiddleware authenticates the requests of every operation secured by the
+// BearerAuth or AdminApiKey scheme in the OpenAPI spec, other routes are left untouched.
+func (s *Server) AuthMiddleware(swagger *openapi3.T) echo.MiddlewareFunc {
+	secured := securedRoutes(swagger)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			switch secured[routeKey(ctx.Request().Method, ctx.Path())] {
+			case bearerAuthScheme:
+				// authenticated below
+			case adminApiKeyScheme:
+				if !s.authenticateAdmin(ctx) {
+					return newProblem(http.StatusUnauthorized, ErrCodeUnauthorized, "a valid admin API key is required")
+				}
+				return next(ctx)
+			default:
+				return next(ctx)
+			}
+
+			principal, err := s.authenticate(ctx)
+			if errors.Is(err, errRevocationCheck) {
+				return internalError(err)
+			}
+			if err != nil {
+				return unauthorized(err)
+			}
+
+			ctx.Set(principalContextKey, principal)
+			return next(ctx)
+		}
+	}
+}
+
+// securedRoutes maps the routes, in echo path syntax, to the security scheme they require.
+func securedRoutes(swagger *openapi3.T) map[string]string {
+	secured := make(map[string]string)
+	for path, pathItem := range swagger.Paths {
+		for method, operation := range pathItem.Operations() {
+			// the operation security overrides the top level one
+			requirements := swagger.Security
+			if operation.Security != nil {
+				requirements = *operation.Security
+			}
+
+			for _, requirement := range requirements {
+				for _, scheme := range []string{bearerAuthScheme, adminApiKeyScheme} {
+					if _, ok := requirement[scheme]; ok {
+						secured[routeKey(method, echoPath(path))] = scheme
+					}
+				}
+			}
+		}
+	}
+	return secured
+}
+
+// echoPath converts an OpenAPI path template /users/{id} to echo syntax /users/:id
+func echoPath(path string) string {
+	path = strings.ReplaceAll(path, "{", ":")
+	return strings.ReplaceAll(path, "}", "")
+}
+
+func routeKey(method string, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+// unauthorized builds the 401 problem of RFC 6750, the error attributes are
+// only set when a token was sent.
+func unauthorized(err error) *Problem {
+	challenge := fmt.Sprintf(`Bearer realm=%q`, authRealm)
+	code := ErrCodeUnauthorized
+	if err != errMissingToken {
+		challenge += fmt.Sprintf(`, error="invalid_token", error_description=%q`, err.Error())
+		code = ErrCodeInvalidToken
+	}
+
+	problem := newProblem(http.StatusUnauthorized, code, err.Error())
+	problem.Header = http.Header{echo.HeaderWWWAuthenticate: []string{challenge}}
+	return problem
+}
+
+// RequestIdMiddleware tags every request with an id, taken from the
+// X-Request-ID header when the client or a proxy sent one. The id is echoed in
+// the response header and in every problem response.
+func RequestIdMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			requestId := ctx.Request().Header.Get(echo.HeaderXRequestID)
+			if requestId == "" || len(requestId) > maxRequestIdLength {
+				id, err := generateRandomString(12)
+				if err != nil {
+					return internalError(err)
+				}
+				requestId = id
+			}
+
+			ctx.Set(requestIdContextKey, requestId)
+			ctx.Response().Header().Set(echo.HeaderXRequestID, requestId)
+			return next(ctx)
+		}
+	}
+}
+
+// requestIdFromContext returns the id set by RequestIdMiddleware, or the
+// response header when the middleware did not run.
+func requestIdFromContext(ctx echo.Context) string {
+	if requestId, ok := ctx.Get(requestIdContextKey).(string); ok {
+		return requestId
+	}
+	return ctx.Response().Header().Get(echo.HeaderXRequestID)
+}
+
+// authenticateAdmin checks the admin API key of the request, admin routes are
+// closed when no key is configured.
+func (s *Server) authenticateAdmin(ctx echo.Context) bool {
+	apiKey := ctx.Request().Header.Get(adminApiKeyHeader)
+	if s.AdminApiKey == "" || apiKey == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(apiKey), []byte(s.AdminApiKey)) == 1
+}
+
+// authenticate validates the bearer token of the request.
+func (s *Server) authenticate(c echo.Context) (*Principal, error) {
+	authorization := c.Request().Header.Get(echo.HeaderAuthorization)
+	if authorization == "" {
+		return nil, errMissingToken
+	}
+
+	// the scheme is case insensitive
+	const prefix = "bearer "
+	if len(authorization) <= len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return nil, errors.New("authorization header must use the Bearer scheme")
+	}
+	tokenString := strings.TrimSpace(authorization[len(prefix):])
+
+	claims := &JwtCustomClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.keyFunc, jwt.WithValidMethods(validSigningMethods))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT: %v", err)
+	}
+
+	// Check if the token is valid
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	// tokens without jti can't be revoked, so they are not accepted
+	userId, err := claims.UserId()
+	if err != nil || claims.ID == "" || claims.IssuedAt == nil || claims.ExpiresAt == nil {
+		return nil, errors.New("invalid token")
+	}
+
+	// the iat claim is issued in milliseconds, rounding drops the float error of the parsing
+	issuedAt := claims.IssuedAt.Time.Round(time.Millisecond)
+	res, err := s.RevocationStore.IsTokenRevoked(c.Request().Context(), repository.IsTokenRevokedInput{
+		Jti:       claims.ID,
+		UserId:    userId,
+		SessionId: claims.SessionId,
+		IssuedAt:  issuedAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errRevocationCheck, err)
+	}
+	if res.Revoked {
+		return nil, errors.New("token has been revoked")
+	}
+
+	return &Principal{
+		UserId:    userId,
+		SessionId: claims.SessionId,
+		TokenId:   claims.ID,
+		IssuedAt:  issuedAt,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestEcho wires the server the same way cmd/main.go does
+func newTestEcho(t *testing.T, server *Server) *echo.Echo {
+	swagger, err := generated.GetSwagger()
+	assert.NoError(t, err)
+
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(RequestIdMiddleware())
+	e.Use(server.AuthMiddleware(swagger))
+	generated.RegisterHandlers(e, server)
+	return e
+}
+
+func TestAuthMiddleware_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// Mock the Server struct
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	server := &Server{
+		Repository:      mockRepo,
+		RevocationStore: repository.NewMemoryRevocationStore(),
+		KeyManager:      testKeyManager,
+	}
+	e := newTestEcho(t, server)
+
+	req := httptest.NewRequest(http.MethodGet, "/my-profile", nil)
+	// the scheme is case insensitive
+	req.Header.Set("Authorization", fmt.Sprintf("bearer %v", generateTestToken(t)))
+	rec := httptest.NewRecorder()
+
+	// Set up the expected behavior of the mock
+	mockRepo.EXPECT().GetUserById(gomock.Any(), repository.GetUserByIdInput{Id: 1}).Return(
+		newTestUser(),
+		nil,
+	)
+
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id":1,"name":"test","phoneNumber":"+6282222222","phoneVerified":false,"loginCount":0,"createdAt":"2024-01-02T03:04:05Z","updatedAt":"2024-01-02T03:04:05Z"}`, rec.Body.String())
+}
+
+func TestAuthMiddleware_Success_IssuedAfterRevocation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// Mock the Server struct
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	server := &Server{
+		Repository:      mockRepo,
+		RevocationStore: repository.NewMemoryRevocationStore(),
+		KeyManager:      testKeyManager,
+	}
+	e := newTestEcho(t, server)
+
+	// Set up the expected behavior of the mock
+	mockRepo.EXPECT().RevokeUserRefreshTokens(gomock.Any(), repository.RevokeUserRefreshTokensInput{UserId: 1}).Return(
+		nil,
+	)
+	mockRepo.EXPECT().GetUserById(gomock.Any(), repository.GetUserByIdInput{Id: 1}).Return(
+		newTestUser(),
+		nil,
+	)
+
+	// the token of the session created right after, in the same second
+	assert.NoError(t, server.revokeAllUserSessions(context.Background(), 1))
+	req := httptest.NewRequest(http.MethodGet, "/my-profile", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", generateTestTokenIssuedAt(t, "family", time.Now().Add(time.Millisecond))))
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestAuthMiddleware_Error_IssuedBeforeRevocation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// Mock the Server struct
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	server := &Server{
+		Repository:      mockRepo,
+		RevocationStore: repository.NewMemoryRevocationStore(),
+		KeyManager:      testKeyManager,
+	}
+	e := newTestEcho(t, server)
+
+	mockRepo.EXPECT().RevokeUserRefreshTokens(gomock.Any(), repository.RevokeUserRefreshTokensInput{UserId: 1}).Return(
+		nil,
+	)
+
+	// issued a millisecond before the revocation, most likely in the same second
+	token := generateTestTokenIssuedAt(t, "family", time.Now().Add(-time.Millisecond))
+	assert.NoError(t, server.revokeAllUserSessions(context.Background(), 1))
+	req := httptest.NewRequest(http.MethodGet, "/my-profile", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", token))
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestAuthMiddleware_Error_MissingToken(t *testing.T) {
+	server := &Server{
+		RevocationStore: repository.NewMemoryRevocationStore(),
+		KeyManager:      testKeyManager,
+	}
+	e := newTestEcho(t, server)
+
+	req := httptest.NewRequest(http.MethodGet, "/my-profile", nil)
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, `Bearer realm="user-service"`, rec.Header().Get(echo.HeaderWWWAuthenticate))
+}
+
+func TestAuthMiddleware_Error_InvalidToken(t *testing.T) {
+	testCases := map[string]string{
+		"malformed":     fmt.Sprintf("Bearer %v", "test"),
+		"wrong scheme":  fmt.Sprintf("Basic %v", "dGVzdDp0ZXN0"),
+		"short header":  "Bearer",
+		"unknown key":   fmt.Sprintf("Bearer %v", generateTestTokenWith(t, newTestKeyManager())),
+		"missing token": "Bearer ",
+	}
+
+	for name, authorization := range testCases {
+		t.Run(name, func(t *testing.T) {
+			server := &Server{
+				RevocationStore: repository.NewMemoryRevocationStore(),
+				KeyManager:      testKeyManager,
+			}
+			e := newTestEcho(t, server)
+
+			req := httptest.NewRequest(http.MethodGet, "/my-profile", nil)
+			req.Header.Set("Authorization", authorization)
+			rec := httptest.NewRecorder()
+
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			assert.Contains(t, rec.Header().Get(echo.HeaderWWWAuthenticate), `error="invalid_token"`)
+		})
+	}
+}
+
+func TestAuthMiddleware_PublicRoute(t *testing.T) {
+	server := &Server{
+		KeyManager: testKeyManager,
+	}
+	e := newTestEcho(t, server)
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", "test"))
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestAuthMiddleware_AdminApiKey(t *testing.T) {
+	testCases := map[string]struct {
+		configured string
+		sent       string
+		status     int
+	}{
+		"valid key":      {configured: "admin-key", sent: "admin-key", status: http.StatusNoContent},
+		"invalid key":    {configured: "admin-key", sent: "other-key", status: http.StatusUnauthorized},
+		"missing key":    {configured: "admin-key", sent: "", status: http.StatusUnauthorized},
+		"not configured": {configured: "", sent: "", status: http.StatusUnauthorized},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			// Mock the Server struct
+			mockRepo := repository.NewMockRepositoryInterface(ctrl)
+			server := &Server{
+				Repository:  mockRepo,
+				AdminApiKey: testCase.configured,
+			}
+			e := newTestEcho(t, server)
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/users/1/restore", nil)
+			if testCase.sent != "" {
+				req.Header.Set(adminApiKeyHeader, testCase.sent)
+			}
+			rec := httptest.NewRecorder()
+
+			if testCase.status == http.StatusNoContent {
+				mockRepo.EXPECT().RestoreUser(gomock.Any(), repository.RestoreUserInput{Id: 1}).Return(
+					repository.RestoreUserOutput{Restored: true},
+					nil,
+				)
+			}
+
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, testCase.status, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/sms"
+)
+
+const (
+	// otpLength digits, matching the OTP schema of api.yml
+	otpLength      = 6
+	otpTTL         = 10 * time.Minute
+	otpMaxAttempts = 5
+	// at most otpRateLimit SMS are sent to a phone number every otpRateWindow
+	otpRateLimit  = 3
+	otpRateWindow = 15 * time.Minute
+)
+
+// this function for generate numeric one time password, sent by SMS
+func generateOTP() (string, error) {
+	var otp strings.Builder
+	for i := 0; i < otpLength; i++ {
+		digit, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		otp.WriteString(digit.String())
+	}
+	return otp.String(), nil
+}
+
+// only the salted sha256 of the OTP is stored, like the refresh tokens
+func hashOTP(salt string, otp string) string {
+	sum := sha256.Sum256([]byte(salt + otp))
+	return hex.EncodeToString(sum[:])
+}
+
+func compareOTP(hash string, salt string, otp string) bool {
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(hashOTP(salt, otp))) == 1
+}
+
+// sendPhoneVerification sends an OTP to phoneNumber, it replaces the previous
+// verification of the user. Confirming it verifies the phone number of a new
+// account, or applies the pending change when phoneNumber is a new one.
+func (s *Server) sendPhoneVerification(ctx context.Context, userId int, phoneNumber string) error {
+	otp, err := s.createPhoneVerification(ctx, userId, phoneNumber)
+	if err != nil {
+		return err
+	}
+	return s.sendPhoneVerificationCode(ctx, phoneNumber, otp)
+}
+
+// createPhoneVerification stores a new OTP of phoneNumber in place of the
+// previous verification of the user and returns it.
+func (s *Server) createPhoneVerification(ctx context.Context, userId int, phoneNumber string) (string, error) {
+	otp, err := generateOTP()
+	if err != nil {
+		return "", err
+	}
+	salt, err := generateRandomString(16)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.Repository.CreatePhoneVerification(ctx, repository.CreatePhoneVerificationInput{
+		UserId:      userId,
+		PhoneNumber: phoneNumber,
+		OtpHash:     hashOTP(salt, otp),
+		OtpSalt:     salt,
+		ExpiresAt:   time.Now().Add(otpTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return otp, nil
+}
+
+func (s *Server) sendPhoneVerificationCode(ctx context.Context, phoneNumber string, otp string) error {
+	return s.SMSSender.Send(ctx, sms.Message{
+		PhoneNumber: phoneNumber,
+		Body:        fmt.Sprintf("Your verification code is %s. It expires in %d minutes, never share it.", otp, int(otpTTL.Minutes())),
+	})
+}
+
+// sendPasswordReset stores a new password reset code of the user and sends it by SMS.
+func (s *Server) sendPasswordReset(ctx context.Context, userId int, phoneNumber string) error {
+	otp, err := generateOTP()
+	if err != nil {
+		return err
+	}
+	salt, err := generateRandomString(16)
+	if err != nil {
+		return err
+	}
+
+	err = s.Repository.CreatePasswordReset(ctx, repository.CreatePasswordResetInput{
+		UserId:    userId,
+		OtpHash:   hashOTP(salt, otp),
+		OtpSalt:   salt,
+		ExpiresAt: time.Now().Add(otpTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.SMSSender.Send(ctx, sms.Message{
+		PhoneNumber: phoneNumber,
+		Body:        fmt.Sprintf("Your password reset code is %s. It expires in %d minutes, never share it.", otp, int(otpTTL.Minutes())),
+	})
+}
+
+// sendRegistrationNotice tells the owner of the phone number that somebody
+// tried to register it again, the registration itself answers as if it succeeded.
+func (s *Server) sendRegistrationNotice(ctx context.Context, phoneNumber string) error {
+	return s.SMSSender.Send(ctx, sms.Message{
+		PhoneNumber: phoneNumber,
+		Body:        "Somebody tried to register an account with your phone number. If it was you, log in or reset your password instead.",
+	})
+}
+
+// sendPhoneNumberChangeNotice tells the owner of the phone number that another
+// account tried to take it, the change itself answers as if it were pending.
+func (s *Server) sendPhoneNumberChangeNotice(ctx context.Context, phoneNumber string) error {
+	return s.SMSSender.Send(ctx, sms.Message{
+		PhoneNumber: phoneNumber,
+		Body:        "Somebody tried to change the phone number of their account to yours. Your account is unchanged, no action is needed.",
+	})
+}
+
+// consumePhoneVerification checks the OTP of the verification and marks it
+// used, it returns false whenever the OTP can't be accepted.
+func (s *Server) consumePhoneVerification(ctx context.Context, verification repository.GetPhoneVerificationOutput, otp string) (bool, error) {
+	if verification.UsedAt != nil || time.Now().After(verification.ExpiresAt) {
+		return false, nil
+	}
+
+	attempts, err := s.Repository.IncrementPhoneVerificationAttempts(ctx, repository.IncrementPhoneVerificationAttemptsInput{
+		Id: verification.Id,
+	})
+	if err != nil {
+		return false, err
+	}
+	if attempts.Attempts > otpMaxAttempts || !compareOTP(verification.OtpHash, verification.OtpSalt, otp) {
+		return false, nil
+	}
+
+	// single use, a concurrent confirmation may have won
+	consumed, err := s.Repository.ConsumePhoneVerification(ctx, repository.ConsumePhoneVerificationInput{
+		Id: verification.Id,
+	})
+	if err != nil {
+		return false, err
+	}
+	return consumed.Consumed, nil
+}
@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateOTP(t *testing.T) {
+	otp, err := generateOTP()
+
+	assert.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^[0-9]{6}$`), otp)
+}
+
+func TestCompareOTP(t *testing.T) {
+	hash := hashOTP("salt", "123456")
+
+	assert.True(t, compareOTP(hash, "salt", "123456"))
+	assert.False(t, compareOTP(hash, "salt", "654321"))
+	assert.False(t, compareOTP(hash, "other-salt", "123456"))
+}
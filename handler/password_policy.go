@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+)
+
+// bcrypt ignores everything past the first 72 bytes
+const maxPasswordBytes = 72
+
+// PasswordPolicy is the set of rules a new password must follow.
+type PasswordPolicy struct {
+	MinLength int `json:"minLength"`
+	MaxLength int `json:"maxLength"`
+
+	RequireUppercase bool `json:"requireUppercase"`
+	RequireLowercase bool `json:"requireLowercase"`
+	RequireDigit     bool `json:"requireDigit"`
+	RequireSpecial   bool `json:"requireSpecial"`
+	// MaxRepeated is the longest run of the same character allowed, not limited when 0
+	MaxRepeated int `json:"maxRepeated"`
+	// BreachedPasswordsDir holds the breached password hashes split by the first
+	// 5 hex characters of their SHA-1, as served by the Pwned Passwords range API:
+	// the file 21BD1.txt has a SUFFIX:COUNT line per hash. Not checked when empty.
+	BreachedPasswordsDir string `json:"breachedPasswordsDir"`
+	// BlockPersonalInfo rejects passwords containing the name or the phone number of the user
+	BlockPersonalInfo bool `json:"blockPersonalInfo"`
+}
+
+// PasswordViolation is a rule the password breaks.
+type PasswordViolation struct {
+	Rule    string
+	Message string
+}
+
+// PasswordOwner is what the password must not contain, empty fields are not checked.
+type PasswordOwner struct {
+	FullName    string
+	PhoneNumber string
+}
+
+// DefaultPasswordPolicy requires 6 to 64 characters with at least 1 capital
+// character, 1 number and 1 special character.
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:         6,
+		MaxLength:         64,
+		RequireUppercase:  true,
+		RequireDigit:      true,
+		RequireSpecial:    true,
+		BlockPersonalInfo: true,
+	}
+}
+
+// PasswordPolicyFromEnv starts from the JSON file PASSWORD_POLICY_FILE when
+// set, the PASSWORD_* variables then override single rules.
+func PasswordPolicyFromEnv() (PasswordPolicy, error) {
+	policy := DefaultPasswordPolicy()
+
+	if path := os.Getenv("PASSWORD_POLICY_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return policy, err
+		}
+		if err := json.Unmarshal(data, &policy); err != nil {
+			return policy, fmt.Errorf("PASSWORD_POLICY_FILE: %w", err)
+		}
+	}
+
+	ints := map[string]*int{
+		"PASSWORD_MIN_LENGTH":   &policy.MinLength,
+		"PASSWORD_MAX_LENGTH":   &policy.MaxLength,
+		"PASSWORD_MAX_REPEATED": &policy.MaxRepeated,
+	}
+	for name, target := range ints {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				return policy, fmt.Errorf("%s must be a non-negative integer", name)
+			}
+			*target = parsed
+		}
+	}
+
+	bools := map[string]*bool{
+		"PASSWORD_REQUIRE_UPPERCASE":   &policy.RequireUppercase,
+		"PASSWORD_REQUIRE_LOWERCASE":   &policy.RequireLowercase,
+		"PASSWORD_REQUIRE_DIGIT":       &policy.RequireDigit,
+		"PASSWORD_REQUIRE_SPECIAL":     &policy.RequireSpecial,
+		"PASSWORD_BLOCK_PERSONAL_INFO": &policy.BlockPersonalInfo,
+	}
+	for name, target := range bools {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return policy, fmt.Errorf("%s must be a boolean", name)
+			}
+			*target = parsed
+		}
+	}
+
+	if value := os.Getenv("PASSWORD_BREACHED_DIR"); value != "" {
+		policy.BreachedPasswordsDir = value
+	}
+
+	return policy, policy.check()
+}
+
+func (p PasswordPolicy) check() error {
+	if p.MinLength < 1 {
+		return errors.New("password policy: the minimum length must be at least 1")
+	}
+	if p.MaxLength < p.MinLength || p.MaxLength > maxPasswordBytes {
+		return fmt.Errorf("password policy: the maximum length must be between the minimum length and %d", maxPasswordBytes)
+	}
+	if p.BreachedPasswordsDir != "" {
+		info, err := os.Stat(p.BreachedPasswordsDir)
+		if err != nil {
+			return fmt.Errorf("password policy: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("password policy: %s is not a directory", p.BreachedPasswordsDir)
+		}
+	}
+	return nil
+}
+
+// Validate returns every rule the password breaks, none when it is accepted.
+func (p PasswordPolicy) Validate(password string, owner PasswordOwner) ([]PasswordViolation, error) {
+	violations := []PasswordViolation{}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, PasswordViolation{Rule: "minLength", Message: fmt.Sprintf("must be at least %d characters", p.MinLength)})
+	}
+	// multi-byte characters count for more than one, the hash only takes 72 bytes
+	if length > p.MaxLength || len(password) > maxPasswordBytes {
+		violations = append(violations, PasswordViolation{Rule: "maxLength", Message: fmt.Sprintf("must be at most %d characters", p.MaxLength)})
+	}
+
+	var hasUppercase, hasLowercase, hasDigit, hasSpecial bool
+	for _, char := range password {
+		switch {
+		case unicode.IsUpper(char):
+			hasUppercase = true
+		case unicode.IsLower(char):
+			hasLowercase = true
+		case unicode.IsDigit(char):
+			hasDigit = true
+		case !unicode.IsLetter(char) && !unicode.IsSpace(char):
+			hasSpecial = true
+		}
+	}
+	if p.RequireUppercase && !hasUppercase {
+		violations = append(violations, PasswordViolation{Rule: "uppercase", Message: "must contain a capital character"})
+	}
+	if p.RequireLowercase && !hasLowercase {
+		violations = append(violations, PasswordViolation{Rule: "lowercase", Message: "must contain a lowercase character"})
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, PasswordViolation{Rule: "digit", Message: "must contain a number"})
+	}
+	if p.RequireSpecial && !hasSpecial {
+		violations = append(violations, PasswordViolation{Rule: "special", Message: "must contain a special (non alphanumeric) character"})
+	}
+
+	if p.MaxRepeated > 0 && longestRun(password) > p.MaxRepeated {
+		violations = append(violations, PasswordViolation{Rule: "repeated", Message: fmt.Sprintf("must not repeat a character more than %d times in a row", p.MaxRepeated)})
+	}
+
+	if p.BlockPersonalInfo && containsPersonalInfo(password, owner) {
+		violations = append(violations, PasswordViolation{Rule: "personalInfo", Message: "must not contain the name or the phone number"})
+	}
+
+	if p.BreachedPasswordsDir != "" {
+		breached, err := isBreachedPassword(p.BreachedPasswordsDir, password)
+		if err != nil {
+			return nil, err
+		}
+		if breached {
+			violations = append(violations, PasswordViolation{Rule: "breached", Message: "appears in a data breach, choose another one"})
+		}
+	}
+
+	return violations, nil
+}
+
+func longestRun(password string) int {
+	longest, run := 0, 0
+	var previous rune
+	for i, char := range []rune(password) {
+		if i > 0 && char == previous {
+			run++
+		} else {
+			run = 1
+		}
+		previous = char
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
+
+// containsPersonalInfo looks for every part of the name of at least 3
+// characters and for the phone number, with or without its country code.
+func containsPersonalInfo(password string, owner PasswordOwner) bool {
+	lowered := strings.ToLower(password)
+
+	for _, part := range strings.Fields(strings.ToLower(owner.FullName)) {
+		if utf8.RuneCountInString(part) >= 3 && strings.Contains(lowered, part) {
+			return true
+		}
+	}
+
+	if owner.PhoneNumber != "" {
+		digits := strings.TrimPrefix(owner.PhoneNumber, "+")
+		national := strings.TrimPrefix(digits, "62")
+		for _, candidate := range []string{digits, national, "0" + national} {
+			if strings.Contains(password, candidate) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isBreachedPassword looks the SHA-1 of the password up in the range file of
+// its prefix, a missing file means no breached password has the prefix.
+func isBreachedPassword(dir string, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	file, err := os.Open(filepath.Join(dir, prefix+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		candidate, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if strings.EqualFold(candidate, suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// validatePassword checks the password against the policy of the server, the
+// violations are answered as a validation problem of the field.
+func (s *Server) validatePassword(field string, password string, owner PasswordOwner) error {
+	policy := DefaultPasswordPolicy()
+	if s.PasswordPolicy != nil {
+		policy = *s.PasswordPolicy
+	}
+
+	violations, err := policy.Validate(password, owner)
+	if err != nil {
+		return internalError(err)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	problems := make([]generated.FieldError, 0, len(violations))
+	for _, violation := range violations {
+		problems = append(problems, generated.FieldError{
+			Field:   field,
+			Rule:    violation.Rule,
+			Message: violation.Message,
+		})
+	}
+	return validationProblem(problems)
+}
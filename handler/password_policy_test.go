@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func violationRules(violations []PasswordViolation) []string {
+	rules := []string{}
+	for _, violation := range violations {
+		rules = append(rules, violation.Rule)
+	}
+	return rules
+}
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	policy := DefaultPasswordPolicy()
+	policy.MaxRepeated = 3
+
+	testCases := map[string]struct {
+		password string
+		owner    PasswordOwner
+		rules    []string
+	}{
+		"accepted":              {password: "my@Password1", rules: []string{}},
+		"lowercase not special": {password: "myPassword1", rules: []string{"special"}},
+		"every violation":       {password: "aaaa", rules: []string{"minLength", "uppercase", "digit", "special", "repeated"}},
+		"too long":              {password: "my@Password1" + string(bytes.Repeat([]byte("xy"), 30)), rules: []string{"maxLength"}},
+		"name":                  {password: "arthur@Dent1", owner: PasswordOwner{FullName: "Arthur Dent"}, rules: []string{"personalInfo"}},
+		"short name part":       {password: "my@Password1", owner: PasswordOwner{FullName: "Al My"}, rules: []string{}},
+		"phone number":          {password: "My@0821234567", owner: PasswordOwner{PhoneNumber: "+62821234567"}, rules: []string{"personalInfo"}},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			violations, err := policy.Validate(testCase.password, testCase.owner)
+
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.rules, violationRules(violations))
+		})
+	}
+}
+
+func TestPasswordPolicy_Validate_Breached(t *testing.T) {
+	// SHA-1 of "my@Password1" is 7EFB5 + the suffix, the other line is a neighbour
+	dir := t.TempDir()
+	content := "0018A45C4D1DEF81644B54AB7F969B88D65:3\r\n76B4EDC04014B6135ACB41BC340260013C2:12\r\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "7EFB5.txt"), []byte(content), 0600))
+
+	policy := DefaultPasswordPolicy()
+	policy.BreachedPasswordsDir = dir
+
+	violations, err := policy.Validate("my@Password1", PasswordOwner{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"breached"}, violationRules(violations))
+
+	// no range file for the prefix
+	violations, err = policy.Validate("my@Password2", PasswordOwner{})
+	assert.NoError(t, err)
+	assert.Empty(t, violations)
+}
+
+func TestPasswordPolicyFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password-policy.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"minLength":12,"requireLowercase":true,"maxRepeated":2}`), 0600))
+	t.Setenv("PASSWORD_POLICY_FILE", path)
+	t.Setenv("PASSWORD_MAX_REPEATED", "3")
+
+	policy, err := PasswordPolicyFromEnv()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 12, policy.MinLength)
+	assert.True(t, policy.RequireLowercase)
+	assert.Equal(t, 3, policy.MaxRepeated)
+	assert.Equal(t, DefaultPasswordPolicy().MaxLength, policy.MaxLength)
+
+	// 0 turns the repetition rule off, a negative value is no setting
+	t.Setenv("PASSWORD_MAX_REPEATED", "0")
+	policy, err = PasswordPolicyFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, policy.MaxRepeated)
+	t.Setenv("PASSWORD_MAX_REPEATED", "-1")
+	_, err = PasswordPolicyFromEnv()
+	assert.EqualError(t, err, "PASSWORD_MAX_REPEATED must be a non-negative integer")
+
+	// bcrypt would ignore the end of longer passwords
+	t.Setenv("PASSWORD_MAX_REPEATED", "3")
+	t.Setenv("PASSWORD_MAX_LENGTH", "100")
+	_, err = PasswordPolicyFromEnv()
+	assert.Error(t, err)
+}
+
+func TestRegistration_Error_PasswordPolicy(t *testing.T) {
+	e := echo.New()
+	server := &Server{}
+
+	req := httptest.NewRequest(http.MethodPost, "/registration", bytes.NewReader([]byte(`{"phoneNumber":"+6282222222","fullName":"Arthur Dent","password":"arthurdent"}`)))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := server.Registration(c)
+
+	// every violation is answered at once
+	assertProblem(t, err, http.StatusBadRequest, ErrCodeValidationFailed)
+	problem := err.(*Problem)
+	assert.Equal(t, []generated.FieldError{
+		{Field: "password", Rule: "uppercase", Message: "must contain a capital character"},
+		{Field: "password", Rule: "digit", Message: "must contain a number"},
+		{Field: "password", Rule: "special", Message: "must contain a special (non alphanumeric) character"},
+		{Field: "password", Rule: "personalInfo", Message: "must not contain the name or the phone number"},
+	}, problem.Errors)
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/mail"
+	"net/url"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"golang.org/x/text/language"
+)
+
+// the oldest date of birth accepted, older ones are typos
+var minDateOfBirth = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// validateProfile checks the formats api.yml can't express, every broken rule
+// is reported. The locale is rewritten in its canonical form.
+func validateProfile(params *generated.UpdateProfileParam, now time.Time) []generated.FieldError {
+	problems := []generated.FieldError{}
+
+	if params.Email != nil {
+		address, err := mail.ParseAddress(*params.Email)
+		if err != nil || address.Address != *params.Email {
+			problems = append(problems, generated.FieldError{Field: "email", Rule: "format", Message: "must be an email address"})
+		}
+	}
+
+	if params.DateOfBirth != nil {
+		dateOfBirth := params.DateOfBirth.Time
+		if !dateOfBirth.Before(now) || dateOfBirth.Before(minDateOfBirth) {
+			problems = append(problems, generated.FieldError{Field: "dateOfBirth", Rule: "range", Message: "must be a past date after 1900-01-01"})
+		}
+	}
+
+	if params.Locale != nil {
+		tag, err := language.Parse(*params.Locale)
+		if err != nil {
+			problems = append(problems, generated.FieldError{Field: "locale", Rule: "format", Message: "must be a BCP 47 language tag, e.g. id-ID"})
+		} else {
+			locale := tag.String()
+			params.Locale = &locale
+		}
+	}
+
+	if params.Timezone != nil {
+		// "" and "Local" would be the zone of the server
+		_, err := time.LoadLocation(*params.Timezone)
+		if err != nil || *params.Timezone == "" || *params.Timezone == "Local" {
+			problems = append(problems, generated.FieldError{Field: "timezone", Rule: "format", Message: "must be an IANA time zone, e.g. Asia/Jakarta"})
+		}
+	}
+
+	if params.AvatarUrl != nil {
+		avatarUrl, err := url.Parse(*params.AvatarUrl)
+		if err != nil || (avatarUrl.Scheme != "https" && avatarUrl.Scheme != "http") || avatarUrl.Host == "" {
+			problems = append(problems, generated.FieldError{Field: "avatarUrl", Rule: "format", Message: "must be an http or https URL"})
+		}
+	}
+
+	return problems
+}
+
+// hasProfileFields tells whether the update touches the optional profile fields
+func hasProfileFields(params generated.UpdateProfileParam) bool {
+	return params.Email != nil || params.DateOfBirth != nil || params.Locale != nil || params.Timezone != nil || params.AvatarUrl != nil
+}
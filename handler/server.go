@@ -1,17 +1,128 @@
 package handler
 
-import "github.com/SawitProRecruitment/UserService/repository"
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/blobstore"
+	"github.com/SawitProRecruitment/UserService/keymanager"
+	"github.com/SawitProRecruitment/UserService/passwordhash"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/sms"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/labstack/echo/v4"
+)
 
 type Server struct {
-	Repository repository.RepositoryInterface
+	Repository      repository.RepositoryInterface
+	RevocationStore repository.RevocationStoreInterface
+	KeyManager      keymanager.KeyManagerInterface
+	LoginProtection LoginProtectionOptions
+	// LoginRateLimiter limits the login attempts per client IP, not limited when nil
+	LoginRateLimiter *RateLimiter
+	// UnknownPhoneLockout locks the unknown phone numbers like accounts, not locked when nil
+	UnknownPhoneLockout *FailedLoginTracker
+	// AdminApiKey authenticates the admin routes, they are closed when empty
+	AdminApiKey string
+	SMSSender   sms.SMSSender
+	// OTPRateLimiter limits the one time passwords sent per phone number, not limited when nil
+	OTPRateLimiter *RateLimiter
+	// RequirePhoneVerification rejects the login of accounts whose phone number is not verified
+	RequirePhoneVerification bool
+	// WebAuthn is the relying party of the passkeys
+	WebAuthn *webauthn.WebAuthn
+	// PasswordPolicy defaults to DefaultPasswordPolicy when nil
+	PasswordPolicy *PasswordPolicy
+	// PasswordHasher defaults to argon2id when nil
+	PasswordHasher passwordhash.PasswordHasher
+	// BlobStore keeps the uploaded avatars, uploads fail when nil
+	BlobStore blobstore.BlobStore
+	// AvatarMaxBytes defaults to DefaultAvatarMaxBytes when 0
+	AvatarMaxBytes int64
+
+	// dummyHash is verified on the login of unknown phone numbers, see verifyDummyPassword
+	dummyHashOnce sync.Once
+	dummyHash     string
+	// background tracks the work started by runInBackground
+	background sync.WaitGroup
 }
 
 type NewServerOptions struct {
 	Repository repository.RepositoryInterface
+	// RevocationStore defaults to the Repository when not set
+	RevocationStore repository.RevocationStoreInterface
+	KeyManager      keymanager.KeyManagerInterface
+	// LoginProtection defaults to DefaultLoginProtectionOptions when not set
+	LoginProtection *LoginProtectionOptions
+	AdminApiKey     string
+	SMSSender       sms.SMSSender
+	// RequirePhoneVerification rejects the login of accounts whose phone number is not verified
+	RequirePhoneVerification bool
+	WebAuthn                 *webauthn.WebAuthn
+	// PasswordPolicy defaults to DefaultPasswordPolicy when not set
+	PasswordPolicy *PasswordPolicy
+	// PasswordHasher defaults to argon2id when not set
+	PasswordHasher passwordhash.PasswordHasher
+	BlobStore      blobstore.BlobStore
+	// AvatarMaxBytes defaults to DefaultAvatarMaxBytes when not set
+	AvatarMaxBytes int64
 }
 
 func NewServer(opts NewServerOptions) *Server {
+	revocationStore := opts.RevocationStore
+	if revocationStore == nil {
+		revocationStore = opts.Repository
+	}
+
+	loginProtection := DefaultLoginProtectionOptions()
+	if opts.LoginProtection != nil {
+		loginProtection = *opts.LoginProtection
+	}
+
 	return &Server{
-		Repository: opts.Repository,
+		Repository:          opts.Repository,
+		RevocationStore:     revocationStore,
+		KeyManager:          opts.KeyManager,
+		LoginProtection:     loginProtection,
+		LoginRateLimiter:    NewRateLimiter(loginProtection.IPRateLimit, loginProtection.IPRateWindow),
+		UnknownPhoneLockout: NewFailedLoginTracker(loginProtection),
+		AdminApiKey:         opts.AdminApiKey,
+		SMSSender:           opts.SMSSender,
+		OTPRateLimiter:      NewRateLimiter(otpRateLimit, otpRateWindow),
+
+		RequirePhoneVerification: opts.RequirePhoneVerification,
+		WebAuthn:                 opts.WebAuthn,
+		PasswordPolicy:           opts.PasswordPolicy,
+		PasswordHasher:           opts.PasswordHasher,
+		BlobStore:                opts.BlobStore,
+		AvatarMaxBytes:           opts.AvatarMaxBytes,
 	}
 }
+
+// backgroundTimeout bounds the work finished after the response, e.g. an SMS
+const backgroundTimeout = 30 * time.Second
+
+// runInBackground runs work once the response no longer depends on it, so its
+// duration and failures are not seen by the client. The errors are logged
+// with the request id.
+func (s *Server) runInBackground(ctx echo.Context, name string, work func(ctx context.Context) error) {
+	logger := ctx.Logger()
+	requestId := requestIdFromContext(ctx)
+	workCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx.Request().Context()), backgroundTimeout)
+
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		defer cancel()
+		if err := work(workCtx); err != nil {
+			logger.Errorf("request %s: %s: %v", requestId, name, err)
+		}
+	}()
+}
+
+// WaitBackground returns once the work started by runInBackground is done,
+// the process waits for it before exiting.
+func (s *Server) WaitBackground() {
+	s.background.Wait()
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/keymanager"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// only asymmetric algorithms are accepted, verifiers never hold a shared secret
+var validSigningMethods = []string{
+	keymanager.AlgorithmRS256,
+	keymanager.AlgorithmES256,
+	keymanager.AlgorithmEdDSA,
+}
+
+const (
+	// access tokens are short-lived, clients renew them with a refresh token
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+	// jwksMaxAge is how long verifiers may cache the published keys
+	jwksMaxAge = 5 * time.Minute
+)
+
+// the iat claim carries milliseconds, so a revocation also covers the tokens
+// issued earlier in the same second. The claims are parsed to the microsecond,
+// a finer precision than they are issued with, so the float error of the
+// parsing can be rounded off.
+func init() {
+	jwt.TimePrecision = time.Microsecond
+}
+
+// this function for generate signed access token for the given user session,
+// the session id is the refresh token family the access token belongs to
+func (s *Server) generateAccessToken(userId int, sessionId string) (string, error) {
+	key, err := s.KeyManager.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	jti, err := generateRandomString(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().Truncate(time.Millisecond)
+	claims := &JwtCustomClaims{
+		SessionId: sessionId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   strconv.Itoa(userId),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(accessTokenTTL)),
+		},
+	}
+
+	// kid tells verifiers which of the published keys to use
+	token := jwt.NewWithClaims(key.SigningMethod(), claims)
+	token.Header["kid"] = key.Id
+	return token.SignedString(key.PrivateKey)
+}
+
+// this function for generate opaque random string, used for refresh token and token family
+func generateRandomString(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// only the sha256 of refresh token is stored, the plain token is only known by the client
+func hashRefreshToken(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueRefreshToken creates a new refresh token in the given token family and
+// returns the plain token to hand to the client.
+func (s *Server) issueRefreshToken(ctx context.Context, userId int, familyId string) (string, error) {
+	refreshToken, err := generateRandomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.Repository.CreateRefreshToken(ctx, repository.CreateRefreshTokenInput{
+		UserId:    userId,
+		FamilyId:  familyId,
+		TokenHash: hashRefreshToken(refreshToken),
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return refreshToken, nil
+}
+
+// revokeAllUserSessions revokes every access and refresh token of the user
+// issued up to now.
+func (s *Server) revokeAllUserSessions(ctx context.Context, userId int) error {
+	return s.revokeUserSessions(ctx, userId, "")
+}
+
+// revokeUserSessions revokes every access and refresh token of the user
+// issued up to now, but the ones of keepSessionId.
+func (s *Server) revokeUserSessions(ctx context.Context, userId int, keepSessionId string) error {
+	now := time.Now()
+	err := s.RevocationStore.RevokeUserTokens(ctx, repository.RevokeUserTokensInput{
+		UserId:          userId,
+		RevokedBefore:   now,
+		ExpiresAt:       now.Add(accessTokenTTL),
+		ExceptSessionId: keepSessionId,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.Repository.RevokeUserRefreshTokens(ctx, repository.RevokeUserRefreshTokensInput{
+		UserId:         userId,
+		ExceptFamilyId: keepSessionId,
+	})
+}
+
+// keyFunc resolves the verification key from the kid header of the token.
+func (s *Server) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, fmt.Errorf("token has no kid header")
+	}
+
+	key, err := s.KeyManager.VerificationKey(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	// the algorithm must be the one of the key, never the one chosen by the token
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.PublicKey, nil
+}
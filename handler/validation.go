@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/labstack/echo/v4"
+)
+
+type ValidationOptions struct {
+	// ValidateResponses checks every response against the spec as well, a
+	// response breaking the spec is replaced by a 500. Meant for tests only.
+	ValidateResponses bool
+}
+
+// ValidationMiddleware validates every request against the OpenAPI spec before
+// it reaches the handlers. Authentication is left to AuthMiddleware.
+func ValidationMiddleware(swagger *openapi3.T, opts ValidationOptions) (echo.MiddlewareFunc, error) {
+	// routes are matched on path only, whatever host the service runs on
+	swagger.Servers = nil
+
+	router, err := legacy.NewRouter(swagger)
+	if err != nil {
+		return nil, err
+	}
+
+	filterOptions := &openapi3filter.Options{
+		MultiError:         true,
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			route, pathParams, err := router.FindRoute(ctx.Request())
+			if err != nil {
+				// unknown routes are answered by echo
+				return next(ctx)
+			}
+
+			requestInput := &openapi3filter.RequestValidationInput{
+				Request:    ctx.Request(),
+				PathParams: pathParams,
+				Route:      route,
+				Options:    filterOptions,
+			}
+			if err := openapi3filter.ValidateRequest(ctx.Request().Context(), requestInput); err != nil {
+				return validationProblem(fieldErrors(err))
+			}
+
+			if !opts.ValidateResponses {
+				return next(ctx)
+			}
+			return validateResponse(ctx, next, requestInput, route)
+		}
+	}, nil
+}
+
+// validateResponse buffers the handler response and only writes it out once
+// it is known to match the spec.
+func validateResponse(ctx echo.Context, next echo.HandlerFunc, requestInput *openapi3filter.RequestValidationInput, route *routers.Route) error {
+	writer := ctx.Response().Writer
+	buffer := &bufferedResponseWriter{ResponseWriter: writer, status: http.StatusOK}
+	ctx.Response().Writer = buffer
+	defer func() {
+		ctx.Response().Writer = writer
+	}()
+
+	// handler errors are written here so that the problem is validated as well
+	if err := next(ctx); err != nil {
+		ctx.Error(err)
+	}
+
+	err := openapi3filter.ValidateResponse(ctx.Request().Context(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: requestInput,
+		Status:                 buffer.status,
+		Header:                 writer.Header(),
+		Body:                   io.NopCloser(bytes.NewReader(buffer.body.Bytes())),
+		Options:                &openapi3filter.Options{MultiError: true, IncludeResponseStatus: true},
+	})
+	if err != nil {
+		ctx.Response().Writer = writer
+		ctx.Response().Committed = false
+		writer.Header().Del(echo.HeaderContentLength)
+		problem := internalError(fmt.Errorf("response validation failed for %s %s: %w", route.Method, route.Path, err))
+		problem.Errors = fieldErrors(err)
+		return problem
+	}
+
+	writer.WriteHeader(buffer.status)
+	_, err = writer.Write(buffer.body.Bytes())
+	return err
+}
+
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+// fieldErrors flattens the kin-openapi errors into one entry per broken rule.
+func fieldErrors(err error) []generated.FieldError {
+	result := []generated.FieldError{}
+	collectFieldErrors(err, "", &result)
+	return result
+}
+
+func collectFieldErrors(err error, field string, result *[]generated.FieldError) {
+	var multiError openapi3.MultiError
+	if errors.As(err, &multiError) {
+		for _, e := range multiError {
+			collectFieldErrors(e, field, result)
+		}
+		return
+	}
+
+	var requestError *openapi3filter.RequestError
+	if errors.As(err, &requestError) {
+		if requestError.Parameter != nil {
+			field = requestError.Parameter.Name
+		}
+		if requestError.Err == nil {
+			*result = append(*result, generated.FieldError{Field: field, Rule: "request", Message: requestError.Error()})
+			return
+		}
+		if errors.Is(requestError.Err, openapi3filter.ErrInvalidRequired) {
+			*result = append(*result, generated.FieldError{Field: field, Rule: "required", Message: "value is required"})
+			return
+		}
+		collectFieldErrors(requestError.Err, field, result)
+		return
+	}
+
+	var responseError *openapi3filter.ResponseError
+	if errors.As(err, &responseError) && responseError.Err != nil {
+		collectFieldErrors(responseError.Err, "response", result)
+		return
+	}
+
+	var schemaError *openapi3.SchemaError
+	if errors.As(err, &schemaError) {
+		if pointer := schemaError.JSONPointer(); len(pointer) > 0 {
+			field = strings.Join(pointer, ".")
+		}
+		*result = append(*result, generated.FieldError{
+			Field:   field,
+			Rule:    schemaError.SchemaField,
+			Message: schemaError.Reason,
+		})
+		return
+	}
+
+	*result = append(*result, generated.FieldError{Field: field, Rule: "format", Message: err.Error()})
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/sms"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestValidationEcho(t *testing.T, server *Server, opts ValidationOptions) *echo.Echo {
+	swagger, err := generated.GetSwagger()
+	assert.NoError(t, err)
+
+	validationMiddleware, err := ValidationMiddleware(swagger, opts)
+	assert.NoError(t, err)
+
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(RequestIdMiddleware())
+	e.Use(server.AuthMiddleware(swagger))
+	e.Use(validationMiddleware)
+	generated.RegisterHandlers(e, server)
+	return e
+}
+
+func TestValidationMiddleware_Error_Request(t *testing.T) {
+	server := &Server{}
+	e := newTestValidationEcho(t, server, ValidationOptions{})
+
+	req := httptest.NewRequest(http.MethodPost, "/registration", strings.NewReader(`{"phoneNumber":"0812","password":"my@Password1"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	assert.Equal(t, MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+
+	var resp generated.Problem
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, ErrCodeValidationFailed, resp.Code)
+	assert.Equal(t, rec.Header().Get(echo.HeaderXRequestID), resp.RequestId)
+	assert.NotNil(t, resp.Errors)
+	assert.ElementsMatch(t, []string{"phoneNumber:minLength", "phoneNumber:pattern", "fullName:required"}, fieldRules(*resp.Errors))
+}
+
+func TestValidationMiddleware_Error_EmptyPatch(t *testing.T) {
+	server := &Server{
+		RevocationStore: repository.NewMemoryRevocationStore(),
+		KeyManager:      testKeyManager,
+	}
+	e := newTestValidationEcho(t, server, ValidationOptions{})
+
+	req := httptest.NewRequest(http.MethodPatch, "/update-profile", strings.NewReader(`{}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+generateTestToken(t))
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"rule":"minProperties"`)
+}
+
+func TestValidationMiddleware_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// Mock the Server struct
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	server := &Server{
+		Repository: mockRepo,
+		SMSSender:  sms.NewLogSender(log.New(io.Discard, "", 0)),
+	}
+	e := newTestValidationEcho(t, server, ValidationOptions{ValidateResponses: true})
+
+	req := httptest.NewRequest(http.MethodPost, "/registration", strings.NewReader(`{"phoneNumber":"+6282222222","fullName":"testFullName","password":"my@Password1"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	// Set up the expected behavior of the mock
+	mockRepo.EXPECT().CreateNewUser(gomock.Any(), gomock.Any()).Return(
+		repository.GetRegistrationOutput{Id: 1},
+		nil,
+	)
+	mockRepo.EXPECT().CreatePhoneVerification(gomock.Any(), gomock.Any()).Return(
+		nil,
+	)
+
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
+
+func TestValidationMiddleware_Error_Response(t *testing.T) {
+	swagger, err := generated.GetSwagger()
+	assert.NoError(t, err)
+
+	validationMiddleware, err := ValidationMiddleware(swagger, ValidationOptions{ValidateResponses: true})
+	assert.NoError(t, err)
+
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(validationMiddleware)
+	// a response body breaking the JWKSResponse schema
+	e.GET("/.well-known/jwks.json", func(ctx echo.Context) error {
+		return ctx.JSON(http.StatusOK, map[string]string{"keys": "none"})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	var resp generated.Problem
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, ErrCodeInternal, resp.Code)
+	assert.Contains(t, fieldRules(*resp.Errors), "keys:type")
+}
+
+func TestValidationMiddleware_Error_Handler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// Mock the Server struct
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	server := &Server{
+		Repository: mockRepo,
+	}
+	e := newTestValidationEcho(t, server, ValidationOptions{ValidateResponses: true})
+
+	req := httptest.NewRequest(http.MethodPost, "/token/refresh", strings.NewReader(`{"refreshToken":"token"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	// the problem written for the handler error is part of the spec
+	mockRepo.EXPECT().GetRefreshTokenByHash(gomock.Any(), gomock.Any()).Return(
+		repository.GetRefreshTokenOutput{},
+		assert.AnError,
+	)
+
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"code":"internal_error"`)
+	assert.NotContains(t, rec.Body.String(), "response validation failed")
+}
+
+func fieldRules(fieldErrors []generated.FieldError) []string {
+	result := make([]string, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		result = append(result, fieldError.Field+":"+fieldError.Rule)
+	}
+	return result
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// a ceremony must be finished within webauthnSessionTTL after it began
+const webauthnSessionTTL = 5 * time.Minute
+
+// NewWebAuthnFromEnv configures the relying party from WEBAUTHN_RP_ID and the
+// comma separated WEBAUTHN_RP_ORIGINS, passkeys are bound to the RP ID.
+func NewWebAuthnFromEnv() (*webauthn.WebAuthn, error) {
+	rpId := os.Getenv("WEBAUTHN_RP_ID")
+	if rpId == "" {
+		rpId = "localhost"
+	}
+
+	origins := []string{"http://localhost:8080"}
+	if value := os.Getenv("WEBAUTHN_RP_ORIGINS"); value != "" {
+		origins = strings.Split(value, ",")
+	}
+
+	return webauthn.New(&webauthn.Config{
+		RPID:          rpId,
+		RPDisplayName: "UserService",
+		RPOrigins:     origins,
+	})
+}
+
+// webauthnUser is a user and its passkeys, as seen by the webauthn library.
+type webauthnUser struct {
+	id              int
+	fullName        string
+	phoneNumber     string
+	phoneVerifiedAt *time.Time
+	passkeys        []repository.WebauthnCredential
+	credentials     []webauthn.Credential
+}
+
+// the user handle stored in the passkey is the user id, it is not personal data
+func (u *webauthnUser) WebAuthnID() []byte {
+	return []byte(strconv.Itoa(u.id))
+}
+
+func (u *webauthnUser) WebAuthnName() string {
+	return u.phoneNumber
+}
+
+func (u *webauthnUser) WebAuthnDisplayName() string {
+	return u.fullName
+}
+
+func (u *webauthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+func (u *webauthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+// passkeyId returns the id of the stored passkey of the credential, 0 when unknown.
+func (u *webauthnUser) passkeyId(credentialId []byte) int {
+	for _, passkey := range u.passkeys {
+		if bytes.Equal(passkey.CredentialId, credentialId) {
+			return passkey.Id
+		}
+	}
+	return 0
+}
+
+// loadWebauthnUser returns repository.ErrNotFound when the user doesn't exist or is deleted.
+func (s *Server) loadWebauthnUser(ctx context.Context, userId int) (*webauthnUser, error) {
+	user, err := s.Repository.GetUserCredentialsById(ctx, repository.GetUserCredentialsByIdInput{
+		Id: userId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.Repository.ListWebauthnCredentials(ctx, repository.ListWebauthnCredentialsInput{
+		UserId: userId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := make([]webauthn.Credential, 0, len(res.Credentials))
+	for _, passkey := range res.Credentials {
+		var credential webauthn.Credential
+		if err := json.Unmarshal([]byte(passkey.Data), &credential); err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, credential)
+	}
+
+	return &webauthnUser{
+		id:              user.Id,
+		fullName:        user.FullName,
+		phoneNumber:     user.PhoneNumber,
+		phoneVerifiedAt: user.PhoneVerifiedAt,
+		passkeys:        res.Credentials,
+		credentials:     credentials,
+	}, nil
+}
+
+// saveWebauthnSession keeps the ceremony until the authenticator answers,
+// userId is 0 for a login.
+func (s *Server) saveWebauthnSession(ctx context.Context, userId int, session *webauthn.SessionData) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	return s.Repository.CreateWebauthnSession(ctx, repository.CreateWebauthnSessionInput{
+		Challenge: session.Challenge,
+		UserId:    userId,
+		Data:      string(data),
+		ExpiresAt: time.Now().Add(webauthnSessionTTL),
+	})
+}
+
+// consumeWebauthnSession returns the ceremony of the challenge signed by the
+// authenticator, repository.ErrNotFound when it is unknown, expired or already used.
+func (s *Server) consumeWebauthnSession(ctx context.Context, challenge string) (int, webauthn.SessionData, error) {
+	var session webauthn.SessionData
+
+	res, err := s.Repository.ConsumeWebauthnSession(ctx, repository.ConsumeWebauthnSessionInput{
+		Challenge: challenge,
+	})
+	if err != nil {
+		return 0, session, err
+	}
+
+	err = json.Unmarshal([]byte(res.Data), &session)
+	return res.UserId, session, err
+}
@@ -0,0 +1,328 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+const testWebauthnOrigin = "http://localhost:8080"
+
+func newTestWebAuthn(t *testing.T) *webauthn.WebAuthn {
+	relyingParty, err := NewWebAuthnFromEnv()
+	assert.NoError(t, err)
+	return relyingParty
+}
+
+// testAuthenticator is a software passkey, answering the ceremonies the way a
+// browser and a platform authenticator would.
+type testAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialId []byte
+	signCount    uint32
+}
+
+func newTestAuthenticator(t *testing.T) *testAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	credentialId := make([]byte, 16)
+	_, err = rand.Read(credentialId)
+	assert.NoError(t, err)
+	return &testAuthenticator{key: key, credentialId: credentialId}
+}
+
+func (a *testAuthenticator) authenticatorData(t *testing.T, attested bool) []byte {
+	rpIdHash := sha256.Sum256([]byte("localhost"))
+	data := append([]byte{}, rpIdHash[:]...)
+	// user present and verified
+	flags := byte(0x01 | 0x04)
+	if attested {
+		flags |= 0x40
+	}
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if !attested {
+		return data
+	}
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  1,
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	assert.NoError(t, err)
+
+	// no AAGUID, the attestation is "none"
+	data = append(data, make([]byte, 16)...)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialId)))
+	data = append(data, a.credentialId...)
+	return append(data, publicKey...)
+}
+
+func testClientData(ceremony string, challenge string) []byte {
+	clientData, _ := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": challenge,
+		"origin":    testWebauthnOrigin,
+	})
+	return clientData
+}
+
+// create answers navigator.credentials.create()
+func (a *testAuthenticator) create(t *testing.T, challenge string) json.RawMessage {
+	attestationObject, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authenticatorData(t, true),
+	})
+	assert.NoError(t, err)
+
+	credential, _ := json.Marshal(map[string]interface{}{
+		"id":    base64.RawURLEncoding.EncodeToString(a.credentialId),
+		"rawId": base64.RawURLEncoding.EncodeToString(a.credentialId),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    base64.RawURLEncoding.EncodeToString(testClientData("webauthn.create", challenge)),
+			"attestationObject": base64.RawURLEncoding.EncodeToString(attestationObject),
+		},
+	})
+	return credential
+}
+
+// get answers navigator.credentials.get(), every assertion increments the sign count
+func (a *testAuthenticator) get(t *testing.T, challenge string, userHandle string) []byte {
+	a.signCount++
+	authenticatorData := a.authenticatorData(t, false)
+	clientData := testClientData("webauthn.get", challenge)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authenticatorData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	assert.NoError(t, err)
+
+	credential, _ := json.Marshal(map[string]interface{}{
+		"id":    base64.RawURLEncoding.EncodeToString(a.credentialId),
+		"rawId": base64.RawURLEncoding.EncodeToString(a.credentialId),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    base64.RawURLEncoding.EncodeToString(clientData),
+			"authenticatorData": base64.RawURLEncoding.EncodeToString(authenticatorData),
+			"signature":         base64.RawURLEncoding.EncodeToString(signature),
+			"userHandle":        base64.RawURLEncoding.EncodeToString([]byte(userHandle)),
+		},
+	})
+	return credential
+}
+
+func TestWebauthn_RegisterAndLogin(t *testing.T) {
+	e := echo.New()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// Mock the Server struct
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	server := &Server{
+		Repository: mockRepo,
+		KeyManager: testKeyManager,
+		WebAuthn:   newTestWebAuthn(t),
+	}
+	authenticator := newTestAuthenticator(t)
+	user := repository.GetUserCredentialsByIdOutput{Id: 1, FullName: "test", PhoneNumber: "+6282222222"}
+
+	// begin the registration, the session is kept by its challenge
+	var session repository.CreateWebauthnSessionInput
+	mockRepo.EXPECT().GetUserCredentialsById(gomock.Any(), repository.GetUserCredentialsByIdInput{Id: 1}).Return(user, nil).Times(2)
+	mockRepo.EXPECT().ListWebauthnCredentials(gomock.Any(), repository.ListWebauthnCredentialsInput{UserId: 1}).Return(
+		repository.ListWebauthnCredentialsOutput{},
+		nil,
+	).Times(2)
+	mockRepo.EXPECT().CreateWebauthnSession(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, input repository.CreateWebauthnSessionInput) error {
+			session = input
+			return nil
+		},
+	)
+
+	req := httptest.NewRequest(http.MethodPost, "/webauthn/register/begin", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(principalContextKey, newTestPrincipal())
+
+	err := server.BeginWebauthnRegistration(c)
+
+	assert.NoError(t, err)
+	var creation generated.WebauthnOptions
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &creation))
+	assert.Equal(t, session.Challenge, creation.PublicKey["challenge"])
+	assert.Equal(t, 1, session.UserId)
+
+	// finish the registration with the credential of the authenticator
+	var stored repository.CreateWebauthnCredentialInput
+	mockRepo.EXPECT().ConsumeWebauthnSession(gomock.Any(), repository.ConsumeWebauthnSessionInput{Challenge: session.Challenge}).Return(
+		repository.ConsumeWebauthnSessionOutput{UserId: 1, Data: session.Data},
+		nil,
+	)
+	mockRepo.EXPECT().CreateWebauthnCredential(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, input repository.CreateWebauthnCredentialInput) (repository.CreateWebauthnCredentialOutput, error) {
+			stored = input
+			return repository.CreateWebauthnCredentialOutput{Id: 5, CreatedAt: time.Now()}, nil
+		},
+	)
+
+	body, _ := json.Marshal(map[string]interface{}{"name": "My phone", "credential": authenticator.create(t, session.Challenge)})
+	req = httptest.NewRequest(http.MethodPost, "/webauthn/register/finish", bytes.NewReader(body))
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+	c.Set(principalContextKey, newTestPrincipal())
+
+	err = server.FinishWebauthnRegistration(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, authenticator.credentialId, stored.CredentialId)
+	assert.Equal(t, "My phone", stored.Name)
+
+	// begin the login, no user yet
+	mockRepo.EXPECT().CreateWebauthnSession(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, input repository.CreateWebauthnSessionInput) error {
+			session = input
+			return nil
+		},
+	)
+
+	req = httptest.NewRequest(http.MethodPost, "/webauthn/login/begin", nil)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	err = server.BeginWebauthnLogin(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, session.UserId)
+
+	// finish the login, the passkey tells the user
+	passkeys := repository.ListWebauthnCredentialsOutput{Credentials: []repository.WebauthnCredential{
+		{Id: 5, CredentialId: stored.CredentialId, Name: stored.Name, Data: stored.Data},
+	}}
+	mockRepo.EXPECT().ConsumeWebauthnSession(gomock.Any(), repository.ConsumeWebauthnSessionInput{Challenge: session.Challenge}).Return(
+		repository.ConsumeWebauthnSessionOutput{Data: session.Data},
+		nil,
+	)
+	mockRepo.EXPECT().GetUserCredentialsById(gomock.Any(), repository.GetUserCredentialsByIdInput{Id: 1}).Return(user, nil)
+	mockRepo.EXPECT().ListWebauthnCredentials(gomock.Any(), gomock.Any()).Return(passkeys, nil)
+	mockRepo.EXPECT().UpdateWebauthnCredential(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, input repository.UpdateWebauthnCredentialInput) error {
+			var credential webauthn.Credential
+			assert.NoError(t, json.Unmarshal([]byte(input.Data), &credential))
+			assert.Equal(t, 5, input.Id)
+			assert.Equal(t, uint32(1), credential.Authenticator.SignCount)
+			return nil
+		},
+	)
+	mockRepo.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).Return(nil)
+	mockRepo.EXPECT().UpdateUserSuccesLogin(gomock.Any(), repository.PostUpdateUserSuccesLoginInput{Id: 1}).Return(nil)
+	mockRepo.EXPECT().CreateLoginEvent(gomock.Any(), repository.CreateLoginEventInput{UserId: 1, Success: true, IpAddress: "192.0.2.1"}).Return(nil)
+
+	req = httptest.NewRequest(http.MethodPost, "/webauthn/login/finish", bytes.NewReader(authenticator.get(t, session.Challenge, "1")))
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	err = server.FinishWebauthnLogin(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp generated.LoginResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Id)
+	assert.NotEmpty(t, resp.Token)
+}
+
+func TestFinishWebauthnLogin_Error_UnknownChallenge(t *testing.T) {
+	e := echo.New()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// Mock the Server struct
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	server := &Server{
+		Repository: mockRepo,
+		WebAuthn:   newTestWebAuthn(t),
+	}
+	authenticator := newTestAuthenticator(t)
+
+	// expired, already answered or never issued
+	mockRepo.EXPECT().ConsumeWebauthnSession(gomock.Any(), gomock.Any()).Return(
+		repository.ConsumeWebauthnSessionOutput{},
+		repository.ErrNotFound,
+	)
+
+	req := httptest.NewRequest(http.MethodPost, "/webauthn/login/finish", bytes.NewReader(authenticator.get(t, "Y2hhbGxlbmdl", "1")))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := server.FinishWebauthnLogin(c)
+
+	assertProblem(t, err, http.StatusUnauthorized, ErrCodeInvalidCredentials)
+}
+
+func TestFinishWebauthnLogin_Error_InvalidAssertion(t *testing.T) {
+	e := echo.New()
+	server := &Server{
+		WebAuthn: newTestWebAuthn(t),
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/webauthn/login/finish", bytes.NewReader([]byte(`{"id":"x","rawId":"x","type":"public-key","response":{}}`)))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := server.FinishWebauthnLogin(c)
+
+	assertProblem(t, err, http.StatusBadRequest, ErrCodeInvalidWebauthn)
+}
+
+func TestDeleteMyPasskey_Error_NotFound(t *testing.T) {
+	e := echo.New()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// Mock the Server struct
+	mockRepo := repository.NewMockRepositoryInterface(ctrl)
+	server := &Server{
+		Repository: mockRepo,
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/my-profile/passkeys/5", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(principalContextKey, newTestPrincipal())
+
+	// the passkey of another user is not found either
+	mockRepo.EXPECT().DeleteWebauthnCredential(gomock.Any(), repository.DeleteWebauthnCredentialInput{Id: 5, UserId: 1}).Return(
+		repository.DeleteWebauthnCredentialOutput{Deleted: false},
+		nil,
+	)
+
+	err := server.DeleteMyPasskey(c, 5)
+
+	assertProblem(t, err, http.StatusNotFound, ErrCodeNotFound)
+}
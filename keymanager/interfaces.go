@@ -0,0 +1,13 @@
+// This file contains the interfaces for the key manager.
+// The key manager owns the asymmetric keys used to sign and verify tokens,
+// so services verifying our tokens only need the public keys (see JWKS).
+package keymanager
+
+type KeyManagerInterface interface {
+	// SigningKey returns the active key new tokens are signed with.
+	SigningKey() (*Key, error)
+	// VerificationKey returns the key with the given kid, active or retired.
+	VerificationKey(kid string) (*Key, error)
+	// JWKS returns the public part of every key that can still verify tokens.
+	JWKS() JWKSet
+}
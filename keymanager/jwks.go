@@ -0,0 +1,115 @@
+// This file contains the JSON Web Key representation of the public keys.
+package keymanager
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK is a public key as described in RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC and OKP
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+func publicJWK(key *Key) (JWK, error) {
+	jwk, err := jwkFor(key.PublicKey)
+	if err != nil {
+		return jwk, err
+	}
+	jwk.Kid = key.Id
+	jwk.Use = "sig"
+	jwk.Alg = key.Algorithm
+	return jwk, nil
+}
+
+// jwkFor returns only the required members of the key, as used by the thumbprint.
+func jwkFor(publicKey crypto.PublicKey) (JWK, error) {
+	switch k := publicKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			N:   encode(k.N.Bytes()),
+			E:   encode(big.NewInt(int64(k.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		return JWK{
+			Kty: "EC",
+			Crv: k.Curve.Params().Name,
+			X:   encode(k.X.FillBytes(make([]byte, size))),
+			Y:   encode(k.Y.FillBytes(make([]byte, size))),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   encode(k),
+		}, nil
+	default:
+		return JWK{}, fmt.Errorf("unsupported key type %T", publicKey)
+	}
+}
+
+// thumbprint computes the RFC 7638 thumbprint of the key, used as default kid.
+// The json members must be in lexicographic order, which a struct can't give us
+// for every key type, so the documents are written by hand.
+func thumbprint(publicKey crypto.PublicKey) (string, error) {
+	jwk, err := jwkFor(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	var members []byte
+	switch jwk.Kty {
+	case "RSA":
+		members, err = json.Marshal(struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N})
+	case "EC":
+		members, err = json.Marshal(struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y})
+	case "OKP":
+		members, err = json.Marshal(struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X})
+	}
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(members)
+	return encode(sum[:]), nil
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
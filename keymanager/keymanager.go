@@ -0,0 +1,252 @@
+// This file contains the key manager implementation.
+package keymanager
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+var (
+	ErrNoSigningKey = errors.New("no signing key configured")
+	ErrKeyNotFound  = errors.New("key not found")
+)
+
+// Key is a single asymmetric key. PrivateKey is nil for keys that are only
+// kept to verify tokens signed before a rotation.
+type Key struct {
+	Id         string
+	Algorithm  string
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+// SigningMethod returns the jwt signing method matching the key algorithm.
+func (k *Key) SigningMethod() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+type KeyManager struct {
+	mu     sync.RWMutex
+	active *Key
+	keys   map[string]*Key
+}
+
+func NewKeyManager() *KeyManager {
+	return &KeyManager{
+		keys: make(map[string]*Key),
+	}
+}
+
+func (m *KeyManager) SigningKey() (*Key, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.active == nil {
+		return nil, ErrNoSigningKey
+	}
+	return m.active, nil
+}
+
+func (m *KeyManager) VerificationKey(kid string) (*Key, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key, ok := m.keys[kid]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// Rotate makes key the active signing key. The previous signing key is kept
+// for verification only, until it is removed with RemoveKey.
+func (m *KeyManager) Rotate(key *Key) error {
+	if key.PrivateKey == nil {
+		return fmt.Errorf("key %q has no private key and can't sign tokens", key.Id)
+	}
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// the retired key can no longer sign, its private key is dropped
+	if m.active != nil {
+		m.keys[m.active.Id] = verificationOnly(m.active)
+	}
+	m.keys[key.Id] = key
+	m.active = key
+	return nil
+}
+
+// AddVerificationKey adds a key that is only used to verify tokens.
+func (m *KeyManager) AddVerificationKey(key *Key) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.keys[key.Id]; ok {
+		return fmt.Errorf("duplicate key id %q", key.Id)
+	}
+	m.keys[key.Id] = verificationOnly(key)
+	return nil
+}
+
+// verificationOnly returns a copy of key without its private key.
+func verificationOnly(key *Key) *Key {
+	return &Key{
+		Id:        key.Id,
+		Algorithm: key.Algorithm,
+		PublicKey: key.PublicKey,
+	}
+}
+
+// RemoveKey drops a retired key, tokens signed with it are no longer accepted.
+func (m *KeyManager) RemoveKey(kid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.active != nil && m.active.Id == kid {
+		return fmt.Errorf("key %q is the active signing key", kid)
+	}
+	delete(m.keys, kid)
+	return nil
+}
+
+func (m *KeyManager) JWKS() JWKSet {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	set := JWKSet{Keys: make([]JWK, 0, len(m.keys))}
+	for _, key := range m.keys {
+		jwk, err := publicJWK(key)
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	// stable output, so the document is cache friendly
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+	return set
+}
+
+// GenerateKey creates a new random key for the given algorithm, it is used for
+// local development and tests when no key is configured.
+func GenerateKey(algorithm string) (*Key, error) {
+	var signer crypto.Signer
+	var err error
+
+	switch algorithm {
+	case AlgorithmRS256:
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgorithmES256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgorithmEdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKey("", signer)
+}
+
+// NewKey wraps a private key, the algorithm is derived from the key type and
+// the kid defaults to the RFC 7638 thumbprint of the public key.
+func NewKey(kid string, signer crypto.Signer) (*Key, error) {
+	key := &Key{
+		Id:         kid,
+		PrivateKey: signer,
+		PublicKey:  signer.Public(),
+	}
+	if err := fillKey(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// NewPublicKey wraps a public key, for keys kept for verification only.
+func NewPublicKey(kid string, publicKey crypto.PublicKey) (*Key, error) {
+	key := &Key{
+		Id:        kid,
+		PublicKey: publicKey,
+	}
+	if err := fillKey(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func fillKey(key *Key) error {
+	algorithm, err := algorithmFor(key.PublicKey)
+	if err != nil {
+		return err
+	}
+	key.Algorithm = algorithm
+
+	if key.Id == "" {
+		key.Id, err = thumbprint(key.PublicKey)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func algorithmFor(publicKey crypto.PublicKey) (string, error) {
+	switch k := publicKey.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < 2048 {
+			return "", fmt.Errorf("RSA keys must be at least 2048 bits, got %d", k.N.BitLen())
+		}
+		return AlgorithmRS256, nil
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return "", fmt.Errorf("ES256 requires a P-256 key, got %s", k.Curve.Params().Name)
+		}
+		return AlgorithmES256, nil
+	case ed25519.PublicKey:
+		return AlgorithmEdDSA, nil
+	default:
+		return "", fmt.Errorf("unsupported key type %T", publicKey)
+	}
+}
+
+func validateKey(key *Key) error {
+	if key.Id == "" {
+		return errors.New("key id is required")
+	}
+	algorithm, err := algorithmFor(key.PublicKey)
+	if err != nil {
+		return err
+	}
+	if algorithm != key.Algorithm {
+		return fmt.Errorf("key %q algorithm %s does not match key type", key.Id, key.Algorithm)
+	}
+	return nil
+}
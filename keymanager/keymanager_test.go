@@ -0,0 +1,128 @@
+package keymanager
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyManager_Rotate(t *testing.T) {
+	manager := NewKeyManager()
+
+	oldKey, err := GenerateKey(AlgorithmRS256)
+	assert.NoError(t, err)
+	assert.NoError(t, manager.Rotate(oldKey))
+
+	newKey, err := GenerateKey(AlgorithmES256)
+	assert.NoError(t, err)
+	assert.NoError(t, manager.Rotate(newKey))
+
+	signingKey, err := manager.SigningKey()
+	assert.NoError(t, err)
+	assert.Equal(t, newKey.Id, signingKey.Id)
+
+	// the retired key still verifies tokens, but can no longer sign them
+	verificationKey, err := manager.VerificationKey(oldKey.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, AlgorithmRS256, verificationKey.Algorithm)
+	assert.Nil(t, verificationKey.PrivateKey)
+	assert.Len(t, manager.JWKS().Keys, 2)
+
+	assert.Error(t, manager.RemoveKey(newKey.Id))
+	assert.NoError(t, manager.RemoveKey(oldKey.Id))
+	_, err = manager.VerificationKey(oldKey.Id)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestKeyManager_JWKS(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmRS256, AlgorithmES256, AlgorithmEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			key, err := GenerateKey(algorithm)
+			assert.NoError(t, err)
+
+			manager := NewKeyManager()
+			assert.NoError(t, manager.Rotate(key))
+
+			set := manager.JWKS()
+			assert.Len(t, set.Keys, 1)
+			assert.Equal(t, key.Id, set.Keys[0].Kid)
+			assert.Equal(t, algorithm, set.Keys[0].Alg)
+			assert.Equal(t, "sig", set.Keys[0].Use)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	oldKey, err := GenerateKey(AlgorithmEdDSA)
+	assert.NoError(t, err)
+	oldKeyFile := writePrivateKey(t, dir, "old.pem", oldKey)
+
+	newKey, err := GenerateKey(AlgorithmES256)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(newKey.PrivateKey)
+	assert.NoError(t, err)
+
+	manager, err := Load(LoadOptions{
+		SigningKey:           string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		SigningKeyId:         "2024-01",
+		VerificationKeyFiles: []string{oldKeyFile},
+	})
+	assert.NoError(t, err)
+
+	signingKey, err := manager.SigningKey()
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-01", signingKey.Id)
+	assert.Equal(t, AlgorithmES256, signingKey.Algorithm)
+
+	// retired keys never sign
+	verificationKey, err := manager.VerificationKey(oldKey.Id)
+	assert.NoError(t, err)
+	assert.Nil(t, verificationKey.PrivateKey)
+}
+
+func TestLoad_UpcomingKey(t *testing.T) {
+	dir := t.TempDir()
+
+	currentKey, err := GenerateKey(AlgorithmEdDSA)
+	assert.NoError(t, err)
+	currentKeyFile := writePrivateKey(t, dir, "current.pem", currentKey)
+	upcomingKey, err := GenerateKey(AlgorithmEdDSA)
+	assert.NoError(t, err)
+	upcomingKeyFile := writePrivateKey(t, dir, "upcoming.pem", upcomingKey)
+
+	// first step, the upcoming key is published but doesn't sign
+	manager, err := Load(LoadOptions{SigningKeyFile: currentKeyFile, VerificationKeyFiles: []string{upcomingKeyFile}})
+	assert.NoError(t, err)
+	signingKey, err := manager.SigningKey()
+	assert.NoError(t, err)
+	assert.Equal(t, currentKey.Id, signingKey.Id)
+	assert.Len(t, manager.JWKS().Keys, 2)
+
+	// second step, it signs with the same kid and stays in the list
+	manager, err = Load(LoadOptions{SigningKeyFile: upcomingKeyFile, VerificationKeyFiles: []string{upcomingKeyFile, currentKeyFile}})
+	assert.NoError(t, err)
+	signingKey, err = manager.SigningKey()
+	assert.NoError(t, err)
+	assert.Equal(t, upcomingKey.Id, signingKey.Id)
+	assert.Len(t, manager.JWKS().Keys, 2)
+}
+
+func TestLoad_Error_NoKey(t *testing.T) {
+	_, err := Load(LoadOptions{})
+	assert.ErrorIs(t, err, ErrNoSigningKey)
+}
+
+func writePrivateKey(t *testing.T, dir string, name string, key *Key) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+	assert.NoError(t, err)
+
+	file := filepath.Join(dir, name)
+	assert.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+	return file
+}
@@ -0,0 +1,150 @@
+// This file contains the helpers loading keys from PEM files or env vars.
+package keymanager
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type LoadOptions struct {
+	// SigningKey is the PEM encoded private key, SigningKeyFile a path to it.
+	SigningKey     string
+	SigningKeyFile string
+	// SigningKeyId overrides the thumbprint based kid of the signing key.
+	SigningKeyId string
+	// VerificationKeyFiles are retired keys, public or private PEM, kept to
+	// verify tokens issued before the last rotation.
+	VerificationKeyFiles []string
+}
+
+// LoadOptionsFromEnv reads the key configuration from the env vars
+// JWT_SIGNING_KEY, JWT_SIGNING_KEY_FILE, JWT_SIGNING_KEY_ID and
+// JWT_VERIFICATION_KEY_FILES (comma separated).
+func LoadOptionsFromEnv() LoadOptions {
+	opts := LoadOptions{
+		SigningKey:     os.Getenv("JWT_SIGNING_KEY"),
+		SigningKeyFile: os.Getenv("JWT_SIGNING_KEY_FILE"),
+		SigningKeyId:   os.Getenv("JWT_SIGNING_KEY_ID"),
+	}
+	for _, file := range strings.Split(os.Getenv("JWT_VERIFICATION_KEY_FILES"), ",") {
+		if file = strings.TrimSpace(file); file != "" {
+			opts.VerificationKeyFiles = append(opts.VerificationKeyFiles, file)
+		}
+	}
+	return opts
+}
+
+// Configured reports whether a signing key has been provided.
+func (o LoadOptions) Configured() bool {
+	return o.SigningKey != "" || o.SigningKeyFile != ""
+}
+
+// Load builds a key manager from the options.
+func Load(opts LoadOptions) (*KeyManager, error) {
+	data := []byte(opts.SigningKey)
+	if opts.SigningKeyFile != "" {
+		var err error
+		data, err = os.ReadFile(opts.SigningKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read signing key: %w", err)
+		}
+	}
+	if len(data) == 0 {
+		return nil, ErrNoSigningKey
+	}
+
+	signer, err := ParsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse signing key: %w", err)
+	}
+	signingKey, err := NewKey(opts.SigningKeyId, signer)
+	if err != nil {
+		return nil, err
+	}
+
+	manager := NewKeyManager()
+	for _, file := range opts.VerificationKeyFiles {
+		key, err := LoadVerificationKeyFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if key.Id == signingKey.Id {
+			continue
+		}
+		if err := manager.AddVerificationKey(key); err != nil {
+			return nil, err
+		}
+	}
+	if err := manager.Rotate(signingKey); err != nil {
+		return nil, err
+	}
+	return manager, nil
+}
+
+// LoadVerificationKeyFile reads a public or private PEM key from file, only
+// its public part is kept.
+func LoadVerificationKeyFile(file string) (*Key, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read verification key: %w", err)
+	}
+
+	publicKey, err := ParsePublicKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse verification key %s: %w", file, err)
+	}
+	return NewPublicKey("", publicKey)
+}
+
+// ParsePrivateKeyPEM parses a PKCS#8, PKCS#1 (RSA) or SEC 1 (EC) private key.
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+// ParsePublicKeyPEM parses a PKIX public key, or derives it from a private key.
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		signer, err := ParsePrivateKeyPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		return signer.Public(), nil
+	}
+}
@@ -0,0 +1,32 @@
+/**
+  The schema of the former database.sql as first released. It is written to be
+  replayed on a database created by that script, which then adopts the
+  migrations with its data, 0002 brings it up to date.
+  */
+
+CREATE TABLE IF NOT EXISTS users (
+  id serial PRIMARY KEY,
+  phone_number VARCHAR(13) UNIQUE NOT NULL,
+  full_name VARCHAR(60) NOT NULL,
+  hash_password VARCHAR UNIQUE NOT NULL,
+  count_login BIGINT DEFAULT 0,
+  created_at TIMESTAMP default CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP default CURRENT_TIMESTAMP,
+  deleted_at TIMESTAMP
+);
+
+CREATE OR REPLACE FUNCTION update_updated_at_users()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = now();
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS update_users_updated_at ON users;
+CREATE TRIGGER update_users_updated_at
+    BEFORE UPDATE
+    ON
+        users
+    FOR EACH ROW
+EXECUTE PROCEDURE update_updated_at_users();
@@ -0,0 +1,30 @@
+DROP TABLE IF EXISTS webauthn_sessions;
+DROP TABLE IF EXISTS webauthn_credentials;
+DROP TABLE IF EXISTS mfa_challenges;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS user_totp;
+DROP TABLE IF EXISTS phone_verifications;
+DROP TABLE IF EXISTS password_resets;
+DROP TABLE IF EXISTS login_events;
+DROP TABLE IF EXISTS revoked_user_tokens;
+DROP TABLE IF EXISTS revoked_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+
+DROP INDEX IF EXISTS users_deleted_at_idx;
+DROP INDEX IF EXISTS users_email_key;
+
+/** Fails when a deleted user and another one share a phone number, the deleted one has to be purged first. */
+DROP INDEX IF EXISTS users_phone_number_key;
+ALTER TABLE users ADD CONSTRAINT users_phone_number_key UNIQUE (phone_number);
+
+ALTER TABLE users
+  DROP COLUMN IF EXISTS failed_login_count,
+  DROP COLUMN IF EXISTS locked_until,
+  DROP COLUMN IF EXISTS phone_verified_at,
+  DROP COLUMN IF EXISTS email,
+  DROP COLUMN IF EXISTS email_verified_at,
+  DROP COLUMN IF EXISTS date_of_birth,
+  DROP COLUMN IF EXISTS locale,
+  DROP COLUMN IF EXISTS timezone,
+  DROP COLUMN IF EXISTS avatar_url,
+  DROP COLUMN IF EXISTS avatar_thumbnails;
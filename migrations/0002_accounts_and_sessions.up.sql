@@ -0,0 +1,152 @@
+/**
+  Everything added to the former database.sql since its first release. It is
+  also replayed on a database created by a later version of that script, so
+  every statement only changes what is missing.
+  */
+
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS failed_login_count INT NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP,
+  ADD COLUMN IF NOT EXISTS phone_verified_at TIMESTAMP,
+  ADD COLUMN IF NOT EXISTS email VARCHAR(254),
+  ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP,
+  ADD COLUMN IF NOT EXISTS date_of_birth DATE,
+  ADD COLUMN IF NOT EXISTS locale VARCHAR(35),
+  ADD COLUMN IF NOT EXISTS timezone VARCHAR(64),
+  ADD COLUMN IF NOT EXISTS avatar_url VARCHAR(2048),
+  ADD COLUMN IF NOT EXISTS avatar_thumbnails JSONB;
+
+/** Deleted users keep their row until purged, their phone number can be registered again. The unique constraint of 0001 is replaced by a partial unique index of the same name. */
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_phone_number_key;
+CREATE UNIQUE INDEX IF NOT EXISTS users_phone_number_key ON users(phone_number) WHERE deleted_at IS NULL;
+
+/** Emails are compared case insensitively, deleted users free theirs like their phone number. */
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON users(lower(email)) WHERE deleted_at IS NULL AND email IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS users_deleted_at_idx ON users(deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+  id serial PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  family_id VARCHAR(32) NOT NULL,
+  token_hash VARCHAR(64) UNIQUE NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  revoked_at TIMESTAMP,
+  created_at TIMESTAMP default CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_id_idx ON refresh_tokens(family_id);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+  jti VARCHAR(64) PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  expires_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP default CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS revoked_tokens_expires_at_idx ON revoked_tokens(expires_at);
+
+/** Every token of the user issued before revoked_before is revoked (logout-all), but the ones of except_session_id (password change). */
+CREATE TABLE IF NOT EXISTS revoked_user_tokens (
+  user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  revoked_before TIMESTAMP NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  except_session_id VARCHAR(32)
+);
+
+/** Every login attempt on an existing account, successful or not. */
+CREATE TABLE IF NOT EXISTS login_events (
+  id bigserial PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  success BOOLEAN NOT NULL,
+  failure_reason VARCHAR(32),
+  ip_address VARCHAR(45) NOT NULL,
+  user_agent VARCHAR(512) NOT NULL,
+  created_at TIMESTAMP NOT NULL default CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS login_events_user_id_idx ON login_events(user_id, id DESC);
+
+/** One time passwords sent by SMS to reset a forgotten password, only the latest one of a user is kept. */
+CREATE TABLE IF NOT EXISTS password_resets (
+  id serial PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  otp_hash VARCHAR(64) NOT NULL,
+  otp_salt VARCHAR(32) NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP default CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS password_resets_user_id_idx ON password_resets(user_id);
+
+/** One time passwords sent by SMS to verify a phone number, either the one of a new account or a pending change. Only the latest one of a user is kept. */
+CREATE TABLE IF NOT EXISTS phone_verifications (
+  id serial PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  phone_number VARCHAR(13) NOT NULL,
+  otp_hash VARCHAR(64) NOT NULL,
+  otp_salt VARCHAR(32) NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP default CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS phone_verifications_user_id_idx ON phone_verifications(user_id);
+
+/** TOTP authenticator app of a user, enabled once a first code is confirmed. */
+CREATE TABLE IF NOT EXISTS user_totp (
+  user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  secret VARCHAR(64) NOT NULL,
+  /** Time step of the last accepted code, a code is never accepted twice. */
+  last_used_step BIGINT,
+  enabled_at TIMESTAMP,
+  created_at TIMESTAMP default CURRENT_TIMESTAMP
+);
+
+/** One time recovery codes of the two-factor authentication, only their sha256 is stored. */
+CREATE TABLE IF NOT EXISTS recovery_codes (
+  id serial PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code_hash VARCHAR(64) NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP default CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS recovery_codes_user_id_idx ON recovery_codes(user_id);
+
+/** Second login step of the users with two-factor authentication, only the sha256 of the token is stored. */
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+  id serial PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  token_hash VARCHAR(64) UNIQUE NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP default CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS mfa_challenges_user_id_idx ON mfa_challenges(user_id);
+
+/** Passkeys of the users, data is the JSON of the credential, public key and sign count included. */
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+  id serial PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  credential_id BYTEA UNIQUE NOT NULL,
+  name VARCHAR(60) NOT NULL,
+  data TEXT NOT NULL,
+  created_at TIMESTAMP default CURRENT_TIMESTAMP,
+  last_used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS webauthn_credentials_user_id_idx ON webauthn_credentials(user_id);
+
+/** Pending WebAuthn ceremonies, looked up by the challenge the authenticator signs. user_id is null for a login. */
+CREATE TABLE IF NOT EXISTS webauthn_sessions (
+  challenge VARCHAR(128) PRIMARY KEY,
+  user_id INT REFERENCES users(id) ON DELETE CASCADE,
+  data TEXT NOT NULL,
+  expires_at TIMESTAMP NOT NULL
+);
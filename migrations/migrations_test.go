@@ -0,0 +1,69 @@
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAll(t *testing.T) {
+	migrations, err := All()
+	assert.NoError(t, err)
+
+	// versions follow each other, a gap is likely a file that went missing
+	for i, migration := range migrations {
+		assert.Equal(t, int64(i+1), migration.Version, migration.String())
+	}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_email.up.sql":   {Data: []byte("ALTER TABLE users ADD email VARCHAR")},
+		"0002_add_email.down.sql": {Data: []byte("ALTER TABLE users DROP email")},
+		"0001_initial.up.sql":     {Data: []byte("CREATE TABLE users (id serial)")},
+		"0001_initial.down.sql":   {Data: []byte("DROP TABLE users")},
+	}
+
+	migrations, err := Load(fsys)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Migration{
+		{Version: 1, Name: "initial", Up: "CREATE TABLE users (id serial)", Down: "DROP TABLE users"},
+		{Version: 2, Name: "add_email", Up: "ALTER TABLE users ADD email VARCHAR", Down: "ALTER TABLE users DROP email"},
+	}, migrations)
+	assert.Equal(t, "0002_add_email", migrations[1].String())
+}
+
+func TestLoad_Error(t *testing.T) {
+	testCases := map[string]fstest.MapFS{
+		"missing down": {
+			"0001_initial.up.sql": {Data: []byte("CREATE TABLE users (id serial)")},
+		},
+		"invalid name": {
+			"initial.sql": {Data: []byte("CREATE TABLE users (id serial)")},
+		},
+		"zero version": {
+			"0000_initial.up.sql":   {Data: []byte("CREATE TABLE users (id serial)")},
+			"0000_initial.down.sql": {Data: []byte("DROP TABLE users")},
+		},
+		"duplicate version": {
+			"0001_initial.up.sql":   {Data: []byte("CREATE TABLE users (id serial)")},
+			"0001_initial.down.sql": {Data: []byte("DROP TABLE users")},
+			"0001_other.up.sql":     {Data: []byte("CREATE TABLE others (id serial)")},
+			"0001_other.down.sql":   {Data: []byte("DROP TABLE others")},
+		},
+		"duplicate file": {
+			"0001_initial.up.sql":   {Data: []byte("CREATE TABLE users (id serial)")},
+			"1_initial.up.sql":      {Data: []byte("CREATE TABLE users (id serial)")},
+			"0001_initial.down.sql": {Data: []byte("DROP TABLE users")},
+		},
+	}
+
+	for name, fsys := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := Load(fsys)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// lockId is the key of the advisory lock held while migrating, the instances
+// started together wait for each other instead of racing.
+const lockId int64 = 0x7573_6572_7376_63 // "usersvc"
+
+// Migrator applies the migrations to a database, the applied versions are
+// tracked in the schema_migrations table.
+type Migrator struct {
+	Db         *sql.DB
+	Migrations []Migration
+	// DryRun prints the SQL that would be run instead of running it
+	DryRun bool
+	Out    io.Writer
+}
+
+type NewMigratorOptions struct {
+	Db     *sql.DB
+	DryRun bool
+	// Out receives the SQL of a dry run, os.Stdout by default
+	Out io.Writer
+}
+
+// NewMigrator returns a migrator of the migrations embedded in the binary.
+func NewMigrator(opts NewMigratorOptions) (*Migrator, error) {
+	migrations, err := All()
+	if err != nil {
+		return nil, err
+	}
+	out := opts.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	return &Migrator{Db: opts.Db, Migrations: migrations, DryRun: opts.DryRun, Out: out}, nil
+}
+
+// Status is a migration and when it was applied, nil while pending.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Status lists every migration along with when it was applied.
+func (m *Migrator) Status(ctx context.Context) (statuses []Status, err error) {
+	err = m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.Migrations {
+			status := Status{Migration: migration}
+			if appliedAt, ok := applied[migration.Version]; ok {
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return
+}
+
+// Up applies the pending migrations up to the version to, all of them when 0.
+// Every migration runs in a transaction of its own.
+func (m *Migrator) Up(ctx context.Context, to int64) (applied []Migration, err error) {
+	if to != 0 && !m.known(to) {
+		return nil, fmt.Errorf("migrations: unknown version %d", to)
+	}
+	err = m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if !m.DryRun {
+			_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+				version BIGINT PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+			)`)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, migration := range m.Migrations {
+			if to != 0 && migration.Version > to {
+				break
+			}
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+			if err := m.run(ctx, conn, migration, true); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return
+}
+
+// Down reverts the given number of migrations, the latest applied first.
+func (m *Migrator) Down(ctx context.Context, steps int) (reverted []Migration, err error) {
+	if steps <= 0 {
+		return nil, fmt.Errorf("migrations: the steps to revert must be positive")
+	}
+	err = m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.Migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.Migrations[i]
+			if _, ok := versions[migration.Version]; !ok {
+				continue
+			}
+			if err := m.run(ctx, conn, migration, false); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return
+}
+
+// run applies or reverts a migration along with its schema_migrations row, or
+// prints its SQL on a dry run.
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, migration Migration, up bool) error {
+	direction, query := "up", migration.Up
+	if !up {
+		direction, query = "down", migration.Down
+	}
+	if m.DryRun {
+		_, err := fmt.Fprintf(m.Out, "-- %s %s\n%s\n", direction, migration, query)
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// a no-op once committed
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("migrations: %s %s: %w", direction, migration, err)
+	}
+	if up {
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations(version, name) VALUES ($1, $2)`, migration.Version, migration.Name)
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// appliedVersions returns when every applied migration was. A version this
+// binary doesn't know means the database was migrated by a newer one, which
+// is refused rather than guessed around.
+func (m *Migrator) appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	var exists bool
+	if err := conn.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, err
+	}
+	applied := map[int64]time.Time{}
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		if !m.known(version) {
+			return nil, fmt.Errorf("migrations: the database has the unknown version %d, it was migrated by a newer release", version)
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+func (m *Migrator) known(version int64) bool {
+	for _, migration := range m.Migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// withLock runs fn on a connection holding the advisory lock, the lock belongs
+// to the session so every statement must go through this connection.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.Db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockId); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockId)
+
+	return fn(conn)
+}
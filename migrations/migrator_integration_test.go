@@ -0,0 +1,205 @@
+package migrations
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+// newIntegrationDb connects to the Postgres of TEST_DATABASE_URL on an empty
+// schema of its own, dropped once the test is over. The test is skipped unless
+// TEST_DATABASE_URL is set, and with -short.
+func newIntegrationDb(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" || testing.Short() {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	schema := "test_" + hex.EncodeToString(b)
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		admin.Close()
+		t.Fatal(err)
+	}
+
+	// every connection of the pool starts with the schema as search path
+	if u, err := url.Parse(dsn); err == nil && strings.HasPrefix(u.Scheme, "postgres") {
+		query := u.Query()
+		query.Set("search_path", schema)
+		u.RawQuery = query.Encode()
+		dsn = u.String()
+	} else {
+		dsn += " search_path=" + schema
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		if _, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`); err != nil {
+			t.Error(err)
+		}
+		admin.Close()
+	})
+	return db
+}
+
+func tableExists(t *testing.T, db *sql.DB, table string) bool {
+	var exists bool
+	if err := db.QueryRow(`SELECT to_regclass($1) IS NOT NULL`, table).Scan(&exists); err != nil {
+		t.Fatal(err)
+	}
+	return exists
+}
+
+func TestIntegration_Migrator(t *testing.T) {
+	db := newIntegrationDb(t)
+	ctx := context.Background()
+	var out bytes.Buffer
+	migrator, err := NewMigrator(NewMigratorOptions{Db: db, Out: &out})
+	assert.NoError(t, err)
+
+	// a dry run only prints the SQL
+	migrator.DryRun = true
+	applied, err := migrator.Up(ctx, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, migrator.Migrations, applied)
+	assert.Contains(t, out.String(), "-- up 0001_initial\n")
+	assert.False(t, tableExists(t, db, "schema_migrations"))
+	assert.False(t, tableExists(t, db, "users"))
+
+	migrator.DryRun = false
+	applied, err = migrator.Up(ctx, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, migrator.Migrations, applied)
+	assert.True(t, tableExists(t, db, "users"))
+
+	applied, err = migrator.Up(ctx, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+
+	statuses, err := migrator.Status(ctx)
+	assert.NoError(t, err)
+	for _, status := range statuses {
+		assert.NotNil(t, status.AppliedAt, status.Migration.String())
+	}
+
+	// every down reverts its up
+	reverted, err := migrator.Down(ctx, len(migrator.Migrations))
+	assert.NoError(t, err)
+	assert.Len(t, reverted, len(migrator.Migrations))
+	assert.Equal(t, migrator.Migrations[0], reverted[len(reverted)-1])
+	assert.False(t, tableExists(t, db, "users"))
+	statuses, err = migrator.Status(ctx)
+	assert.NoError(t, err)
+	for _, status := range statuses {
+		assert.Nil(t, status.AppliedAt, status.Migration.String())
+	}
+
+	applied, err = migrator.Up(ctx, 0)
+	assert.NoError(t, err)
+	assert.Len(t, applied, len(migrator.Migrations))
+}
+
+func TestIntegration_Migrator_Concurrent(t *testing.T) {
+	db := newIntegrationDb(t)
+	ctx := context.Background()
+
+	// the advisory lock lets a single instance apply every migration
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	total := 0
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			migrator, err := NewMigrator(NewMigratorOptions{Db: db})
+			assert.NoError(t, err)
+			applied, err := migrator.Up(ctx, 0)
+			assert.NoError(t, err)
+			mutex.Lock()
+			total += len(applied)
+			mutex.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	migrations, err := All()
+	assert.NoError(t, err)
+	assert.Equal(t, len(migrations), total)
+}
+
+func TestIntegration_Migrator_Adoption(t *testing.T) {
+	// the former database.sql as first released, and as it was when the migrations were introduced
+	for _, file := range []string{"testdata/database.baseline.sql", "testdata/database.sql"} {
+		t.Run(file, func(t *testing.T) {
+			db := newIntegrationDb(t)
+			ctx := context.Background()
+			migrator, err := NewMigrator(NewMigratorOptions{Db: db})
+			assert.NoError(t, err)
+
+			// such a database has a schema but no schema_migrations
+			schema, err := os.ReadFile(file)
+			assert.NoError(t, err)
+			_, err = db.Exec(string(schema))
+			assert.NoError(t, err)
+			_, err = db.Exec(`INSERT INTO users(phone_number, full_name, hash_password, deleted_at) VALUES ('+628111111111', 'Arthur Dent', 'hash', now())`)
+			assert.NoError(t, err)
+
+			applied, err := migrator.Up(ctx, 0)
+			assert.NoError(t, err)
+			assert.Equal(t, migrator.Migrations, applied)
+
+			// the rows are kept and get the columns added since
+			var failedLoginCount int
+			assert.NoError(t, db.QueryRow(`SELECT failed_login_count FROM users WHERE phone_number = '+628111111111'`).Scan(&failedLoginCount))
+			assert.Equal(t, 0, failedLoginCount)
+			assert.True(t, tableExists(t, db, "webauthn_sessions"))
+
+			// the phone number of the deleted user can be registered again, but only once
+			_, err = db.Exec(`INSERT INTO users(phone_number, full_name, hash_password, email) VALUES ('+628111111111', 'Ford Prefect', 'other-hash', 'ford@example.com')`)
+			assert.NoError(t, err)
+			_, err = db.Exec(`INSERT INTO users(phone_number, full_name, hash_password) VALUES ('+628111111111', 'Zaphod Beeblebrox', 'third-hash')`)
+			assert.ErrorContains(t, err, "users_phone_number_key")
+		})
+	}
+}
+
+func TestIntegration_Migrator_Error_UnknownVersion(t *testing.T) {
+	db := newIntegrationDb(t)
+	ctx := context.Background()
+	migrator, err := NewMigrator(NewMigratorOptions{Db: db})
+	assert.NoError(t, err)
+	_, err = migrator.Up(ctx, 0)
+	assert.NoError(t, err)
+
+	// migrated by a newer release
+	_, err = db.Exec(`INSERT INTO schema_migrations(version, name) VALUES (9999, 'future')`)
+	assert.NoError(t, err)
+
+	_, err = migrator.Up(ctx, 0)
+	assert.Error(t, err)
+	_, err = migrator.Down(ctx, 1)
+	assert.Error(t, err)
+	_, err = migrator.Up(ctx, 9999)
+	assert.Error(t, err)
+}
@@ -0,0 +1,12 @@
+// This file contains the interfaces for the password hashers.
+// The encoded hash carries its algorithm and parameters, so hashes made with
+// older parameters keep verifying and can be upgraded when the user logs in.
+package passwordhash
+
+type PasswordHasher interface {
+	// Hash returns the encoded hash of the password, with a random salt.
+	Hash(password string) (string, error)
+	// Verify tells whether the password matches the hash, and whether the hash
+	// should be replaced because its algorithm or parameters are outdated.
+	Verify(hash string, password string) (match bool, needsRehash bool, err error)
+}
@@ -0,0 +1,108 @@
+package passwordhash
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+var ErrUnsupportedHash = errors.New("passwordhash: unsupported hash format")
+
+// DefaultBcrypt and DefaultArgon2id are the parameters used when nothing is
+// configured, argon2id follows the OWASP recommendation.
+var (
+	DefaultBcrypt   = Bcrypt{Cost: 12}
+	DefaultArgon2id = Argon2id{Memory: 19 * 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+)
+
+// algorithm is a PasswordHasher recognizing its own hashes.
+type algorithm interface {
+	PasswordHasher
+	identifies(hash string) bool
+}
+
+// Hasher hashes new passwords with its current algorithm and verifies the
+// hashes of every supported algorithm, those of another algorithm need a rehash.
+type Hasher struct {
+	current    algorithm
+	algorithms []algorithm
+}
+
+// New hashes with current, which is either a Bcrypt or an Argon2id.
+func New(current PasswordHasher) (*Hasher, error) {
+	switch current := current.(type) {
+	case Bcrypt:
+		return &Hasher{current: current, algorithms: []algorithm{current, DefaultArgon2id}}, nil
+	case Argon2id:
+		return &Hasher{current: current, algorithms: []algorithm{current, DefaultBcrypt}}, nil
+	default:
+		return nil, fmt.Errorf("passwordhash: unsupported algorithm %T", current)
+	}
+}
+
+// Default hashes with DefaultArgon2id.
+func Default() *Hasher {
+	hasher, _ := New(DefaultArgon2id)
+	return hasher
+}
+
+// NewHasherFromEnv picks the algorithm from PASSWORD_HASH_ALGORITHM, argon2id
+// by default, unset parameters keep their default value.
+func NewHasherFromEnv() (*Hasher, error) {
+	switch algo := os.Getenv("PASSWORD_HASH_ALGORITHM"); algo {
+	case "", "argon2id":
+		params := DefaultArgon2id
+		ints := map[string]*uint32{
+			"PASSWORD_ARGON2_MEMORY":     &params.Memory,
+			"PASSWORD_ARGON2_ITERATIONS": &params.Iterations,
+		}
+		for name, target := range ints {
+			if value := os.Getenv(name); value != "" {
+				parsed, err := strconv.ParseUint(value, 10, 32)
+				if err != nil || parsed == 0 {
+					return nil, fmt.Errorf("%s must be a positive integer", name)
+				}
+				*target = uint32(parsed)
+			}
+		}
+		if value := os.Getenv("PASSWORD_ARGON2_PARALLELISM"); value != "" {
+			parsed, err := strconv.ParseUint(value, 10, 8)
+			if err != nil || parsed == 0 {
+				return nil, fmt.Errorf("PASSWORD_ARGON2_PARALLELISM must be an integer between 1 and 255")
+			}
+			params.Parallelism = uint8(parsed)
+		}
+		return New(params)
+	case "bcrypt":
+		params := DefaultBcrypt
+		if value := os.Getenv("PASSWORD_BCRYPT_COST"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 4 || parsed > 31 {
+				return nil, fmt.Errorf("PASSWORD_BCRYPT_COST must be an integer between 4 and 31")
+			}
+			params.Cost = parsed
+		}
+		return New(params)
+	default:
+		return nil, fmt.Errorf("PASSWORD_HASH_ALGORITHM must be argon2id or bcrypt, not %q", algo)
+	}
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+func (h *Hasher) Verify(hash string, password string) (bool, bool, error) {
+	for _, algo := range h.algorithms {
+		if !algo.identifies(hash) {
+			continue
+		}
+		match, needsRehash, err := algo.Verify(hash, password)
+		if err != nil || !match {
+			return false, false, err
+		}
+		return true, needsRehash || algo != h.current, nil
+	}
+	return false, false, ErrUnsupportedHash
+}
@@ -0,0 +1,110 @@
+package passwordhash
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fast parameters, the defaults take a while
+var (
+	testBcrypt   = Bcrypt{Cost: 4}
+	testArgon2id = Argon2id{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+)
+
+func TestHasher_HashAndVerify(t *testing.T) {
+	for _, current := range []PasswordHasher{testBcrypt, testArgon2id} {
+		hasher, err := New(current)
+		assert.NoError(t, err)
+
+		hash, err := hasher.Hash("my@Password1")
+		assert.NoError(t, err)
+
+		match, needsRehash, err := hasher.Verify(hash, "my@Password1")
+		assert.NoError(t, err)
+		assert.True(t, match)
+		assert.False(t, needsRehash)
+
+		match, _, err = hasher.Verify(hash, "my@Password2")
+		assert.NoError(t, err)
+		assert.False(t, match)
+	}
+}
+
+func TestArgon2id_Hash_Format(t *testing.T) {
+	hash, err := testArgon2id.Hash("my@Password1")
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$"), hash)
+
+	// the same password gets another salt
+	other, err := testArgon2id.Hash("my@Password1")
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, other)
+}
+
+func TestHasher_Verify_NeedsRehash(t *testing.T) {
+	bcryptHash, err := testBcrypt.Hash("my@Password1")
+	assert.NoError(t, err)
+	argon2idHash, err := testArgon2id.Hash("my@Password1")
+	assert.NoError(t, err)
+
+	stronger := testArgon2id
+	stronger.Iterations = 2
+
+	testCases := map[string]struct {
+		current PasswordHasher
+		hash    string
+	}{
+		"other algorithm":     {current: testArgon2id, hash: bcryptHash},
+		"other bcrypt cost":   {current: Bcrypt{Cost: 5}, hash: bcryptHash},
+		"other argon2id cost": {current: stronger, hash: argon2idHash},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			hasher, err := New(testCase.current)
+			assert.NoError(t, err)
+
+			match, needsRehash, err := hasher.Verify(testCase.hash, "my@Password1")
+
+			assert.NoError(t, err)
+			assert.True(t, match)
+			assert.True(t, needsRehash)
+		})
+	}
+}
+
+func TestHasher_Verify_Error(t *testing.T) {
+	hasher, err := New(testArgon2id)
+	assert.NoError(t, err)
+
+	for _, hash := range []string{"", "plain", "$argon2id$v=19$m=64,t=1$c2FsdA$a2V5", "$argon2id$v=18$m=64,t=1,p=1$c2FsdA$a2V5"} {
+		_, _, err := hasher.Verify(hash, "my@Password1")
+		assert.Error(t, err, hash)
+	}
+}
+
+func TestNewHasherFromEnv(t *testing.T) {
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "bcrypt")
+	t.Setenv("PASSWORD_BCRYPT_COST", "10")
+
+	hasher, err := NewHasherFromEnv()
+
+	assert.NoError(t, err)
+	assert.Equal(t, Bcrypt{Cost: 10}, hasher.current)
+
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "argon2id")
+	t.Setenv("PASSWORD_ARGON2_MEMORY", "65536")
+
+	hasher, err = NewHasherFromEnv()
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(65536), hasher.current.(Argon2id).Memory)
+	assert.Equal(t, DefaultArgon2id.Iterations, hasher.current.(Argon2id).Iterations)
+
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "md5")
+	_, err = NewHasherFromEnv()
+	assert.Error(t, err)
+}
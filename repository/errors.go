@@ -0,0 +1,89 @@
+// This file contains the errors of the repository layer. The driver errors
+// are translated, so callers never match on pq types or messages.
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when the row looked up doesn't exist, or belongs to a deleted user.
+	ErrNotFound = errors.New("repository: not found")
+	// ErrUniqueViolation matches the ConstraintError of a unique constraint or index.
+	ErrUniqueViolation = errors.New("repository: unique violation")
+	// ErrForeignKeyViolation matches the ConstraintError of a foreign key, the
+	// referenced row doesn't exist (anymore).
+	ErrForeignKeyViolation = errors.New("repository: foreign key violation")
+	// ErrSerialization is returned when the statement conflicted with a
+	// concurrent transaction, it can be retried.
+	ErrSerialization = errors.New("repository: serialization failure")
+)
+
+// The constraints callers tell apart, the names are those of the schema.
+const (
+	ConstraintUserPhoneNumber = "users_phone_number_key"
+	ConstraintUserEmail       = "users_email_key"
+)
+
+// ConstraintError is the violation of a constraint. errors.Is matches it
+// against ErrUniqueViolation or ErrForeignKeyViolation.
+type ConstraintError struct {
+	// Kind is either ErrUniqueViolation or ErrForeignKeyViolation
+	Kind error
+	// Constraint is the name of the violated constraint or unique index
+	Constraint string
+	// Err is the error of the driver
+	Err error
+}
+
+func (e *ConstraintError) Error() string {
+	return fmt.Sprintf("%v %q: %v", e.Kind, e.Constraint, e.Err)
+}
+
+func (e *ConstraintError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *ConstraintError) Unwrap() error {
+	return e.Err
+}
+
+// IsConstraintViolation tells whether err is the violation of the named constraint.
+func IsConstraintViolation(err error, constraint string) bool {
+	var constraintErr *ConstraintError
+	return errors.As(err, &constraintErr) && constraintErr.Constraint == constraint
+}
+
+// translateError turns the errors of database/sql and pq into the errors of
+// this package, the others are returned as is.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	// already translated, a ConstraintError wraps the pq error
+	var constraintErr *ConstraintError
+	if errors.As(err, &constraintErr) {
+		return err
+	}
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch pqErr.Code.Name() {
+	case "unique_violation":
+		return &ConstraintError{Kind: ErrUniqueViolation, Constraint: pqErr.Constraint, Err: err}
+	case "foreign_key_violation":
+		return &ConstraintError{Kind: ErrForeignKeyViolation, Constraint: pqErr.Constraint, Err: err}
+	case "serialization_failure", "deadlock_detected":
+		return fmt.Errorf("%w: %v", ErrSerialization, err)
+	}
+	return err
+}
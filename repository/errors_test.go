@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslateError(t *testing.T) {
+	assert.Nil(t, translateError(nil))
+	assert.Equal(t, ErrNotFound, translateError(sql.ErrNoRows))
+	assert.Equal(t, ErrNotFound, translateError(fmt.Errorf("scan: %w", sql.ErrNoRows)))
+
+	// the others are returned as is
+	other := errors.New("connection refused")
+	assert.Equal(t, other, translateError(other))
+	checkViolation := &pq.Error{Code: "23514", Constraint: "users_check"}
+	assert.Equal(t, error(checkViolation), translateError(checkViolation))
+}
+
+func TestTranslateError_Constraint(t *testing.T) {
+	testCases := map[string]struct {
+		err  *pq.Error
+		kind error
+	}{
+		"unique":      {err: &pq.Error{Code: "23505", Constraint: ConstraintUserPhoneNumber}, kind: ErrUniqueViolation},
+		"foreign key": {err: &pq.Error{Code: "23503", Constraint: "refresh_tokens_user_id_fkey"}, kind: ErrForeignKeyViolation},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := translateError(testCase.err)
+
+			var constraintErr *ConstraintError
+			if assert.ErrorAs(t, err, &constraintErr) {
+				assert.Equal(t, testCase.err.Constraint, constraintErr.Constraint)
+			}
+			assert.ErrorIs(t, err, testCase.kind)
+			assert.True(t, IsConstraintViolation(err, testCase.err.Constraint))
+			assert.False(t, IsConstraintViolation(err, ConstraintUserEmail))
+			// the driver error is still there
+			var pqErr *pq.Error
+			assert.ErrorAs(t, err, &pqErr)
+
+			// translating twice changes nothing
+			assert.Equal(t, err, translateError(err))
+		})
+	}
+}
+
+func TestTranslateError_Serialization(t *testing.T) {
+	for _, code := range []pq.ErrorCode{"40001", "40P01"} {
+		err := translateError(&pq.Error{Code: code})
+
+		assert.ErrorIs(t, err, ErrSerialization, string(code))
+		assert.False(t, errors.Is(err, ErrUniqueViolation))
+	}
+}
@@ -2,13 +2,16 @@ package repository
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 func (r *Repository) GetTestById(ctx context.Context, input GetTestByIdInput) (output GetTestByIdOutput, err error) {
 	err = r.Db.QueryRowContext(ctx, "SELECT name FROM test WHERE id = $1", input.Id).Scan(&output.Name)
 	if err != nil {
-		return
+		return output, translateError(err)
 	}
 	return
 }
@@ -16,45 +19,633 @@ func (r *Repository) GetTestById(ctx context.Context, input GetTestByIdInput) (o
 func (r *Repository) CreateNewUser(ctx context.Context, input GetRegistrationInput) (output GetRegistrationOutput, err error) {
 	err = r.Db.QueryRowContext(ctx, `INSERT INTO users(phone_number, full_name, hash_password) VALUES ($1, $2, $3) RETURNING id`, input.PhoneNumber, input.FullName, input.Password).Scan(&output.Id)
 	if err != nil {
-		return
+		return output, translateError(err)
 	}
 	return
 }
 
 func (r *Repository) GetUserByPhoneNumber(ctx context.Context, input GetLoginInput) (output GetLoginOutput, err error) {
-	err = r.Db.QueryRowContext(ctx, `SELECT id, hash_password, phone_number, full_name FROM users WHERE phone_number = $1`, input.PhoneNumber).Scan(&output.Id, &output.Password, &output.PhoneNumber, &output.FullName)
+	err = r.Db.QueryRowContext(ctx, `SELECT u.id, u.hash_password, u.phone_number, u.full_name, u.failed_login_count, u.locked_until, u.phone_verified_at, t.enabled_at IS NOT NULL
+		FROM users u LEFT JOIN user_totp t ON t.user_id = u.id
+		WHERE u.phone_number = $1 AND u.deleted_at IS NULL`, input.PhoneNumber).Scan(&output.Id, &output.Password, &output.PhoneNumber, &output.FullName, &output.FailedLoginCount, &output.LockedUntil, &output.PhoneVerifiedAt, &output.TotpEnabled)
 	if err != nil {
-		return output, err
+		return output, translateError(err)
 	}
 	return
 }
 
+// UpdateUserSuccesLogin counts the login and clears the failed login streak and lock.
 func (r *Repository) UpdateUserSuccesLogin(ctx context.Context, input PostUpdateUserSuccesLoginInput) error {
-	err := r.Db.QueryRowContext(ctx, `UPDATE users SET count_login = count_login + $1 WHERE id = $2`, 1, input.Id)
+	err := r.Db.QueryRowContext(ctx, `UPDATE users SET count_login = count_login + $1, failed_login_count = 0, locked_until = NULL WHERE id = $2 AND deleted_at IS NULL`, 1, input.Id)
 	if err.Err() != nil {
-		return err.Err()
+		return translateError(err.Err())
+	}
+	return nil
+}
+
+// RecordFailedLogin increments the failed login streak atomically, concurrent
+// attempts all get a distinct count.
+func (r *Repository) RecordFailedLogin(ctx context.Context, input RecordFailedLoginInput) (output RecordFailedLoginOutput, err error) {
+	err = r.Db.QueryRowContext(ctx, `UPDATE users SET failed_login_count = failed_login_count + 1 WHERE id = $1 AND deleted_at IS NULL RETURNING failed_login_count`, input.Id).Scan(&output.FailedLoginCount)
+	if err != nil {
+		return output, translateError(err)
+	}
+	return
+}
+
+func (r *Repository) LockUser(ctx context.Context, input LockUserInput) error {
+	_, err := r.Db.ExecContext(ctx, `UPDATE users SET locked_until = $1 WHERE id = $2 AND deleted_at IS NULL`, input.LockedUntil.UTC(), input.Id)
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (r *Repository) GetUserCredentialsById(ctx context.Context, input GetUserCredentialsByIdInput) (output GetUserCredentialsByIdOutput, err error) {
+	err = r.Db.QueryRowContext(ctx, `SELECT id, hash_password, full_name, phone_number, phone_verified_at, locked_until FROM users WHERE id = $1 AND deleted_at IS NULL`, input.Id).Scan(&output.Id, &output.Password, &output.FullName, &output.PhoneNumber, &output.PhoneVerifiedAt, &output.LockedUntil)
+	if err != nil {
+		return output, translateError(err)
+	}
+	return
+}
+
+// UpdateUserPassword also unlocks the account, the failed logins were made with the previous password.
+func (r *Repository) UpdateUserPassword(ctx context.Context, input UpdateUserPasswordInput) error {
+	_, err := r.Db.ExecContext(ctx, `UPDATE users SET hash_password = $1, failed_login_count = 0, locked_until = NULL WHERE id = $2 AND deleted_at IS NULL`, input.Password, input.Id)
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// RehashUserPassword replaces the hash of the same password, unless the
+// password was changed in the meantime.
+func (r *Repository) RehashUserPassword(ctx context.Context, input RehashUserPasswordInput) error {
+	_, err := r.Db.ExecContext(ctx, `UPDATE users SET hash_password = $1 WHERE id = $2 AND hash_password = $3 AND deleted_at IS NULL`, input.NewHash, input.Id, input.CurrentHash)
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// SoftDeleteUser only flags the user as deleted, the row is kept until PurgeDeletedUsers.
+// The avatar is dropped at once, its files are no longer served.
+func (r *Repository) SoftDeleteUser(ctx context.Context, input SoftDeleteUserInput) (output SoftDeleteUserOutput, err error) {
+	res, err := r.Db.ExecContext(ctx, `UPDATE users SET deleted_at = $1, avatar_url = NULL, avatar_thumbnails = NULL WHERE id = $2 AND deleted_at IS NULL`, time.Now().UTC(), input.Id)
+	if err != nil {
+		return output, translateError(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return output, translateError(err)
+	}
+	output.Deleted = affected == 1
+	return
+}
+
+// RestoreUser fails with the users_phone_number_key violation when the phone
+// number was registered again in the meantime.
+func (r *Repository) RestoreUser(ctx context.Context, input RestoreUserInput) (output RestoreUserOutput, err error) {
+	res, err := r.Db.ExecContext(ctx, `UPDATE users SET deleted_at = NULL, failed_login_count = 0, locked_until = NULL WHERE id = $1 AND deleted_at IS NOT NULL`, input.Id)
+	if err != nil {
+		return output, translateError(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return output, translateError(err)
+	}
+	output.Restored = affected == 1
+	return
+}
+
+// PurgeDeletedUsers hard deletes the users soft deleted before DeletedBefore,
+// their tokens and login history are deleted by the foreign keys cascade.
+func (r *Repository) PurgeDeletedUsers(ctx context.Context, input PurgeDeletedUsersInput) (output PurgeDeletedUsersOutput, err error) {
+	rows, err := r.Db.QueryContext(ctx, `DELETE FROM users WHERE deleted_at IS NOT NULL AND deleted_at < $1 RETURNING id`, input.DeletedBefore.UTC())
+	if err != nil {
+		return output, translateError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			return output, translateError(err)
+		}
+		output.Ids = append(output.Ids, id)
+	}
+	output.Purged = int64(len(output.Ids))
+	return output, translateError(rows.Err())
+}
+
+// CreatePasswordReset replaces the previous reset of the user, only the latest OTP is valid.
+func (r *Repository) CreatePasswordReset(ctx context.Context, input CreatePasswordResetInput) error {
+	_, err := r.Db.ExecContext(ctx, `WITH deleted AS (DELETE FROM password_resets WHERE user_id = $1)
+		INSERT INTO password_resets(user_id, otp_hash, otp_salt, expires_at) VALUES ($1, $2, $3, $4)`,
+		input.UserId, input.OtpHash, input.OtpSalt, input.ExpiresAt.UTC())
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (r *Repository) GetPasswordResetByPhoneNumber(ctx context.Context, input GetPasswordResetInput) (output GetPasswordResetOutput, err error) {
+	err = r.Db.QueryRowContext(ctx, `SELECT pr.id, pr.user_id, u.full_name, pr.otp_hash, pr.otp_salt, pr.attempts, pr.expires_at, pr.used_at
+		FROM password_resets pr JOIN users u ON u.id = pr.user_id
+		WHERE u.phone_number = $1 AND u.deleted_at IS NULL
+		ORDER BY pr.id DESC LIMIT 1`, input.PhoneNumber).Scan(&output.Id, &output.UserId, &output.FullName, &output.OtpHash, &output.OtpSalt, &output.Attempts, &output.ExpiresAt, &output.UsedAt)
+	if err != nil {
+		return output, translateError(err)
+	}
+	return
+}
+
+// IncrementPasswordResetAttempts counts the attempt before the OTP is checked,
+// so concurrent guesses can't go past the limit.
+func (r *Repository) IncrementPasswordResetAttempts(ctx context.Context, input IncrementPasswordResetAttemptsInput) (output IncrementPasswordResetAttemptsOutput, err error) {
+	err = r.Db.QueryRowContext(ctx, `UPDATE password_resets SET attempts = attempts + 1 WHERE id = $1 RETURNING attempts`, input.Id).Scan(&output.Attempts)
+	if err != nil {
+		return output, translateError(err)
+	}
+	return
+}
+
+func (r *Repository) ConsumePasswordReset(ctx context.Context, input ConsumePasswordResetInput) (output ConsumePasswordResetOutput, err error) {
+	res, err := r.Db.ExecContext(ctx, `UPDATE password_resets SET used_at = $1 WHERE id = $2 AND used_at IS NULL`, time.Now().UTC(), input.Id)
+	if err != nil {
+		return output, translateError(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return output, translateError(err)
+	}
+	output.Consumed = affected == 1
+	return
+}
+
+// CreatePhoneVerification replaces the previous verification of the user, only the latest OTP is valid.
+func (r *Repository) CreatePhoneVerification(ctx context.Context, input CreatePhoneVerificationInput) error {
+	_, err := r.Db.ExecContext(ctx, `WITH deleted AS (DELETE FROM phone_verifications WHERE user_id = $1)
+		INSERT INTO phone_verifications(user_id, phone_number, otp_hash, otp_salt, expires_at) VALUES ($1, $2, $3, $4, $5)`,
+		input.UserId, input.PhoneNumber, input.OtpHash, input.OtpSalt, input.ExpiresAt.UTC())
+	if err != nil {
+		return translateError(err)
 	}
 	return nil
 }
 
-func (r *Repository) UpdateUserByPhoneNumber(ctx context.Context, input UpdateUserInput) (output UpdateUserOutput, err error) {
-	var query string
-	if input.Name != nil && input.PhoneNumber != nil {
-		query = fmt.Sprintf(`UPDATE users SET full_name = '%s', phone_number = '%s' WHERE phone_number = '%s'`, *input.Name, *input.PhoneNumber, input.OldPhoneNumber)
+func (r *Repository) GetPhoneVerificationByUserId(ctx context.Context, input GetPhoneVerificationInput) (output GetPhoneVerificationOutput, err error) {
+	err = r.Db.QueryRowContext(ctx, `SELECT pv.id, pv.user_id, pv.phone_number, pv.otp_hash, pv.otp_salt, pv.attempts, pv.expires_at, pv.used_at
+		FROM phone_verifications pv JOIN users u ON u.id = pv.user_id
+		WHERE pv.user_id = $1 AND u.deleted_at IS NULL
+		ORDER BY pv.id DESC LIMIT 1`, input.UserId).Scan(&output.Id, &output.UserId, &output.PhoneNumber, &output.OtpHash, &output.OtpSalt, &output.Attempts, &output.ExpiresAt, &output.UsedAt)
+	if err != nil {
+		return output, translateError(err)
 	}
+	return
+}
 
-	if input.Name != nil && input.PhoneNumber == nil {
-		query = fmt.Sprintf(`UPDATE users SET full_name = '%s' WHERE phone_number = '%s'`, *input.Name, input.OldPhoneNumber)
+// IncrementPhoneVerificationAttempts counts the attempt before the OTP is checked,
+// so concurrent guesses can't go past the limit.
+func (r *Repository) IncrementPhoneVerificationAttempts(ctx context.Context, input IncrementPhoneVerificationAttemptsInput) (output IncrementPhoneVerificationAttemptsOutput, err error) {
+	err = r.Db.QueryRowContext(ctx, `UPDATE phone_verifications SET attempts = attempts + 1 WHERE id = $1 RETURNING attempts`, input.Id).Scan(&output.Attempts)
+	if err != nil {
+		return output, translateError(err)
 	}
+	return
+}
 
-	if input.Name == nil && input.PhoneNumber != nil {
-		query = fmt.Sprintf(`UPDATE users SET phone_number = '%s' WHERE phone_number = '%s'`, *input.PhoneNumber, input.OldPhoneNumber)
+func (r *Repository) ConsumePhoneVerification(ctx context.Context, input ConsumePhoneVerificationInput) (output ConsumePhoneVerificationOutput, err error) {
+	res, err := r.Db.ExecContext(ctx, `UPDATE phone_verifications SET used_at = $1 WHERE id = $2 AND used_at IS NULL`, time.Now().UTC(), input.Id)
+	if err != nil {
+		return output, translateError(err)
 	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return output, translateError(err)
+	}
+	output.Consumed = affected == 1
+	return
+}
+
+// VerifyUserPhoneNumber sets the verified phone number of the user, it fails
+// on the unique phone number index when another account registered it meanwhile.
+func (r *Repository) VerifyUserPhoneNumber(ctx context.Context, input VerifyUserPhoneNumberInput) error {
+	now := time.Now().UTC()
+	_, err := r.Db.ExecContext(ctx, `UPDATE users SET phone_number = $1, phone_verified_at = $2, updated_at = $2 WHERE id = $3 AND deleted_at IS NULL`, input.PhoneNumber, now, input.Id)
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// CreateTotpSecret starts the enrollment, a pending one is replaced.
+func (r *Repository) CreateTotpSecret(ctx context.Context, input CreateTotpSecretInput) (output CreateTotpSecretOutput, err error) {
+	res, err := r.Db.ExecContext(ctx, `INSERT INTO user_totp(user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_used_step = NULL, created_at = CURRENT_TIMESTAMP
+		WHERE user_totp.enabled_at IS NULL`, input.UserId, input.Secret)
+	if err != nil {
+		return output, translateError(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return output, translateError(err)
+	}
+	output.Created = affected == 1
+	return
+}
 
-	err = r.Db.QueryRowContext(ctx, query+"RETURNING id").Scan(&output.Id)
+func (r *Repository) GetTotpByUserId(ctx context.Context, input GetTotpInput) (output GetTotpOutput, err error) {
+	err = r.Db.QueryRowContext(ctx, `SELECT t.secret, t.enabled_at FROM user_totp t JOIN users u ON u.id = t.user_id
+		WHERE t.user_id = $1 AND u.deleted_at IS NULL`, input.UserId).Scan(&output.Secret, &output.EnabledAt)
 	if err != nil {
+		return output, translateError(err)
+	}
+	return
+}
+
+// UseTotpStep records the time step of an accepted code, so that neither it
+// nor an older one can be replayed.
+func (r *Repository) UseTotpStep(ctx context.Context, input UseTotpStepInput) (output UseTotpStepOutput, err error) {
+	res, err := r.Db.ExecContext(ctx, `UPDATE user_totp t SET last_used_step = $1 FROM users u
+		WHERE u.id = t.user_id AND t.user_id = $2 AND u.deleted_at IS NULL AND (t.last_used_step IS NULL OR t.last_used_step < $1)`, input.Step, input.UserId)
+	if err != nil {
+		return output, translateError(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return output, translateError(err)
+	}
+	output.Used = affected == 1
+	return
+}
+
+// EnableTotp enables the pending enrollment and replaces the recovery codes in
+// a single statement.
+func (r *Repository) EnableTotp(ctx context.Context, input EnableTotpInput) (output EnableTotpOutput, err error) {
+	err = r.Db.QueryRowContext(ctx, `WITH enabled AS (
+			UPDATE user_totp SET enabled_at = $1 WHERE user_id = $2 AND enabled_at IS NULL RETURNING user_id
+		), deleted AS (
+			DELETE FROM recovery_codes WHERE user_id IN (SELECT user_id FROM enabled)
+		), inserted AS (
+			INSERT INTO recovery_codes(user_id, code_hash) SELECT enabled.user_id, code_hash FROM enabled, unnest($3::varchar[]) AS code_hash
+		)
+		SELECT count(*) > 0 FROM enabled`, time.Now().UTC(), input.UserId, pq.Array(input.RecoveryCodeHashes)).Scan(&output.Enabled)
+	if err != nil {
+		return output, translateError(err)
+	}
+	return
+}
+
+func (r *Repository) ConsumeRecoveryCode(ctx context.Context, input ConsumeRecoveryCodeInput) (output ConsumeRecoveryCodeOutput, err error) {
+	res, err := r.Db.ExecContext(ctx, `UPDATE recovery_codes SET used_at = $1 WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL`, time.Now().UTC(), input.UserId, input.CodeHash)
+	if err != nil {
+		return output, translateError(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return output, translateError(err)
+	}
+	output.Consumed = affected > 0
+	return
+}
+
+// CreateMfaChallenge also drops the expired challenges of the user.
+func (r *Repository) CreateMfaChallenge(ctx context.Context, input CreateMfaChallengeInput) error {
+	_, err := r.Db.ExecContext(ctx, `WITH deleted AS (DELETE FROM mfa_challenges WHERE user_id = $1 AND expires_at < $4)
+		INSERT INTO mfa_challenges(user_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
+		input.UserId, input.TokenHash, input.ExpiresAt.UTC(), time.Now().UTC())
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (r *Repository) GetMfaChallengeByHash(ctx context.Context, input GetMfaChallengeInput) (output GetMfaChallengeOutput, err error) {
+	err = r.Db.QueryRowContext(ctx, `SELECT c.id, c.user_id, c.expires_at, c.used_at, t.secret
+		FROM mfa_challenges c JOIN users u ON u.id = c.user_id JOIN user_totp t ON t.user_id = c.user_id
+		WHERE c.token_hash = $1 AND u.deleted_at IS NULL AND t.enabled_at IS NOT NULL`, input.TokenHash).Scan(&output.Id, &output.UserId, &output.ExpiresAt, &output.UsedAt, &output.TotpSecret)
+	if err != nil {
+		return output, translateError(err)
+	}
+	return
+}
+
+// IncrementMfaChallengeAttempts counts the attempt before the code is checked,
+// so concurrent guesses can't go past the limit.
+func (r *Repository) IncrementMfaChallengeAttempts(ctx context.Context, input IncrementMfaChallengeAttemptsInput) (output IncrementMfaChallengeAttemptsOutput, err error) {
+	err = r.Db.QueryRowContext(ctx, `UPDATE mfa_challenges SET attempts = attempts + 1 WHERE id = $1 RETURNING attempts`, input.Id).Scan(&output.Attempts)
+	if err != nil {
+		return output, translateError(err)
+	}
+	return
+}
+
+func (r *Repository) ConsumeMfaChallenge(ctx context.Context, input ConsumeMfaChallengeInput) (output ConsumeMfaChallengeOutput, err error) {
+	res, err := r.Db.ExecContext(ctx, `UPDATE mfa_challenges SET used_at = $1 WHERE id = $2 AND used_at IS NULL`, time.Now().UTC(), input.Id)
+	if err != nil {
+		return output, translateError(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return output, translateError(err)
+	}
+	output.Consumed = affected == 1
+	return
+}
+
+// CreateWebauthnSession also drops the expired sessions, abandoned ceremonies
+// would pile up otherwise.
+func (r *Repository) CreateWebauthnSession(ctx context.Context, input CreateWebauthnSessionInput) error {
+	var userId *int
+	if input.UserId != 0 {
+		userId = &input.UserId
+	}
+	_, err := r.Db.ExecContext(ctx, `WITH deleted AS (DELETE FROM webauthn_sessions WHERE expires_at < $5)
+		INSERT INTO webauthn_sessions(challenge, user_id, data, expires_at) VALUES ($1, $2, $3, $4)`,
+		input.Challenge, userId, input.Data, input.ExpiresAt.UTC(), time.Now().UTC())
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// ConsumeWebauthnSession deletes the session and returns it, a challenge is
+// answered once. It returns sql.ErrNoRows when unknown or expired.
+func (r *Repository) ConsumeWebauthnSession(ctx context.Context, input ConsumeWebauthnSessionInput) (output ConsumeWebauthnSessionOutput, err error) {
+	var userId *int
+	err = r.Db.QueryRowContext(ctx, `DELETE FROM webauthn_sessions WHERE challenge = $1 AND expires_at > $2 RETURNING user_id, data`,
+		input.Challenge, time.Now().UTC()).Scan(&userId, &output.Data)
+	if err != nil {
+		return output, translateError(err)
+	}
+	if userId != nil {
+		output.UserId = *userId
+	}
+	return
+}
+
+func (r *Repository) CreateWebauthnCredential(ctx context.Context, input CreateWebauthnCredentialInput) (output CreateWebauthnCredentialOutput, err error) {
+	err = r.Db.QueryRowContext(ctx, `INSERT INTO webauthn_credentials(user_id, credential_id, name, data, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+		input.UserId, input.CredentialId, input.Name, input.Data, time.Now().UTC()).Scan(&output.Id, &output.CreatedAt)
+	if err != nil {
+		return output, translateError(err)
+	}
+	return
+}
+
+func (r *Repository) ListWebauthnCredentials(ctx context.Context, input ListWebauthnCredentialsInput) (output ListWebauthnCredentialsOutput, err error) {
+	rows, err := r.Db.QueryContext(ctx, `SELECT c.id, c.credential_id, c.name, c.data, c.created_at, c.last_used_at
+		FROM webauthn_credentials c JOIN users u ON u.id = c.user_id
+		WHERE c.user_id = $1 AND u.deleted_at IS NULL
+		ORDER BY c.id DESC`, input.UserId)
+	if err != nil {
+		return output, translateError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var credential WebauthnCredential
+		err = rows.Scan(&credential.Id, &credential.CredentialId, &credential.Name, &credential.Data, &credential.CreatedAt, &credential.LastUsedAt)
+		if err != nil {
+			return output, translateError(err)
+		}
+		output.Credentials = append(output.Credentials, credential)
+	}
+	return output, translateError(rows.Err())
+}
+
+// UpdateWebauthnCredential stores the credential after a login, its sign count changes.
+func (r *Repository) UpdateWebauthnCredential(ctx context.Context, input UpdateWebauthnCredentialInput) error {
+	_, err := r.Db.ExecContext(ctx, `UPDATE webauthn_credentials SET data = $1, last_used_at = $2 WHERE id = $3`, input.Data, time.Now().UTC(), input.Id)
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (r *Repository) DeleteWebauthnCredential(ctx context.Context, input DeleteWebauthnCredentialInput) (output DeleteWebauthnCredentialOutput, err error) {
+	res, err := r.Db.ExecContext(ctx, `DELETE FROM webauthn_credentials c USING users u
+		WHERE u.id = c.user_id AND c.id = $1 AND c.user_id = $2 AND u.deleted_at IS NULL`, input.Id, input.UserId)
+	if err != nil {
+		return output, translateError(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return output, translateError(err)
+	}
+	output.Deleted = affected == 1
+	return
+}
+
+func (r *Repository) CreateLoginEvent(ctx context.Context, input CreateLoginEventInput) error {
+	var failureReason *string
+	if input.FailureReason != "" {
+		failureReason = &input.FailureReason
+	}
+	_, err := r.Db.ExecContext(ctx, `INSERT INTO login_events(user_id, success, failure_reason, ip_address, user_agent, created_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+		input.UserId, input.Success, failureReason, input.IpAddress, input.UserAgent, time.Now().UTC())
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// ListLoginEvents pages on the id, unlike an offset it is stable while new events are recorded.
+func (r *Repository) ListLoginEvents(ctx context.Context, input ListLoginEventsInput) (output ListLoginEventsOutput, err error) {
+	rows, err := r.Db.QueryContext(ctx, `SELECT e.id, e.success, e.failure_reason, e.ip_address, e.user_agent, e.created_at
+		FROM login_events e JOIN users u ON u.id = e.user_id
+		WHERE e.user_id = $1 AND u.deleted_at IS NULL AND ($2::bigint = 0 OR e.id < $2::bigint)
+		ORDER BY e.id DESC LIMIT $3`, input.UserId, input.BeforeId, input.Limit)
+	if err != nil {
+		return output, translateError(err)
+	}
+	defer rows.Close()
+
+	output.Events = []LoginEvent{}
+	for rows.Next() {
+		var event LoginEvent
+		err = rows.Scan(&event.Id, &event.Success, &event.FailureReason, &event.IpAddress, &event.UserAgent, &event.CreatedAt)
+		if err != nil {
+			return output, translateError(err)
+		}
+		output.Events = append(output.Events, event)
+	}
+	return output, translateError(rows.Err())
+}
+
+// UpdateUserById only sets the columns of the fields that are not nil, an
+// input without any is not sent to the database.
+func (r *Repository) UpdateUserById(ctx context.Context, input UpdateUserByIdInput) (output UpdateUserByIdOutput, err error) {
+	query := newUpdateQuery("users")
+
+	if input.FullName != nil {
+		query.Set("full_name", *input.FullName)
+	}
+	if input.PhoneNumber != nil {
+		phoneNumber := query.Arg(*input.PhoneNumber)
+		query.SetExpression("phone_verified_at", "CASE WHEN phone_number = "+phoneNumber+"::VARCHAR THEN phone_verified_at END")
+		query.SetExpression("phone_number", phoneNumber)
+	}
+	if input.Email != nil {
+		email := query.Arg(*input.Email)
+		query.SetExpression("email_verified_at", "CASE WHEN lower(email) = lower("+email+"::VARCHAR) THEN email_verified_at END")
+		query.SetExpression("email", email)
+	}
+	if input.DateOfBirth != nil {
+		query.SetExpression("date_of_birth", query.Arg(input.DateOfBirth.Format("2006-01-02"))+"::DATE")
+	}
+	if input.Locale != nil {
+		query.Set("locale", *input.Locale)
+	}
+	if input.Timezone != nil {
+		query.Set("timezone", *input.Timezone)
+	}
+	if input.AvatarUrl != nil {
+		// NULL unless the avatar comes with its thumbnails
+		var thumbnails interface{}
+		if input.AvatarThumbnails != nil {
+			encoded, err := json.Marshal(input.AvatarThumbnails)
+			if err != nil {
+				return output, translateError(err)
+			}
+			thumbnails = string(encoded)
+		}
+		query.Set("avatar_url", *input.AvatarUrl)
+		query.SetExpression("avatar_thumbnails", query.Arg(thumbnails)+"::JSONB")
+	}
+
+	if query.Empty() {
 		return
 	}
+
+	query.Where("id = " + query.Arg(input.Id)).Where("deleted_at IS NULL")
+	sqlQuery, args := query.Build()
+	res, err := r.Db.ExecContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return output, translateError(err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return output, translateError(err)
+	}
+	output.Updated = rows > 0
+	return
+}
+
+// GetUserById returns the current record of the user, deleted users are not found.
+func (r *Repository) GetUserById(ctx context.Context, input GetUserByIdInput) (output GetUserByIdOutput, err error) {
+	var thumbnails []byte
+	err = r.Db.QueryRowContext(ctx, `SELECT id, full_name, phone_number, phone_verified_at, email, email_verified_at,
+		date_of_birth, locale, timezone, avatar_url, avatar_thumbnails, COALESCE(count_login, 0), created_at, updated_at
+		FROM users WHERE id = $1 AND deleted_at IS NULL`, input.Id).Scan(&output.Id, &output.FullName, &output.PhoneNumber, &output.PhoneVerifiedAt,
+		&output.Email, &output.EmailVerifiedAt, &output.DateOfBirth, &output.Locale, &output.Timezone, &output.AvatarUrl, &thumbnails,
+		&output.LoginCount, &output.CreatedAt, &output.UpdatedAt)
+	if err != nil {
+		return output, translateError(err)
+	}
+	if thumbnails != nil {
+		err = json.Unmarshal(thumbnails, &output.AvatarThumbnails)
+	}
+	return
+}
+
+func (r *Repository) CreateRefreshToken(ctx context.Context, input CreateRefreshTokenInput) error {
+	_, err := r.Db.ExecContext(ctx, `INSERT INTO refresh_tokens(user_id, family_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)`, input.UserId, input.FamilyId, input.TokenHash, input.ExpiresAt.UTC())
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (r *Repository) GetRefreshTokenByHash(ctx context.Context, input GetRefreshTokenInput) (output GetRefreshTokenOutput, err error) {
+	err = r.Db.QueryRowContext(ctx, `SELECT rt.id, rt.user_id, rt.family_id, rt.expires_at, rt.revoked_at
+		FROM refresh_tokens rt JOIN users u ON u.id = rt.user_id
+		WHERE rt.token_hash = $1 AND u.deleted_at IS NULL`, input.TokenHash).Scan(&output.Id, &output.UserId, &output.FamilyId, &output.ExpiresAt, &output.RevokedAt)
+	if err != nil {
+		return output, translateError(err)
+	}
+	return
+}
+
+// RevokeRefreshToken marks a single refresh token as used. Revoked is false when
+// the token had already been revoked, which the caller must treat as reuse.
+func (r *Repository) RevokeRefreshToken(ctx context.Context, input RevokeRefreshTokenInput) (output RevokeRefreshTokenOutput, err error) {
+	res, err := r.Db.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`, input.Id)
+	if err != nil {
+		return output, translateError(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return output, translateError(err)
+	}
+	output.Revoked = affected == 1
 	return
+}
+
+func (r *Repository) RevokeRefreshTokenFamily(ctx context.Context, input RevokeRefreshTokenFamilyInput) error {
+	_, err := r.Db.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = now() WHERE family_id = $1 AND revoked_at IS NULL`, input.FamilyId)
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (r *Repository) RevokeUserRefreshTokens(ctx context.Context, input RevokeUserRefreshTokensInput) error {
+	_, err := r.Db.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL AND ($2 = '' OR family_id <> $2)`, input.UserId, input.ExceptFamilyId)
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (r *Repository) RevokeToken(ctx context.Context, input RevokeTokenInput) error {
+	if err := r.pruneRevokedTokens(ctx); err != nil {
+		return translateError(err)
+	}
+	_, err := r.Db.ExecContext(ctx, `INSERT INTO revoked_tokens(jti, user_id, expires_at) VALUES ($1, $2, $3) ON CONFLICT (jti) DO NOTHING`, input.Jti, input.UserId, input.ExpiresAt.UTC())
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (r *Repository) RevokeUserTokens(ctx context.Context, input RevokeUserTokensInput) error {
+	if err := r.pruneRevokedTokens(ctx); err != nil {
+		return translateError(err)
+	}
+	var exceptSessionId *string
+	if input.ExceptSessionId != "" {
+		exceptSessionId = &input.ExceptSessionId
+	}
+	_, err := r.Db.ExecContext(ctx, `INSERT INTO revoked_user_tokens(user_id, revoked_before, expires_at, except_session_id) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET revoked_before = EXCLUDED.revoked_before, expires_at = EXCLUDED.expires_at, except_session_id = EXCLUDED.except_session_id`,
+		input.UserId, input.RevokedBefore.UTC(), input.ExpiresAt.UTC(), exceptSessionId)
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
 
+func (r *Repository) IsTokenRevoked(ctx context.Context, input IsTokenRevokedInput) (output IsTokenRevokedOutput, err error) {
+	err = r.Db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1)
+		OR EXISTS(SELECT 1 FROM revoked_user_tokens WHERE user_id = $2 AND revoked_before > $3
+			AND (except_session_id IS NULL OR except_session_id <> $4))`, input.Jti, input.UserId, input.IssuedAt.UTC(), input.SessionId).Scan(&output.Revoked)
+	if err != nil {
+		return output, translateError(err)
+	}
+	return
+}
+
+// revoked entries are useless once the tokens they cover have expired,
+// so they are pruned every time a new entry is written.
+func (r *Repository) pruneRevokedTokens(ctx context.Context) error {
+	now := time.Now().UTC()
+	if _, err := r.Db.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at < $1`, now); err != nil {
+		return translateError(err)
+	}
+	if _, err := r.Db.ExecContext(ctx, `DELETE FROM revoked_user_tokens WHERE expires_at < $1`, now); err != nil {
+		return translateError(err)
+	}
+	return nil
 }
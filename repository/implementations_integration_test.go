@@ -0,0 +1,837 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntegration_GetTestById(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	_, err := repo.Db.Exec(`CREATE TABLE test (id VARCHAR PRIMARY KEY, name VARCHAR NOT NULL); INSERT INTO test VALUES ('1', 'test')`)
+	assert.NoError(t, err)
+
+	res, err := repo.GetTestById(ctx, GetTestByIdInput{Id: "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "test", res.Name)
+
+	_, err = repo.GetTestById(ctx, GetTestByIdInput{Id: "2"})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+// Registration
+func TestIntegration_CreateNewUser(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+
+	res, err := repo.CreateNewUser(ctx, GetRegistrationInput{FullName: "Arthur Dent", PhoneNumber: "+628111111111", Password: "hash"})
+	assert.NoError(t, err)
+
+	user, err := repo.GetUserById(ctx, GetUserByIdInput{Id: res.Id})
+	assert.NoError(t, err)
+	assert.Equal(t, res.Id, user.Id)
+	assert.Equal(t, "Arthur Dent", user.FullName)
+	assert.Equal(t, "+628111111111", user.PhoneNumber)
+	assert.Nil(t, user.PhoneVerifiedAt)
+	assert.Equal(t, int64(0), user.LoginCount)
+	assert.Nil(t, user.AvatarThumbnails)
+
+	_, err = repo.CreateNewUser(ctx, GetRegistrationInput{FullName: "Ford Prefect", PhoneNumber: "+628111111111", Password: "other-hash"})
+	assertViolation(t, err, "users_phone_number_key")
+
+	_, err = repo.CreateNewUser(ctx, GetRegistrationInput{FullName: "Ford Prefect", PhoneNumber: "+628222222222", Password: "hash"})
+	assertViolation(t, err, "users_hash_password_key")
+}
+
+// Login
+func TestIntegration_GetUserByPhoneNumber(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+
+	res, err := repo.GetUserByPhoneNumber(ctx, GetLoginInput{PhoneNumber: "+628111111111"})
+	assert.NoError(t, err)
+	assert.Equal(t, userId, res.Id)
+	assert.Equal(t, "Arthur Dent", res.FullName)
+	assert.Equal(t, 0, res.FailedLoginCount)
+	assert.Nil(t, res.LockedUntil)
+	assert.False(t, res.TotpEnabled)
+
+	// a pending enrollment is not enough
+	_, err = repo.CreateTotpSecret(ctx, CreateTotpSecretInput{UserId: userId, Secret: "secret"})
+	assert.NoError(t, err)
+	res, err = repo.GetUserByPhoneNumber(ctx, GetLoginInput{PhoneNumber: "+628111111111"})
+	assert.NoError(t, err)
+	assert.False(t, res.TotpEnabled)
+
+	_, err = repo.EnableTotp(ctx, EnableTotpInput{UserId: userId})
+	assert.NoError(t, err)
+	res, err = repo.GetUserByPhoneNumber(ctx, GetLoginInput{PhoneNumber: "+628111111111"})
+	assert.NoError(t, err)
+	assert.True(t, res.TotpEnabled)
+
+	_, err = repo.GetUserByPhoneNumber(ctx, GetLoginInput{PhoneNumber: "+628222222222"})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = repo.SoftDeleteUser(ctx, SoftDeleteUserInput{Id: userId})
+	assert.NoError(t, err)
+	_, err = repo.GetUserByPhoneNumber(ctx, GetLoginInput{PhoneNumber: "+628111111111"})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestIntegration_FailedLogins(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+
+	for expected := 1; expected <= 2; expected++ {
+		res, err := repo.RecordFailedLogin(ctx, RecordFailedLoginInput{Id: userId})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, res.FailedLoginCount)
+	}
+
+	lockedUntil := time.Now().Add(15 * time.Minute)
+	assert.NoError(t, repo.LockUser(ctx, LockUserInput{Id: userId, LockedUntil: lockedUntil}))
+	res, err := repo.GetUserByPhoneNumber(ctx, GetLoginInput{PhoneNumber: "+628111111111"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res.FailedLoginCount)
+	if assert.NotNil(t, res.LockedUntil) {
+		assert.WithinDuration(t, lockedUntil, *res.LockedUntil, time.Millisecond)
+	}
+
+	// a successful login is counted and clears the streak and the lock
+	assert.NoError(t, repo.UpdateUserSuccesLogin(ctx, PostUpdateUserSuccesLoginInput{Id: userId}))
+	res, err = repo.GetUserByPhoneNumber(ctx, GetLoginInput{PhoneNumber: "+628111111111"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, res.FailedLoginCount)
+	assert.Nil(t, res.LockedUntil)
+	user, err := repo.GetUserById(ctx, GetUserByIdInput{Id: userId})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), user.LoginCount)
+
+	_, err = repo.RecordFailedLogin(ctx, RecordFailedLoginInput{Id: userId + 1})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+// Account Lifecycle
+func TestIntegration_UserPassword(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+	credentials, err := repo.GetUserCredentialsById(ctx, GetUserCredentialsByIdInput{Id: userId})
+	assert.NoError(t, err)
+	assert.Equal(t, "+628111111111", credentials.PhoneNumber)
+
+	_, err = repo.RecordFailedLogin(ctx, RecordFailedLoginInput{Id: userId})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.LockUser(ctx, LockUserInput{Id: userId, LockedUntil: time.Now().Add(time.Hour)}))
+	credentials, err = repo.GetUserCredentialsById(ctx, GetUserCredentialsByIdInput{Id: userId})
+	assert.NoError(t, err)
+	assert.NotNil(t, credentials.LockedUntil)
+
+	// a new password unlocks the account
+	assert.NoError(t, repo.UpdateUserPassword(ctx, UpdateUserPasswordInput{Id: userId, Password: "new-hash"}))
+	res, err := repo.GetUserByPhoneNumber(ctx, GetLoginInput{PhoneNumber: "+628111111111"})
+	assert.NoError(t, err)
+	assert.Equal(t, "new-hash", res.Password)
+	assert.Equal(t, 0, res.FailedLoginCount)
+	assert.Nil(t, res.LockedUntil)
+
+	// the rehash of an outdated hash is dropped
+	assert.NoError(t, repo.RehashUserPassword(ctx, RehashUserPasswordInput{Id: userId, CurrentHash: credentials.Password, NewHash: "rehashed"}))
+	credentials, err = repo.GetUserCredentialsById(ctx, GetUserCredentialsByIdInput{Id: userId})
+	assert.NoError(t, err)
+	assert.Equal(t, "new-hash", credentials.Password)
+
+	assert.NoError(t, repo.RehashUserPassword(ctx, RehashUserPasswordInput{Id: userId, CurrentHash: "new-hash", NewHash: "rehashed"}))
+	credentials, err = repo.GetUserCredentialsById(ctx, GetUserCredentialsByIdInput{Id: userId})
+	assert.NoError(t, err)
+	assert.Equal(t, "rehashed", credentials.Password)
+
+	otherId := createIntegrationUser(t, repo, "+628222222222")
+	err = repo.UpdateUserPassword(ctx, UpdateUserPasswordInput{Id: otherId, Password: "rehashed"})
+	assertViolation(t, err, "users_hash_password_key")
+}
+
+func TestIntegration_SoftDeleteUser(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+
+	res, err := repo.SoftDeleteUser(ctx, SoftDeleteUserInput{Id: userId})
+	assert.NoError(t, err)
+	assert.True(t, res.Deleted)
+
+	res, err = repo.SoftDeleteUser(ctx, SoftDeleteUserInput{Id: userId})
+	assert.NoError(t, err)
+	assert.False(t, res.Deleted)
+
+	_, err = repo.GetUserById(ctx, GetUserByIdInput{Id: userId})
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = repo.GetUserCredentialsById(ctx, GetUserCredentialsByIdInput{Id: userId})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestIntegration_SoftDeleteUser_Excluded(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+	credentials, err := repo.GetUserCredentialsById(ctx, GetUserCredentialsByIdInput{Id: userId})
+	assert.NoError(t, err)
+	_, err = repo.CreateTotpSecret(ctx, CreateTotpSecretInput{UserId: userId, Secret: "secret"})
+	assert.NoError(t, err)
+	credential, err := repo.CreateWebauthnCredential(ctx, CreateWebauthnCredentialInput{UserId: userId, CredentialId: []byte{1}, Name: "laptop", Data: "data"})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.CreateLoginEvent(ctx, CreateLoginEventInput{UserId: userId, Success: true, IpAddress: "127.0.0.1", UserAgent: "test"}))
+
+	_, err = repo.SoftDeleteUser(ctx, SoftDeleteUserInput{Id: userId})
+	assert.NoError(t, err)
+
+	// the rows of a deleted user are neither read nor written
+	assert.NoError(t, repo.RehashUserPassword(ctx, RehashUserPasswordInput{Id: userId, CurrentHash: credentials.Password, NewHash: "rehashed"}))
+	_, err = repo.GetTotpByUserId(ctx, GetTotpInput{UserId: userId})
+	assert.ErrorIs(t, err, ErrNotFound)
+	used, err := repo.UseTotpStep(ctx, UseTotpStepInput{UserId: userId, Step: 10})
+	assert.NoError(t, err)
+	assert.False(t, used.Used)
+	deleted, err := repo.DeleteWebauthnCredential(ctx, DeleteWebauthnCredentialInput{Id: credential.Id, UserId: userId})
+	assert.NoError(t, err)
+	assert.False(t, deleted.Deleted)
+	events, err := repo.ListLoginEvents(ctx, ListLoginEventsInput{UserId: userId, Limit: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, events.Events)
+
+	// they are kept as they were until the user is restored
+	_, err = repo.RestoreUser(ctx, RestoreUserInput{Id: userId})
+	assert.NoError(t, err)
+	restored, err := repo.GetUserCredentialsById(ctx, GetUserCredentialsByIdInput{Id: userId})
+	assert.NoError(t, err)
+	assert.Equal(t, credentials.Password, restored.Password)
+	list, err := repo.ListWebauthnCredentials(ctx, ListWebauthnCredentialsInput{UserId: userId})
+	assert.NoError(t, err)
+	assert.Len(t, list.Credentials, 1)
+	used, err = repo.UseTotpStep(ctx, UseTotpStepInput{UserId: userId, Step: 10})
+	assert.NoError(t, err)
+	assert.True(t, used.Used)
+}
+
+func TestIntegration_RestoreUser(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+
+	res, err := repo.RestoreUser(ctx, RestoreUserInput{Id: userId})
+	assert.NoError(t, err)
+	assert.False(t, res.Restored)
+
+	_, err = repo.SoftDeleteUser(ctx, SoftDeleteUserInput{Id: userId})
+	assert.NoError(t, err)
+
+	// the phone number of a deleted user can be registered again
+	otherId := createIntegrationUser(t, repo, "+628111111111")
+	_, err = repo.RestoreUser(ctx, RestoreUserInput{Id: userId})
+	assertViolation(t, err, "users_phone_number_key")
+
+	_, err = repo.SoftDeleteUser(ctx, SoftDeleteUserInput{Id: otherId})
+	assert.NoError(t, err)
+	res, err = repo.RestoreUser(ctx, RestoreUserInput{Id: userId})
+	assert.NoError(t, err)
+	assert.True(t, res.Restored)
+
+	user, err := repo.GetUserById(ctx, GetUserByIdInput{Id: userId})
+	assert.NoError(t, err)
+	assert.Equal(t, userId, user.Id)
+}
+
+func TestIntegration_SoftDeleteUser_DropsAvatar(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+	avatarUrl := "http://localhost:1323/blobs/avatars/1/original"
+	_, err := repo.UpdateUserById(ctx, UpdateUserByIdInput{Id: userId, AvatarUrl: &avatarUrl, AvatarThumbnails: map[string]string{"64": avatarUrl}})
+	assert.NoError(t, err)
+
+	_, err = repo.SoftDeleteUser(ctx, SoftDeleteUserInput{Id: userId})
+	assert.NoError(t, err)
+	_, err = repo.RestoreUser(ctx, RestoreUserInput{Id: userId})
+	assert.NoError(t, err)
+
+	// a restored account has no avatar, its files were deleted
+	user, err := repo.GetUserById(ctx, GetUserByIdInput{Id: userId})
+	assert.NoError(t, err)
+	assert.Nil(t, user.AvatarUrl)
+	assert.Nil(t, user.AvatarThumbnails)
+}
+
+func TestIntegration_PurgeDeletedUsers(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+	keptId := createIntegrationUser(t, repo, "+628222222222")
+	assert.NoError(t, repo.CreateRefreshToken(ctx, CreateRefreshTokenInput{UserId: userId, FamilyId: "family", TokenHash: "hash", ExpiresAt: time.Now().Add(time.Hour)}))
+	assert.NoError(t, repo.CreateLoginEvent(ctx, CreateLoginEventInput{UserId: userId, Success: true, IpAddress: "127.0.0.1", UserAgent: "test"}))
+
+	_, err := repo.SoftDeleteUser(ctx, SoftDeleteUserInput{Id: userId})
+	assert.NoError(t, err)
+
+	res, err := repo.PurgeDeletedUsers(ctx, PurgeDeletedUsersInput{DeletedBefore: time.Now().Add(-time.Hour)})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), res.Purged)
+
+	res, err = repo.PurgeDeletedUsers(ctx, PurgeDeletedUsersInput{DeletedBefore: time.Now().Add(time.Minute)})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), res.Purged)
+	assert.Equal(t, []int{userId}, res.Ids)
+
+	// the rows of the user go along with it
+	var count int
+	assert.NoError(t, repo.Db.QueryRow(`SELECT (SELECT count(*) FROM users) + (SELECT count(*) FROM refresh_tokens) + (SELECT count(*) FROM login_events)`).Scan(&count))
+	assert.Equal(t, 1, count)
+	_, err = repo.GetUserById(ctx, GetUserByIdInput{Id: keptId})
+	assert.NoError(t, err)
+}
+
+// Password Reset
+func TestIntegration_PasswordReset(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+	expiresAt := time.Now().Add(10 * time.Minute)
+
+	// only the latest reset is kept
+	assert.NoError(t, repo.CreatePasswordReset(ctx, CreatePasswordResetInput{UserId: userId, OtpHash: "first", OtpSalt: "salt", ExpiresAt: expiresAt}))
+	assert.NoError(t, repo.CreatePasswordReset(ctx, CreatePasswordResetInput{UserId: userId, OtpHash: "second", OtpSalt: "salt", ExpiresAt: expiresAt}))
+	var count int
+	assert.NoError(t, repo.Db.QueryRow(`SELECT count(*) FROM password_resets`).Scan(&count))
+	assert.Equal(t, 1, count)
+
+	reset, err := repo.GetPasswordResetByPhoneNumber(ctx, GetPasswordResetInput{PhoneNumber: "+628111111111"})
+	assert.NoError(t, err)
+	assert.Equal(t, userId, reset.UserId)
+	assert.Equal(t, "Arthur Dent", reset.FullName)
+	assert.Equal(t, "second", reset.OtpHash)
+	assert.Equal(t, 0, reset.Attempts)
+	assert.WithinDuration(t, expiresAt, reset.ExpiresAt, time.Millisecond)
+	assert.Nil(t, reset.UsedAt)
+
+	for expected := 1; expected <= 2; expected++ {
+		attempts, err := repo.IncrementPasswordResetAttempts(ctx, IncrementPasswordResetAttemptsInput{Id: reset.Id})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, attempts.Attempts)
+	}
+
+	consumed, err := repo.ConsumePasswordReset(ctx, ConsumePasswordResetInput{Id: reset.Id})
+	assert.NoError(t, err)
+	assert.True(t, consumed.Consumed)
+	consumed, err = repo.ConsumePasswordReset(ctx, ConsumePasswordResetInput{Id: reset.Id})
+	assert.NoError(t, err)
+	assert.False(t, consumed.Consumed)
+
+	reset, err = repo.GetPasswordResetByPhoneNumber(ctx, GetPasswordResetInput{PhoneNumber: "+628111111111"})
+	assert.NoError(t, err)
+	assert.NotNil(t, reset.UsedAt)
+
+	_, err = repo.GetPasswordResetByPhoneNumber(ctx, GetPasswordResetInput{PhoneNumber: "+628222222222"})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	err = repo.CreatePasswordReset(ctx, CreatePasswordResetInput{UserId: userId + 1, OtpHash: "hash", OtpSalt: "salt", ExpiresAt: expiresAt})
+	assertViolation(t, err, "password_resets_user_id_fkey")
+}
+
+// Phone Verification
+func TestIntegration_PhoneVerification(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+	expiresAt := time.Now().Add(10 * time.Minute)
+
+	assert.NoError(t, repo.CreatePhoneVerification(ctx, CreatePhoneVerificationInput{UserId: userId, PhoneNumber: "+628111111111", OtpHash: "first", OtpSalt: "salt", ExpiresAt: expiresAt}))
+	assert.NoError(t, repo.CreatePhoneVerification(ctx, CreatePhoneVerificationInput{UserId: userId, PhoneNumber: "+628333333333", OtpHash: "second", OtpSalt: "salt", ExpiresAt: expiresAt}))
+
+	verification, err := repo.GetPhoneVerificationByUserId(ctx, GetPhoneVerificationInput{UserId: userId})
+	assert.NoError(t, err)
+	assert.Equal(t, "+628333333333", verification.PhoneNumber)
+	assert.Equal(t, "second", verification.OtpHash)
+	assert.WithinDuration(t, expiresAt, verification.ExpiresAt, time.Millisecond)
+
+	attempts, err := repo.IncrementPhoneVerificationAttempts(ctx, IncrementPhoneVerificationAttemptsInput{Id: verification.Id})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempts.Attempts)
+
+	consumed, err := repo.ConsumePhoneVerification(ctx, ConsumePhoneVerificationInput{Id: verification.Id})
+	assert.NoError(t, err)
+	assert.True(t, consumed.Consumed)
+	consumed, err = repo.ConsumePhoneVerification(ctx, ConsumePhoneVerificationInput{Id: verification.Id})
+	assert.NoError(t, err)
+	assert.False(t, consumed.Consumed)
+
+	_, err = repo.GetPhoneVerificationByUserId(ctx, GetPhoneVerificationInput{UserId: userId + 1})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestIntegration_VerifyUserPhoneNumber(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+	createIntegrationUser(t, repo, "+628222222222")
+
+	assert.NoError(t, repo.VerifyUserPhoneNumber(ctx, VerifyUserPhoneNumberInput{Id: userId, PhoneNumber: "+628333333333"}))
+	user, err := repo.GetUserById(ctx, GetUserByIdInput{Id: userId})
+	assert.NoError(t, err)
+	assert.Equal(t, "+628333333333", user.PhoneNumber)
+	assert.NotNil(t, user.PhoneVerifiedAt)
+
+	err = repo.VerifyUserPhoneNumber(ctx, VerifyUserPhoneNumberInput{Id: userId, PhoneNumber: "+628222222222"})
+	assertViolation(t, err, "users_phone_number_key")
+}
+
+// Two-Factor Authentication
+func TestIntegration_Totp(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+
+	_, err := repo.GetTotpByUserId(ctx, GetTotpInput{UserId: userId})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// a pending enrollment is replaced
+	for _, secret := range []string{"first", "second"} {
+		res, err := repo.CreateTotpSecret(ctx, CreateTotpSecretInput{UserId: userId, Secret: secret})
+		assert.NoError(t, err)
+		assert.True(t, res.Created)
+	}
+	totp, err := repo.GetTotpByUserId(ctx, GetTotpInput{UserId: userId})
+	assert.NoError(t, err)
+	assert.Equal(t, "second", totp.Secret)
+	assert.Nil(t, totp.EnabledAt)
+
+	// a step is used once, an older one never
+	used, err := repo.UseTotpStep(ctx, UseTotpStepInput{UserId: userId, Step: 10})
+	assert.NoError(t, err)
+	assert.True(t, used.Used)
+	for _, step := range []int64{10, 9} {
+		res, err := repo.UseTotpStep(ctx, UseTotpStepInput{UserId: userId, Step: step})
+		assert.NoError(t, err)
+		assert.False(t, res.Used)
+	}
+	used, err = repo.UseTotpStep(ctx, UseTotpStepInput{UserId: userId, Step: 11})
+	assert.NoError(t, err)
+	assert.True(t, used.Used)
+
+	enabled, err := repo.EnableTotp(ctx, EnableTotpInput{UserId: userId, RecoveryCodeHashes: []string{"code-1", "code-2"}})
+	assert.NoError(t, err)
+	assert.True(t, enabled.Enabled)
+	enabled, err = repo.EnableTotp(ctx, EnableTotpInput{UserId: userId, RecoveryCodeHashes: []string{"code-3"}})
+	assert.NoError(t, err)
+	assert.False(t, enabled.Enabled)
+
+	// an enabled TOTP is kept as is
+	created, err := repo.CreateTotpSecret(ctx, CreateTotpSecretInput{UserId: userId, Secret: "third"})
+	assert.NoError(t, err)
+	assert.False(t, created.Created)
+	totp, err = repo.GetTotpByUserId(ctx, GetTotpInput{UserId: userId})
+	assert.NoError(t, err)
+	assert.Equal(t, "second", totp.Secret)
+	assert.NotNil(t, totp.EnabledAt)
+
+	for code, expected := range map[string]bool{"code-1": true, "code-3": false} {
+		res, err := repo.ConsumeRecoveryCode(ctx, ConsumeRecoveryCodeInput{UserId: userId, CodeHash: code})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, res.Consumed, code)
+	}
+	consumed, err := repo.ConsumeRecoveryCode(ctx, ConsumeRecoveryCodeInput{UserId: userId, CodeHash: "code-1"})
+	assert.NoError(t, err)
+	assert.False(t, consumed.Consumed)
+
+	_, err = repo.CreateTotpSecret(ctx, CreateTotpSecretInput{UserId: userId + 1, Secret: "secret"})
+	assertViolation(t, err, "user_totp_user_id_fkey")
+}
+
+func TestIntegration_MfaChallenge(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+	_, err := repo.CreateTotpSecret(ctx, CreateTotpSecretInput{UserId: userId, Secret: "secret"})
+	assert.NoError(t, err)
+
+	// the challenges are only found once the TOTP is enabled
+	expiresAt := time.Now().Add(5 * time.Minute)
+	assert.NoError(t, repo.CreateMfaChallenge(ctx, CreateMfaChallengeInput{UserId: userId, TokenHash: "expired", ExpiresAt: time.Now().Add(-time.Minute)}))
+	assert.NoError(t, repo.CreateMfaChallenge(ctx, CreateMfaChallengeInput{UserId: userId, TokenHash: "hash", ExpiresAt: expiresAt}))
+	_, err = repo.GetMfaChallengeByHash(ctx, GetMfaChallengeInput{TokenHash: "hash"})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = repo.EnableTotp(ctx, EnableTotpInput{UserId: userId})
+	assert.NoError(t, err)
+	challenge, err := repo.GetMfaChallengeByHash(ctx, GetMfaChallengeInput{TokenHash: "hash"})
+	assert.NoError(t, err)
+	assert.Equal(t, userId, challenge.UserId)
+	assert.Equal(t, "secret", challenge.TotpSecret)
+	assert.WithinDuration(t, expiresAt, challenge.ExpiresAt, time.Millisecond)
+	assert.Nil(t, challenge.UsedAt)
+
+	// the expired challenge was dropped by the next one
+	_, err = repo.GetMfaChallengeByHash(ctx, GetMfaChallengeInput{TokenHash: "expired"})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	attempts, err := repo.IncrementMfaChallengeAttempts(ctx, IncrementMfaChallengeAttemptsInput{Id: challenge.Id})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempts.Attempts)
+
+	consumed, err := repo.ConsumeMfaChallenge(ctx, ConsumeMfaChallengeInput{Id: challenge.Id})
+	assert.NoError(t, err)
+	assert.True(t, consumed.Consumed)
+	consumed, err = repo.ConsumeMfaChallenge(ctx, ConsumeMfaChallengeInput{Id: challenge.Id})
+	assert.NoError(t, err)
+	assert.False(t, consumed.Consumed)
+
+	err = repo.CreateMfaChallenge(ctx, CreateMfaChallengeInput{UserId: userId, TokenHash: "hash", ExpiresAt: expiresAt})
+	assertViolation(t, err, "mfa_challenges_token_hash_key")
+}
+
+// Passkeys
+func TestIntegration_WebauthnSession(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+	expiresAt := time.Now().Add(5 * time.Minute)
+
+	assert.NoError(t, repo.CreateWebauthnSession(ctx, CreateWebauthnSessionInput{Challenge: "registration", UserId: userId, Data: "data", ExpiresAt: expiresAt}))
+	assert.NoError(t, repo.CreateWebauthnSession(ctx, CreateWebauthnSessionInput{Challenge: "login", Data: "login-data", ExpiresAt: expiresAt}))
+	assert.NoError(t, repo.CreateWebauthnSession(ctx, CreateWebauthnSessionInput{Challenge: "expired", Data: "data", ExpiresAt: time.Now().Add(-time.Minute)}))
+
+	session, err := repo.ConsumeWebauthnSession(ctx, ConsumeWebauthnSessionInput{Challenge: "registration"})
+	assert.NoError(t, err)
+	assert.Equal(t, userId, session.UserId)
+	assert.Equal(t, "data", session.Data)
+
+	// a challenge is answered once
+	_, err = repo.ConsumeWebauthnSession(ctx, ConsumeWebauthnSessionInput{Challenge: "registration"})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	session, err = repo.ConsumeWebauthnSession(ctx, ConsumeWebauthnSessionInput{Challenge: "login"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, session.UserId)
+	assert.Equal(t, "login-data", session.Data)
+
+	_, err = repo.ConsumeWebauthnSession(ctx, ConsumeWebauthnSessionInput{Challenge: "expired"})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	err = repo.CreateWebauthnSession(ctx, CreateWebauthnSessionInput{Challenge: "unknown-user", UserId: userId + 1, Data: "data", ExpiresAt: expiresAt})
+	assertViolation(t, err, "webauthn_sessions_user_id_fkey")
+}
+
+func TestIntegration_WebauthnCredential(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+	otherId := createIntegrationUser(t, repo, "+628222222222")
+
+	first, err := repo.CreateWebauthnCredential(ctx, CreateWebauthnCredentialInput{UserId: userId, CredentialId: []byte{1}, Name: "laptop", Data: "first"})
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), first.CreatedAt, time.Minute)
+	second, err := repo.CreateWebauthnCredential(ctx, CreateWebauthnCredentialInput{UserId: userId, CredentialId: []byte{2}, Name: "phone", Data: "second"})
+	assert.NoError(t, err)
+
+	_, err = repo.CreateWebauthnCredential(ctx, CreateWebauthnCredentialInput{UserId: otherId, CredentialId: []byte{1}, Name: "laptop", Data: "data"})
+	assertViolation(t, err, "webauthn_credentials_credential_id_key")
+
+	assert.NoError(t, repo.UpdateWebauthnCredential(ctx, UpdateWebauthnCredentialInput{Id: first.Id, Data: "used"}))
+
+	list, err := repo.ListWebauthnCredentials(ctx, ListWebauthnCredentialsInput{UserId: userId})
+	assert.NoError(t, err)
+	if assert.Len(t, list.Credentials, 2) {
+		// the latest first
+		assert.Equal(t, second.Id, list.Credentials[0].Id)
+		assert.Equal(t, []byte{2}, list.Credentials[0].CredentialId)
+		assert.Nil(t, list.Credentials[0].LastUsedAt)
+		assert.Equal(t, first.Id, list.Credentials[1].Id)
+		assert.Equal(t, "used", list.Credentials[1].Data)
+		assert.NotNil(t, list.Credentials[1].LastUsedAt)
+	}
+
+	// a passkey is only deleted by its owner
+	deleted, err := repo.DeleteWebauthnCredential(ctx, DeleteWebauthnCredentialInput{Id: first.Id, UserId: otherId})
+	assert.NoError(t, err)
+	assert.False(t, deleted.Deleted)
+	deleted, err = repo.DeleteWebauthnCredential(ctx, DeleteWebauthnCredentialInput{Id: first.Id, UserId: userId})
+	assert.NoError(t, err)
+	assert.True(t, deleted.Deleted)
+
+	list, err = repo.ListWebauthnCredentials(ctx, ListWebauthnCredentialsInput{UserId: userId})
+	assert.NoError(t, err)
+	assert.Len(t, list.Credentials, 1)
+}
+
+// Login History
+func TestIntegration_LoginEvents(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+	otherId := createIntegrationUser(t, repo, "+628222222222")
+
+	assert.NoError(t, repo.CreateLoginEvent(ctx, CreateLoginEventInput{UserId: userId, Success: false, FailureReason: "invalid_password", IpAddress: "127.0.0.1", UserAgent: "test"}))
+	assert.NoError(t, repo.CreateLoginEvent(ctx, CreateLoginEventInput{UserId: userId, Success: true, IpAddress: "127.0.0.1", UserAgent: "test"}))
+	assert.NoError(t, repo.CreateLoginEvent(ctx, CreateLoginEventInput{UserId: userId, Success: true, IpAddress: "::1", UserAgent: "other"}))
+
+	page, err := repo.ListLoginEvents(ctx, ListLoginEventsInput{UserId: userId, Limit: 2})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Events, 2) {
+		assert.Equal(t, "::1", page.Events[0].IpAddress)
+		assert.Nil(t, page.Events[1].FailureReason)
+	}
+
+	page, err = repo.ListLoginEvents(ctx, ListLoginEventsInput{UserId: userId, BeforeId: page.Events[len(page.Events)-1].Id, Limit: 2})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Events, 1) {
+		assert.False(t, page.Events[0].Success)
+		if assert.NotNil(t, page.Events[0].FailureReason) {
+			assert.Equal(t, "invalid_password", *page.Events[0].FailureReason)
+		}
+	}
+
+	page, err = repo.ListLoginEvents(ctx, ListLoginEventsInput{UserId: otherId, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []LoginEvent{}, page.Events)
+
+	err = repo.CreateLoginEvent(ctx, CreateLoginEventInput{UserId: otherId + 1, Success: true, IpAddress: "127.0.0.1", UserAgent: "test"})
+	assertViolation(t, err, "login_events_user_id_fkey")
+}
+
+// UpdateUser/Profile
+func TestIntegration_UpdateUserById(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+
+	// quotes are stored as they are, they can't break out of the query
+	name := "Robert'); DROP TABLE users;--"
+	email := "arthur@example.com"
+	locale := "id-ID"
+	dateOfBirth := time.Date(1978, 3, 8, 0, 0, 0, 0, time.UTC)
+	res, err := repo.UpdateUserById(ctx, UpdateUserByIdInput{Id: userId, FullName: &name, Email: &email, Locale: &locale, DateOfBirth: &dateOfBirth})
+	assert.NoError(t, err)
+	assert.True(t, res.Updated)
+
+	stored, err := repo.GetUserById(ctx, GetUserByIdInput{Id: userId})
+	assert.NoError(t, err)
+	assert.Equal(t, name, stored.FullName)
+	assert.Equal(t, &email, stored.Email)
+	assert.Equal(t, &locale, stored.Locale)
+	if assert.NotNil(t, stored.DateOfBirth) {
+		assert.Equal(t, dateOfBirth, stored.DateOfBirth.UTC())
+	}
+	assert.Nil(t, stored.Timezone)
+
+	// the avatar comes with its thumbnails, a plain URL drops them
+	avatarUrl := "https://example.com/avatar.png"
+	res, err = repo.UpdateUserById(ctx, UpdateUserByIdInput{Id: userId, AvatarUrl: &avatarUrl, AvatarThumbnails: map[string]string{"64": avatarUrl + "?size=64"}})
+	assert.NoError(t, err)
+	assert.True(t, res.Updated)
+	stored, err = repo.GetUserById(ctx, GetUserByIdInput{Id: userId})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"64": avatarUrl + "?size=64"}, stored.AvatarThumbnails)
+
+	res, err = repo.UpdateUserById(ctx, UpdateUserByIdInput{Id: userId, AvatarUrl: &avatarUrl})
+	assert.NoError(t, err)
+	assert.True(t, res.Updated)
+	stored, err = repo.GetUserById(ctx, GetUserByIdInput{Id: userId})
+	assert.NoError(t, err)
+	assert.Nil(t, stored.AvatarThumbnails)
+	assert.Equal(t, name, stored.FullName)
+}
+
+func TestIntegration_UpdateUserById_ResetsVerification(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+	assert.NoError(t, repo.VerifyUserPhoneNumber(ctx, VerifyUserPhoneNumberInput{Id: userId, PhoneNumber: "+628111111111"}))
+
+	// the same phone number keeps its verification
+	phoneNumber := "+628111111111"
+	res, err := repo.UpdateUserById(ctx, UpdateUserByIdInput{Id: userId, PhoneNumber: &phoneNumber})
+	assert.NoError(t, err)
+	assert.True(t, res.Updated)
+	stored, err := repo.GetUserById(ctx, GetUserByIdInput{Id: userId})
+	assert.NoError(t, err)
+	assert.NotNil(t, stored.PhoneVerifiedAt)
+
+	phoneNumber = "+628333333333"
+	res, err = repo.UpdateUserById(ctx, UpdateUserByIdInput{Id: userId, PhoneNumber: &phoneNumber})
+	assert.NoError(t, err)
+	assert.True(t, res.Updated)
+	stored, err = repo.GetUserById(ctx, GetUserByIdInput{Id: userId})
+	assert.NoError(t, err)
+	assert.Equal(t, phoneNumber, stored.PhoneNumber)
+	assert.Nil(t, stored.PhoneVerifiedAt)
+}
+
+func TestIntegration_UpdateUserById_Error_Duplicate(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+	otherId := createIntegrationUser(t, repo, "+628222222222")
+	email := "arthur@example.com"
+	_, err := repo.UpdateUserById(ctx, UpdateUserByIdInput{Id: userId, Email: &email})
+	assert.NoError(t, err)
+
+	// emails are compared case insensitively
+	otherEmail := "Arthur@Example.com"
+	_, err = repo.UpdateUserById(ctx, UpdateUserByIdInput{Id: otherId, Email: &otherEmail})
+	assertViolation(t, err, "users_email_key")
+
+	phoneNumber := "+628111111111"
+	_, err = repo.UpdateUserById(ctx, UpdateUserByIdInput{Id: otherId, PhoneNumber: &phoneNumber})
+	assertViolation(t, err, "users_phone_number_key")
+}
+
+func TestIntegration_UpdateUserById_NotUpdated(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+	name := "Ford Prefect"
+
+	// an empty patch does nothing
+	res, err := repo.UpdateUserById(ctx, UpdateUserByIdInput{Id: userId})
+	assert.NoError(t, err)
+	assert.False(t, res.Updated)
+
+	res, err = repo.UpdateUserById(ctx, UpdateUserByIdInput{Id: userId + 1, FullName: &name})
+	assert.NoError(t, err)
+	assert.False(t, res.Updated)
+
+	// deleted users are left alone
+	_, err = repo.SoftDeleteUser(ctx, SoftDeleteUserInput{Id: userId})
+	assert.NoError(t, err)
+	res, err = repo.UpdateUserById(ctx, UpdateUserByIdInput{Id: userId, FullName: &name})
+	assert.NoError(t, err)
+	assert.False(t, res.Updated)
+}
+
+// Refresh Token
+func TestIntegration_RefreshToken(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+	expiresAt := time.Now().Add(time.Hour)
+
+	for hash, family := range map[string]string{"first": "family", "second": "family", "other": "other-family"} {
+		assert.NoError(t, repo.CreateRefreshToken(ctx, CreateRefreshTokenInput{UserId: userId, FamilyId: family, TokenHash: hash, ExpiresAt: expiresAt}))
+	}
+
+	token, err := repo.GetRefreshTokenByHash(ctx, GetRefreshTokenInput{TokenHash: "first"})
+	assert.NoError(t, err)
+	assert.Equal(t, userId, token.UserId)
+	assert.Equal(t, "family", token.FamilyId)
+	assert.WithinDuration(t, expiresAt, token.ExpiresAt, time.Millisecond)
+	assert.Nil(t, token.RevokedAt)
+
+	// a second revocation is a reuse
+	revoked, err := repo.RevokeRefreshToken(ctx, RevokeRefreshTokenInput{Id: token.Id})
+	assert.NoError(t, err)
+	assert.True(t, revoked.Revoked)
+	revoked, err = repo.RevokeRefreshToken(ctx, RevokeRefreshTokenInput{Id: token.Id})
+	assert.NoError(t, err)
+	assert.False(t, revoked.Revoked)
+
+	err = repo.CreateRefreshToken(ctx, CreateRefreshTokenInput{UserId: userId, FamilyId: "family", TokenHash: "first", ExpiresAt: expiresAt})
+	assertViolation(t, err, "refresh_tokens_token_hash_key")
+	err = repo.CreateRefreshToken(ctx, CreateRefreshTokenInput{UserId: userId + 1, FamilyId: "family", TokenHash: "unknown-user", ExpiresAt: expiresAt})
+	assertViolation(t, err, "refresh_tokens_user_id_fkey")
+
+	_, err = repo.GetRefreshTokenByHash(ctx, GetRefreshTokenInput{TokenHash: "unknown"})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestIntegration_RevokeRefreshTokens(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+	expiresAt := time.Now().Add(time.Hour)
+	for hash, family := range map[string]string{"first": "family", "second": "family", "other": "other-family", "third": "third-family"} {
+		assert.NoError(t, repo.CreateRefreshToken(ctx, CreateRefreshTokenInput{UserId: userId, FamilyId: family, TokenHash: hash, ExpiresAt: expiresAt}))
+	}
+	isRevoked := func(hash string) bool {
+		token, err := repo.GetRefreshTokenByHash(ctx, GetRefreshTokenInput{TokenHash: hash})
+		assert.NoError(t, err)
+		return token.RevokedAt != nil
+	}
+
+	assert.NoError(t, repo.RevokeRefreshTokenFamily(ctx, RevokeRefreshTokenFamilyInput{FamilyId: "family"}))
+	assert.True(t, isRevoked("first"))
+	assert.True(t, isRevoked("second"))
+	assert.False(t, isRevoked("other"))
+
+	assert.NoError(t, repo.RevokeUserRefreshTokens(ctx, RevokeUserRefreshTokensInput{UserId: userId, ExceptFamilyId: "other-family"}))
+	assert.False(t, isRevoked("other"))
+	assert.True(t, isRevoked("third"))
+
+	assert.NoError(t, repo.RevokeUserRefreshTokens(ctx, RevokeUserRefreshTokensInput{UserId: userId}))
+	assert.True(t, isRevoked("other"))
+
+	// the tokens of deleted users are not found
+	_, err := repo.SoftDeleteUser(ctx, SoftDeleteUserInput{Id: userId})
+	assert.NoError(t, err)
+	_, err = repo.GetRefreshTokenByHash(ctx, GetRefreshTokenInput{TokenHash: "other"})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+// Token Revocation
+func TestIntegration_RevokeToken(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+	issuedAt := time.Now().Add(-time.Minute)
+
+	assert.NoError(t, repo.RevokeToken(ctx, RevokeTokenInput{Jti: "expired", UserId: userId, ExpiresAt: time.Now().Add(-time.Second)}))
+	assert.NoError(t, repo.RevokeToken(ctx, RevokeTokenInput{Jti: "jti", UserId: userId, ExpiresAt: time.Now().Add(time.Hour)}))
+	// revoking twice is fine
+	assert.NoError(t, repo.RevokeToken(ctx, RevokeTokenInput{Jti: "jti", UserId: userId, ExpiresAt: time.Now().Add(time.Hour)}))
+
+	for jti, expected := range map[string]bool{"jti": true, "other": false, "expired": false} {
+		res, err := repo.IsTokenRevoked(ctx, IsTokenRevokedInput{Jti: jti, UserId: userId, SessionId: "family", IssuedAt: issuedAt})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, res.Revoked, jti)
+	}
+
+	err := repo.RevokeToken(ctx, RevokeTokenInput{Jti: "unknown-user", UserId: userId + 1, ExpiresAt: time.Now().Add(time.Hour)})
+	assertViolation(t, err, "revoked_tokens_user_id_fkey")
+}
+
+func TestIntegration_RevokeUserTokens(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	ctx := context.Background()
+	userId := createIntegrationUser(t, repo, "+628111111111")
+	revokedBefore := time.Now().Truncate(time.Second).Add(500 * time.Millisecond)
+
+	assert.NoError(t, repo.RevokeUserTokens(ctx, RevokeUserTokensInput{UserId: userId, RevokedBefore: revokedBefore, ExpiresAt: time.Now().Add(time.Hour), ExceptSessionId: "current"}))
+
+	testCases := map[string]struct {
+		sessionId string
+		issuedAt  time.Time
+		revoked   bool
+	}{
+		"other session":   {sessionId: "other", issuedAt: revokedBefore.Add(-time.Minute), revoked: true},
+		"current session": {sessionId: "current", issuedAt: revokedBefore.Add(-time.Minute), revoked: false},
+		"issued after":    {sessionId: "other", issuedAt: revokedBefore.Add(time.Minute), revoked: false},
+		"same second":     {sessionId: "other", issuedAt: revokedBefore.Add(-time.Millisecond), revoked: true},
+		"just after":      {sessionId: "other", issuedAt: revokedBefore.Add(time.Millisecond), revoked: false},
+	}
+	for name, testCase := range testCases {
+		res, err := repo.IsTokenRevoked(ctx, IsTokenRevokedInput{Jti: "jti", UserId: userId, SessionId: testCase.sessionId, IssuedAt: testCase.issuedAt})
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.revoked, res.Revoked, name)
+	}
+
+	// a new revocation replaces the previous one
+	assert.NoError(t, repo.RevokeUserTokens(ctx, RevokeUserTokensInput{UserId: userId, RevokedBefore: revokedBefore, ExpiresAt: time.Now().Add(time.Hour)}))
+	res, err := repo.IsTokenRevoked(ctx, IsTokenRevokedInput{Jti: "jti", UserId: userId, SessionId: "current", IssuedAt: revokedBefore.Add(-time.Minute)})
+	assert.NoError(t, err)
+	assert.True(t, res.Revoked)
+
+	err = repo.RevokeUserTokens(ctx, RevokeUserTokensInput{UserId: userId + 1, RevokedBefore: revokedBefore, ExpiresAt: time.Now().Add(time.Hour)})
+	assertViolation(t, err, "revoked_user_tokens_user_id_fkey")
+}
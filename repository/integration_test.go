@@ -0,0 +1,114 @@
+// This file contains the harness of the integration tests, they run the
+// repository against the Postgres of TEST_DATABASE_URL.
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/migrations"
+	"github.com/stretchr/testify/assert"
+)
+
+// newIntegrationRepository returns a repository on a schema of its own, migrated
+// to the latest version and dropped once the test is over, so tests neither see
+// each other's rows nor need to clean up. The test is skipped unless
+// TEST_DATABASE_URL is set, and with -short.
+func newIntegrationRepository(t *testing.T) *Repository {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" || testing.Short() {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	schema := "test_" + randomHex(t, 8)
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		admin.Close()
+		t.Fatal(err)
+	}
+
+	// every connection of the pool starts with the schema as search path
+	db, err := sql.Open("postgres", withSearchPath(dsn, schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		if _, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`); err != nil {
+			t.Error(err)
+		}
+		admin.Close()
+	})
+
+	migrator, err := migrations.NewMigrator(migrations.NewMigratorOptions{Db: db})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	return &Repository{Db: db}
+}
+
+// withSearchPath adds the search_path run-time parameter to the DSN, either a
+// URL or a list of key=value settings.
+func withSearchPath(dsn string, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err == nil {
+			query := u.Query()
+			query.Set("search_path", schema)
+			u.RawQuery = query.Encode()
+			return u.String()
+		}
+	}
+	return dsn + " search_path=" + schema
+}
+
+// createIntegrationUser registers a user, the password hash is unique like the column.
+func createIntegrationUser(t *testing.T, repo *Repository, phoneNumber string) int {
+	t.Helper()
+	res, err := repo.CreateNewUser(context.Background(), GetRegistrationInput{
+		FullName:    "Arthur Dent",
+		PhoneNumber: phoneNumber,
+		Password:    "hash-" + randomHex(t, 8),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.Id
+}
+
+// assertViolation checks that the error is the violation of the named
+// constraint, a foreign key when its name ends with _fkey
+func assertViolation(t *testing.T, err error, constraint string) {
+	t.Helper()
+	var constraintErr *ConstraintError
+	if assert.ErrorAs(t, err, &constraintErr) {
+		assert.Equal(t, constraint, constraintErr.Constraint)
+		if strings.HasSuffix(constraint, "_fkey") {
+			assert.ErrorIs(t, err, ErrForeignKeyViolation)
+		} else {
+			assert.ErrorIs(t, err, ErrUniqueViolation)
+		}
+	}
+}
+
+func randomHex(t *testing.T, size int) string {
+	t.Helper()
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -7,9 +7,59 @@ package repository
 import "context"
 
 type RepositoryInterface interface {
+	RevocationStoreInterface
 	GetTestById(ctx context.Context, input GetTestByIdInput) (output GetTestByIdOutput, err error)
 	CreateNewUser(ctx context.Context, input GetRegistrationInput) (output GetRegistrationOutput, err error)
 	GetUserByPhoneNumber(ctx context.Context, input GetLoginInput) (output GetLoginOutput, err error)
 	UpdateUserSuccesLogin(ctx context.Context, input PostUpdateUserSuccesLoginInput) error
-	UpdateUserByPhoneNumber(ctx context.Context, input UpdateUserInput) (output UpdateUserOutput, err error)
+	RecordFailedLogin(ctx context.Context, input RecordFailedLoginInput) (output RecordFailedLoginOutput, err error)
+	LockUser(ctx context.Context, input LockUserInput) error
+	GetUserCredentialsById(ctx context.Context, input GetUserCredentialsByIdInput) (output GetUserCredentialsByIdOutput, err error)
+	UpdateUserPassword(ctx context.Context, input UpdateUserPasswordInput) error
+	RehashUserPassword(ctx context.Context, input RehashUserPasswordInput) error
+	SoftDeleteUser(ctx context.Context, input SoftDeleteUserInput) (output SoftDeleteUserOutput, err error)
+	RestoreUser(ctx context.Context, input RestoreUserInput) (output RestoreUserOutput, err error)
+	PurgeDeletedUsers(ctx context.Context, input PurgeDeletedUsersInput) (output PurgeDeletedUsersOutput, err error)
+	CreatePasswordReset(ctx context.Context, input CreatePasswordResetInput) error
+	GetPasswordResetByPhoneNumber(ctx context.Context, input GetPasswordResetInput) (output GetPasswordResetOutput, err error)
+	IncrementPasswordResetAttempts(ctx context.Context, input IncrementPasswordResetAttemptsInput) (output IncrementPasswordResetAttemptsOutput, err error)
+	ConsumePasswordReset(ctx context.Context, input ConsumePasswordResetInput) (output ConsumePasswordResetOutput, err error)
+	CreatePhoneVerification(ctx context.Context, input CreatePhoneVerificationInput) error
+	GetPhoneVerificationByUserId(ctx context.Context, input GetPhoneVerificationInput) (output GetPhoneVerificationOutput, err error)
+	IncrementPhoneVerificationAttempts(ctx context.Context, input IncrementPhoneVerificationAttemptsInput) (output IncrementPhoneVerificationAttemptsOutput, err error)
+	ConsumePhoneVerification(ctx context.Context, input ConsumePhoneVerificationInput) (output ConsumePhoneVerificationOutput, err error)
+	VerifyUserPhoneNumber(ctx context.Context, input VerifyUserPhoneNumberInput) error
+	CreateTotpSecret(ctx context.Context, input CreateTotpSecretInput) (output CreateTotpSecretOutput, err error)
+	GetTotpByUserId(ctx context.Context, input GetTotpInput) (output GetTotpOutput, err error)
+	UseTotpStep(ctx context.Context, input UseTotpStepInput) (output UseTotpStepOutput, err error)
+	EnableTotp(ctx context.Context, input EnableTotpInput) (output EnableTotpOutput, err error)
+	ConsumeRecoveryCode(ctx context.Context, input ConsumeRecoveryCodeInput) (output ConsumeRecoveryCodeOutput, err error)
+	CreateMfaChallenge(ctx context.Context, input CreateMfaChallengeInput) error
+	GetMfaChallengeByHash(ctx context.Context, input GetMfaChallengeInput) (output GetMfaChallengeOutput, err error)
+	IncrementMfaChallengeAttempts(ctx context.Context, input IncrementMfaChallengeAttemptsInput) (output IncrementMfaChallengeAttemptsOutput, err error)
+	ConsumeMfaChallenge(ctx context.Context, input ConsumeMfaChallengeInput) (output ConsumeMfaChallengeOutput, err error)
+	CreateWebauthnSession(ctx context.Context, input CreateWebauthnSessionInput) error
+	ConsumeWebauthnSession(ctx context.Context, input ConsumeWebauthnSessionInput) (output ConsumeWebauthnSessionOutput, err error)
+	CreateWebauthnCredential(ctx context.Context, input CreateWebauthnCredentialInput) (output CreateWebauthnCredentialOutput, err error)
+	ListWebauthnCredentials(ctx context.Context, input ListWebauthnCredentialsInput) (output ListWebauthnCredentialsOutput, err error)
+	UpdateWebauthnCredential(ctx context.Context, input UpdateWebauthnCredentialInput) error
+	DeleteWebauthnCredential(ctx context.Context, input DeleteWebauthnCredentialInput) (output DeleteWebauthnCredentialOutput, err error)
+	CreateLoginEvent(ctx context.Context, input CreateLoginEventInput) error
+	ListLoginEvents(ctx context.Context, input ListLoginEventsInput) (output ListLoginEventsOutput, err error)
+	UpdateUserById(ctx context.Context, input UpdateUserByIdInput) (output UpdateUserByIdOutput, err error)
+	GetUserById(ctx context.Context, input GetUserByIdInput) (output GetUserByIdOutput, err error)
+	CreateRefreshToken(ctx context.Context, input CreateRefreshTokenInput) error
+	GetRefreshTokenByHash(ctx context.Context, input GetRefreshTokenInput) (output GetRefreshTokenOutput, err error)
+	RevokeRefreshToken(ctx context.Context, input RevokeRefreshTokenInput) (output RevokeRefreshTokenOutput, err error)
+	RevokeRefreshTokenFamily(ctx context.Context, input RevokeRefreshTokenFamilyInput) error
+	RevokeUserRefreshTokens(ctx context.Context, input RevokeUserRefreshTokensInput) error
+}
+
+// RevocationStoreInterface keeps track of revoked access tokens, either a single
+// token by its jti or every token of a user issued before a point in time.
+// Entries are kept until the tokens they cover would have expired anyway.
+type RevocationStoreInterface interface {
+	RevokeToken(ctx context.Context, input RevokeTokenInput) error
+	RevokeUserTokens(ctx context.Context, input RevokeUserTokensInput) error
+	IsTokenRevoked(ctx context.Context, input IsTokenRevokedInput) (output IsTokenRevokedOutput, err error)
 }
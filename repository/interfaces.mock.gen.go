@@ -34,6 +34,109 @@ func (m *MockRepositoryInterface) EXPECT() *MockRepositoryInterfaceMockRecorder
 	return m.recorder
 }
 
+// ConsumeMfaChallenge mocks base method.
+func (m *MockRepositoryInterface) ConsumeMfaChallenge(ctx context.Context, input ConsumeMfaChallengeInput) (ConsumeMfaChallengeOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeMfaChallenge", ctx, input)
+	ret0, _ := ret[0].(ConsumeMfaChallengeOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeMfaChallenge indicates an expected call of ConsumeMfaChallenge.
+func (mr *MockRepositoryInterfaceMockRecorder) ConsumeMfaChallenge(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeMfaChallenge", reflect.TypeOf((*MockRepositoryInterface)(nil).ConsumeMfaChallenge), ctx, input)
+}
+
+// ConsumePasswordReset mocks base method.
+func (m *MockRepositoryInterface) ConsumePasswordReset(ctx context.Context, input ConsumePasswordResetInput) (ConsumePasswordResetOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumePasswordReset", ctx, input)
+	ret0, _ := ret[0].(ConsumePasswordResetOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumePasswordReset indicates an expected call of ConsumePasswordReset.
+func (mr *MockRepositoryInterfaceMockRecorder) ConsumePasswordReset(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumePasswordReset", reflect.TypeOf((*MockRepositoryInterface)(nil).ConsumePasswordReset), ctx, input)
+}
+
+// ConsumePhoneVerification mocks base method.
+func (m *MockRepositoryInterface) ConsumePhoneVerification(ctx context.Context, input ConsumePhoneVerificationInput) (ConsumePhoneVerificationOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumePhoneVerification", ctx, input)
+	ret0, _ := ret[0].(ConsumePhoneVerificationOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumePhoneVerification indicates an expected call of ConsumePhoneVerification.
+func (mr *MockRepositoryInterfaceMockRecorder) ConsumePhoneVerification(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumePhoneVerification", reflect.TypeOf((*MockRepositoryInterface)(nil).ConsumePhoneVerification), ctx, input)
+}
+
+// ConsumeRecoveryCode mocks base method.
+func (m *MockRepositoryInterface) ConsumeRecoveryCode(ctx context.Context, input ConsumeRecoveryCodeInput) (ConsumeRecoveryCodeOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeRecoveryCode", ctx, input)
+	ret0, _ := ret[0].(ConsumeRecoveryCodeOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeRecoveryCode indicates an expected call of ConsumeRecoveryCode.
+func (mr *MockRepositoryInterfaceMockRecorder) ConsumeRecoveryCode(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeRecoveryCode", reflect.TypeOf((*MockRepositoryInterface)(nil).ConsumeRecoveryCode), ctx, input)
+}
+
+// ConsumeWebauthnSession mocks base method.
+func (m *MockRepositoryInterface) ConsumeWebauthnSession(ctx context.Context, input ConsumeWebauthnSessionInput) (ConsumeWebauthnSessionOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeWebauthnSession", ctx, input)
+	ret0, _ := ret[0].(ConsumeWebauthnSessionOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeWebauthnSession indicates an expected call of ConsumeWebauthnSession.
+func (mr *MockRepositoryInterfaceMockRecorder) ConsumeWebauthnSession(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeWebauthnSession", reflect.TypeOf((*MockRepositoryInterface)(nil).ConsumeWebauthnSession), ctx, input)
+}
+
+// CreateLoginEvent mocks base method.
+func (m *MockRepositoryInterface) CreateLoginEvent(ctx context.Context, input CreateLoginEventInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLoginEvent", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateLoginEvent indicates an expected call of CreateLoginEvent.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateLoginEvent(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLoginEvent", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateLoginEvent), ctx, input)
+}
+
+// CreateMfaChallenge mocks base method.
+func (m *MockRepositoryInterface) CreateMfaChallenge(ctx context.Context, input CreateMfaChallengeInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateMfaChallenge", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateMfaChallenge indicates an expected call of CreateMfaChallenge.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateMfaChallenge(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateMfaChallenge", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateMfaChallenge), ctx, input)
+}
+
 // CreateNewUser mocks base method.
 func (m *MockRepositoryInterface) CreateNewUser(ctx context.Context, input GetRegistrationInput) (GetRegistrationOutput, error) {
 	m.ctrl.T.Helper()
@@ -49,6 +152,182 @@ func (mr *MockRepositoryInterfaceMockRecorder) CreateNewUser(ctx, input interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateNewUser", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateNewUser), ctx, input)
 }
 
+// CreatePasswordReset mocks base method.
+func (m *MockRepositoryInterface) CreatePasswordReset(ctx context.Context, input CreatePasswordResetInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePasswordReset", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreatePasswordReset indicates an expected call of CreatePasswordReset.
+func (mr *MockRepositoryInterfaceMockRecorder) CreatePasswordReset(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePasswordReset", reflect.TypeOf((*MockRepositoryInterface)(nil).CreatePasswordReset), ctx, input)
+}
+
+// CreatePhoneVerification mocks base method.
+func (m *MockRepositoryInterface) CreatePhoneVerification(ctx context.Context, input CreatePhoneVerificationInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePhoneVerification", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreatePhoneVerification indicates an expected call of CreatePhoneVerification.
+func (mr *MockRepositoryInterfaceMockRecorder) CreatePhoneVerification(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePhoneVerification", reflect.TypeOf((*MockRepositoryInterface)(nil).CreatePhoneVerification), ctx, input)
+}
+
+// CreateRefreshToken mocks base method.
+func (m *MockRepositoryInterface) CreateRefreshToken(ctx context.Context, input CreateRefreshTokenInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRefreshToken", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateRefreshToken indicates an expected call of CreateRefreshToken.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateRefreshToken(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRefreshToken", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateRefreshToken), ctx, input)
+}
+
+// CreateTotpSecret mocks base method.
+func (m *MockRepositoryInterface) CreateTotpSecret(ctx context.Context, input CreateTotpSecretInput) (CreateTotpSecretOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTotpSecret", ctx, input)
+	ret0, _ := ret[0].(CreateTotpSecretOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateTotpSecret indicates an expected call of CreateTotpSecret.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateTotpSecret(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTotpSecret", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateTotpSecret), ctx, input)
+}
+
+// CreateWebauthnCredential mocks base method.
+func (m *MockRepositoryInterface) CreateWebauthnCredential(ctx context.Context, input CreateWebauthnCredentialInput) (CreateWebauthnCredentialOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebauthnCredential", ctx, input)
+	ret0, _ := ret[0].(CreateWebauthnCredentialOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWebauthnCredential indicates an expected call of CreateWebauthnCredential.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateWebauthnCredential(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebauthnCredential", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateWebauthnCredential), ctx, input)
+}
+
+// CreateWebauthnSession mocks base method.
+func (m *MockRepositoryInterface) CreateWebauthnSession(ctx context.Context, input CreateWebauthnSessionInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebauthnSession", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateWebauthnSession indicates an expected call of CreateWebauthnSession.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateWebauthnSession(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebauthnSession", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateWebauthnSession), ctx, input)
+}
+
+// DeleteWebauthnCredential mocks base method.
+func (m *MockRepositoryInterface) DeleteWebauthnCredential(ctx context.Context, input DeleteWebauthnCredentialInput) (DeleteWebauthnCredentialOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebauthnCredential", ctx, input)
+	ret0, _ := ret[0].(DeleteWebauthnCredentialOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteWebauthnCredential indicates an expected call of DeleteWebauthnCredential.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteWebauthnCredential(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebauthnCredential", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteWebauthnCredential), ctx, input)
+}
+
+// EnableTotp mocks base method.
+func (m *MockRepositoryInterface) EnableTotp(ctx context.Context, input EnableTotpInput) (EnableTotpOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTotp", ctx, input)
+	ret0, _ := ret[0].(EnableTotpOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnableTotp indicates an expected call of EnableTotp.
+func (mr *MockRepositoryInterfaceMockRecorder) EnableTotp(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTotp", reflect.TypeOf((*MockRepositoryInterface)(nil).EnableTotp), ctx, input)
+}
+
+// GetMfaChallengeByHash mocks base method.
+func (m *MockRepositoryInterface) GetMfaChallengeByHash(ctx context.Context, input GetMfaChallengeInput) (GetMfaChallengeOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMfaChallengeByHash", ctx, input)
+	ret0, _ := ret[0].(GetMfaChallengeOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMfaChallengeByHash indicates an expected call of GetMfaChallengeByHash.
+func (mr *MockRepositoryInterfaceMockRecorder) GetMfaChallengeByHash(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMfaChallengeByHash", reflect.TypeOf((*MockRepositoryInterface)(nil).GetMfaChallengeByHash), ctx, input)
+}
+
+// GetPasswordResetByPhoneNumber mocks base method.
+func (m *MockRepositoryInterface) GetPasswordResetByPhoneNumber(ctx context.Context, input GetPasswordResetInput) (GetPasswordResetOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPasswordResetByPhoneNumber", ctx, input)
+	ret0, _ := ret[0].(GetPasswordResetOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPasswordResetByPhoneNumber indicates an expected call of GetPasswordResetByPhoneNumber.
+func (mr *MockRepositoryInterfaceMockRecorder) GetPasswordResetByPhoneNumber(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPasswordResetByPhoneNumber", reflect.TypeOf((*MockRepositoryInterface)(nil).GetPasswordResetByPhoneNumber), ctx, input)
+}
+
+// GetPhoneVerificationByUserId mocks base method.
+func (m *MockRepositoryInterface) GetPhoneVerificationByUserId(ctx context.Context, input GetPhoneVerificationInput) (GetPhoneVerificationOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPhoneVerificationByUserId", ctx, input)
+	ret0, _ := ret[0].(GetPhoneVerificationOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPhoneVerificationByUserId indicates an expected call of GetPhoneVerificationByUserId.
+func (mr *MockRepositoryInterfaceMockRecorder) GetPhoneVerificationByUserId(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPhoneVerificationByUserId", reflect.TypeOf((*MockRepositoryInterface)(nil).GetPhoneVerificationByUserId), ctx, input)
+}
+
+// GetRefreshTokenByHash mocks base method.
+func (m *MockRepositoryInterface) GetRefreshTokenByHash(ctx context.Context, input GetRefreshTokenInput) (GetRefreshTokenOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRefreshTokenByHash", ctx, input)
+	ret0, _ := ret[0].(GetRefreshTokenOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRefreshTokenByHash indicates an expected call of GetRefreshTokenByHash.
+func (mr *MockRepositoryInterfaceMockRecorder) GetRefreshTokenByHash(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefreshTokenByHash", reflect.TypeOf((*MockRepositoryInterface)(nil).GetRefreshTokenByHash), ctx, input)
+}
+
 // GetTestById mocks base method.
 func (m *MockRepositoryInterface) GetTestById(ctx context.Context, input GetTestByIdInput) (GetTestByIdOutput, error) {
 	m.ctrl.T.Helper()
@@ -64,6 +343,36 @@ func (mr *MockRepositoryInterfaceMockRecorder) GetTestById(ctx, input interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTestById", reflect.TypeOf((*MockRepositoryInterface)(nil).GetTestById), ctx, input)
 }
 
+// GetTotpByUserId mocks base method.
+func (m *MockRepositoryInterface) GetTotpByUserId(ctx context.Context, input GetTotpInput) (GetTotpOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTotpByUserId", ctx, input)
+	ret0, _ := ret[0].(GetTotpOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTotpByUserId indicates an expected call of GetTotpByUserId.
+func (mr *MockRepositoryInterfaceMockRecorder) GetTotpByUserId(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTotpByUserId", reflect.TypeOf((*MockRepositoryInterface)(nil).GetTotpByUserId), ctx, input)
+}
+
+// GetUserById mocks base method.
+func (m *MockRepositoryInterface) GetUserById(ctx context.Context, input GetUserByIdInput) (GetUserByIdOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserById", ctx, input)
+	ret0, _ := ret[0].(GetUserByIdOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserById indicates an expected call of GetUserById.
+func (mr *MockRepositoryInterfaceMockRecorder) GetUserById(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserById", reflect.TypeOf((*MockRepositoryInterface)(nil).GetUserById), ctx, input)
+}
+
 // GetUserByPhoneNumber mocks base method.
 func (m *MockRepositoryInterface) GetUserByPhoneNumber(ctx context.Context, input GetLoginInput) (GetLoginOutput, error) {
 	m.ctrl.T.Helper()
@@ -79,19 +388,297 @@ func (mr *MockRepositoryInterfaceMockRecorder) GetUserByPhoneNumber(ctx, input i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByPhoneNumber", reflect.TypeOf((*MockRepositoryInterface)(nil).GetUserByPhoneNumber), ctx, input)
 }
 
-// UpdateUserByPhoneNumber mocks base method.
-func (m *MockRepositoryInterface) UpdateUserByPhoneNumber(ctx context.Context, input UpdateUserInput) (UpdateUserOutput, error) {
+// GetUserCredentialsById mocks base method.
+func (m *MockRepositoryInterface) GetUserCredentialsById(ctx context.Context, input GetUserCredentialsByIdInput) (GetUserCredentialsByIdOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserCredentialsById", ctx, input)
+	ret0, _ := ret[0].(GetUserCredentialsByIdOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserCredentialsById indicates an expected call of GetUserCredentialsById.
+func (mr *MockRepositoryInterfaceMockRecorder) GetUserCredentialsById(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserCredentialsById", reflect.TypeOf((*MockRepositoryInterface)(nil).GetUserCredentialsById), ctx, input)
+}
+
+// IncrementMfaChallengeAttempts mocks base method.
+func (m *MockRepositoryInterface) IncrementMfaChallengeAttempts(ctx context.Context, input IncrementMfaChallengeAttemptsInput) (IncrementMfaChallengeAttemptsOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementMfaChallengeAttempts", ctx, input)
+	ret0, _ := ret[0].(IncrementMfaChallengeAttemptsOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrementMfaChallengeAttempts indicates an expected call of IncrementMfaChallengeAttempts.
+func (mr *MockRepositoryInterfaceMockRecorder) IncrementMfaChallengeAttempts(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementMfaChallengeAttempts", reflect.TypeOf((*MockRepositoryInterface)(nil).IncrementMfaChallengeAttempts), ctx, input)
+}
+
+// IncrementPasswordResetAttempts mocks base method.
+func (m *MockRepositoryInterface) IncrementPasswordResetAttempts(ctx context.Context, input IncrementPasswordResetAttemptsInput) (IncrementPasswordResetAttemptsOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementPasswordResetAttempts", ctx, input)
+	ret0, _ := ret[0].(IncrementPasswordResetAttemptsOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrementPasswordResetAttempts indicates an expected call of IncrementPasswordResetAttempts.
+func (mr *MockRepositoryInterfaceMockRecorder) IncrementPasswordResetAttempts(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementPasswordResetAttempts", reflect.TypeOf((*MockRepositoryInterface)(nil).IncrementPasswordResetAttempts), ctx, input)
+}
+
+// IncrementPhoneVerificationAttempts mocks base method.
+func (m *MockRepositoryInterface) IncrementPhoneVerificationAttempts(ctx context.Context, input IncrementPhoneVerificationAttemptsInput) (IncrementPhoneVerificationAttemptsOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementPhoneVerificationAttempts", ctx, input)
+	ret0, _ := ret[0].(IncrementPhoneVerificationAttemptsOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrementPhoneVerificationAttempts indicates an expected call of IncrementPhoneVerificationAttempts.
+func (mr *MockRepositoryInterfaceMockRecorder) IncrementPhoneVerificationAttempts(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementPhoneVerificationAttempts", reflect.TypeOf((*MockRepositoryInterface)(nil).IncrementPhoneVerificationAttempts), ctx, input)
+}
+
+// IsTokenRevoked mocks base method.
+func (m *MockRepositoryInterface) IsTokenRevoked(ctx context.Context, input IsTokenRevokedInput) (IsTokenRevokedOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsTokenRevoked", ctx, input)
+	ret0, _ := ret[0].(IsTokenRevokedOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsTokenRevoked indicates an expected call of IsTokenRevoked.
+func (mr *MockRepositoryInterfaceMockRecorder) IsTokenRevoked(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsTokenRevoked", reflect.TypeOf((*MockRepositoryInterface)(nil).IsTokenRevoked), ctx, input)
+}
+
+// ListLoginEvents mocks base method.
+func (m *MockRepositoryInterface) ListLoginEvents(ctx context.Context, input ListLoginEventsInput) (ListLoginEventsOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLoginEvents", ctx, input)
+	ret0, _ := ret[0].(ListLoginEventsOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLoginEvents indicates an expected call of ListLoginEvents.
+func (mr *MockRepositoryInterfaceMockRecorder) ListLoginEvents(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLoginEvents", reflect.TypeOf((*MockRepositoryInterface)(nil).ListLoginEvents), ctx, input)
+}
+
+// ListWebauthnCredentials mocks base method.
+func (m *MockRepositoryInterface) ListWebauthnCredentials(ctx context.Context, input ListWebauthnCredentialsInput) (ListWebauthnCredentialsOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebauthnCredentials", ctx, input)
+	ret0, _ := ret[0].(ListWebauthnCredentialsOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebauthnCredentials indicates an expected call of ListWebauthnCredentials.
+func (mr *MockRepositoryInterfaceMockRecorder) ListWebauthnCredentials(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebauthnCredentials", reflect.TypeOf((*MockRepositoryInterface)(nil).ListWebauthnCredentials), ctx, input)
+}
+
+// LockUser mocks base method.
+func (m *MockRepositoryInterface) LockUser(ctx context.Context, input LockUserInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockUser", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockUser indicates an expected call of LockUser.
+func (mr *MockRepositoryInterfaceMockRecorder) LockUser(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockUser", reflect.TypeOf((*MockRepositoryInterface)(nil).LockUser), ctx, input)
+}
+
+// PurgeDeletedUsers mocks base method.
+func (m *MockRepositoryInterface) PurgeDeletedUsers(ctx context.Context, input PurgeDeletedUsersInput) (PurgeDeletedUsersOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeletedUsers", ctx, input)
+	ret0, _ := ret[0].(PurgeDeletedUsersOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeletedUsers indicates an expected call of PurgeDeletedUsers.
+func (mr *MockRepositoryInterfaceMockRecorder) PurgeDeletedUsers(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeletedUsers", reflect.TypeOf((*MockRepositoryInterface)(nil).PurgeDeletedUsers), ctx, input)
+}
+
+// RecordFailedLogin mocks base method.
+func (m *MockRepositoryInterface) RecordFailedLogin(ctx context.Context, input RecordFailedLoginInput) (RecordFailedLoginOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordFailedLogin", ctx, input)
+	ret0, _ := ret[0].(RecordFailedLoginOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecordFailedLogin indicates an expected call of RecordFailedLogin.
+func (mr *MockRepositoryInterfaceMockRecorder) RecordFailedLogin(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordFailedLogin", reflect.TypeOf((*MockRepositoryInterface)(nil).RecordFailedLogin), ctx, input)
+}
+
+// RehashUserPassword mocks base method.
+func (m *MockRepositoryInterface) RehashUserPassword(ctx context.Context, input RehashUserPasswordInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RehashUserPassword", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RehashUserPassword indicates an expected call of RehashUserPassword.
+func (mr *MockRepositoryInterfaceMockRecorder) RehashUserPassword(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RehashUserPassword", reflect.TypeOf((*MockRepositoryInterface)(nil).RehashUserPassword), ctx, input)
+}
+
+// RestoreUser mocks base method.
+func (m *MockRepositoryInterface) RestoreUser(ctx context.Context, input RestoreUserInput) (RestoreUserOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreUser", ctx, input)
+	ret0, _ := ret[0].(RestoreUserOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreUser indicates an expected call of RestoreUser.
+func (mr *MockRepositoryInterfaceMockRecorder) RestoreUser(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreUser", reflect.TypeOf((*MockRepositoryInterface)(nil).RestoreUser), ctx, input)
+}
+
+// RevokeRefreshToken mocks base method.
+func (m *MockRepositoryInterface) RevokeRefreshToken(ctx context.Context, input RevokeRefreshTokenInput) (RevokeRefreshTokenOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeRefreshToken", ctx, input)
+	ret0, _ := ret[0].(RevokeRefreshTokenOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeRefreshToken indicates an expected call of RevokeRefreshToken.
+func (mr *MockRepositoryInterfaceMockRecorder) RevokeRefreshToken(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeRefreshToken", reflect.TypeOf((*MockRepositoryInterface)(nil).RevokeRefreshToken), ctx, input)
+}
+
+// RevokeRefreshTokenFamily mocks base method.
+func (m *MockRepositoryInterface) RevokeRefreshTokenFamily(ctx context.Context, input RevokeRefreshTokenFamilyInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeRefreshTokenFamily", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeRefreshTokenFamily indicates an expected call of RevokeRefreshTokenFamily.
+func (mr *MockRepositoryInterfaceMockRecorder) RevokeRefreshTokenFamily(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeRefreshTokenFamily", reflect.TypeOf((*MockRepositoryInterface)(nil).RevokeRefreshTokenFamily), ctx, input)
+}
+
+// RevokeToken mocks base method.
+func (m *MockRepositoryInterface) RevokeToken(ctx context.Context, input RevokeTokenInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeToken", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeToken indicates an expected call of RevokeToken.
+func (mr *MockRepositoryInterfaceMockRecorder) RevokeToken(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeToken", reflect.TypeOf((*MockRepositoryInterface)(nil).RevokeToken), ctx, input)
+}
+
+// RevokeUserRefreshTokens mocks base method.
+func (m *MockRepositoryInterface) RevokeUserRefreshTokens(ctx context.Context, input RevokeUserRefreshTokensInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserRefreshTokens", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeUserRefreshTokens indicates an expected call of RevokeUserRefreshTokens.
+func (mr *MockRepositoryInterfaceMockRecorder) RevokeUserRefreshTokens(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserRefreshTokens", reflect.TypeOf((*MockRepositoryInterface)(nil).RevokeUserRefreshTokens), ctx, input)
+}
+
+// RevokeUserTokens mocks base method.
+func (m *MockRepositoryInterface) RevokeUserTokens(ctx context.Context, input RevokeUserTokensInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserTokens", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeUserTokens indicates an expected call of RevokeUserTokens.
+func (mr *MockRepositoryInterfaceMockRecorder) RevokeUserTokens(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserTokens", reflect.TypeOf((*MockRepositoryInterface)(nil).RevokeUserTokens), ctx, input)
+}
+
+// SoftDeleteUser mocks base method.
+func (m *MockRepositoryInterface) SoftDeleteUser(ctx context.Context, input SoftDeleteUserInput) (SoftDeleteUserOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SoftDeleteUser", ctx, input)
+	ret0, _ := ret[0].(SoftDeleteUserOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SoftDeleteUser indicates an expected call of SoftDeleteUser.
+func (mr *MockRepositoryInterfaceMockRecorder) SoftDeleteUser(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SoftDeleteUser", reflect.TypeOf((*MockRepositoryInterface)(nil).SoftDeleteUser), ctx, input)
+}
+
+// UpdateUserById mocks base method.
+func (m *MockRepositoryInterface) UpdateUserById(ctx context.Context, input UpdateUserByIdInput) (UpdateUserByIdOutput, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateUserByPhoneNumber", ctx, input)
-	ret0, _ := ret[0].(UpdateUserOutput)
+	ret := m.ctrl.Call(m, "UpdateUserById", ctx, input)
+	ret0, _ := ret[0].(UpdateUserByIdOutput)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// UpdateUserByPhoneNumber indicates an expected call of UpdateUserByPhoneNumber.
-func (mr *MockRepositoryInterfaceMockRecorder) UpdateUserByPhoneNumber(ctx, input interface{}) *gomock.Call {
+// UpdateUserById indicates an expected call of UpdateUserById.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateUserById(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserById", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateUserById), ctx, input)
+}
+
+// UpdateUserPassword mocks base method.
+func (m *MockRepositoryInterface) UpdateUserPassword(ctx context.Context, input UpdateUserPasswordInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserPassword", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserPassword indicates an expected call of UpdateUserPassword.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateUserPassword(ctx, input interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserByPhoneNumber", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateUserByPhoneNumber), ctx, input)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserPassword", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateUserPassword), ctx, input)
 }
 
 // UpdateUserSuccesLogin mocks base method.
@@ -107,3 +694,112 @@ func (mr *MockRepositoryInterfaceMockRecorder) UpdateUserSuccesLogin(ctx, input
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserSuccesLogin", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateUserSuccesLogin), ctx, input)
 }
+
+// UpdateWebauthnCredential mocks base method.
+func (m *MockRepositoryInterface) UpdateWebauthnCredential(ctx context.Context, input UpdateWebauthnCredentialInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWebauthnCredential", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateWebauthnCredential indicates an expected call of UpdateWebauthnCredential.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateWebauthnCredential(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWebauthnCredential", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateWebauthnCredential), ctx, input)
+}
+
+// UseTotpStep mocks base method.
+func (m *MockRepositoryInterface) UseTotpStep(ctx context.Context, input UseTotpStepInput) (UseTotpStepOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTotpStep", ctx, input)
+	ret0, _ := ret[0].(UseTotpStepOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseTotpStep indicates an expected call of UseTotpStep.
+func (mr *MockRepositoryInterfaceMockRecorder) UseTotpStep(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTotpStep", reflect.TypeOf((*MockRepositoryInterface)(nil).UseTotpStep), ctx, input)
+}
+
+// VerifyUserPhoneNumber mocks base method.
+func (m *MockRepositoryInterface) VerifyUserPhoneNumber(ctx context.Context, input VerifyUserPhoneNumberInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyUserPhoneNumber", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyUserPhoneNumber indicates an expected call of VerifyUserPhoneNumber.
+func (mr *MockRepositoryInterfaceMockRecorder) VerifyUserPhoneNumber(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyUserPhoneNumber", reflect.TypeOf((*MockRepositoryInterface)(nil).VerifyUserPhoneNumber), ctx, input)
+}
+
+// MockRevocationStoreInterface is a mock of RevocationStoreInterface interface.
+type MockRevocationStoreInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockRevocationStoreInterfaceMockRecorder
+}
+
+// MockRevocationStoreInterfaceMockRecorder is the mock recorder for MockRevocationStoreInterface.
+type MockRevocationStoreInterfaceMockRecorder struct {
+	mock *MockRevocationStoreInterface
+}
+
+// NewMockRevocationStoreInterface creates a new mock instance.
+func NewMockRevocationStoreInterface(ctrl *gomock.Controller) *MockRevocationStoreInterface {
+	mock := &MockRevocationStoreInterface{ctrl: ctrl}
+	mock.recorder = &MockRevocationStoreInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRevocationStoreInterface) EXPECT() *MockRevocationStoreInterfaceMockRecorder {
+	return m.recorder
+}
+
+// IsTokenRevoked mocks base method.
+func (m *MockRevocationStoreInterface) IsTokenRevoked(ctx context.Context, input IsTokenRevokedInput) (IsTokenRevokedOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsTokenRevoked", ctx, input)
+	ret0, _ := ret[0].(IsTokenRevokedOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsTokenRevoked indicates an expected call of IsTokenRevoked.
+func (mr *MockRevocationStoreInterfaceMockRecorder) IsTokenRevoked(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsTokenRevoked", reflect.TypeOf((*MockRevocationStoreInterface)(nil).IsTokenRevoked), ctx, input)
+}
+
+// RevokeToken mocks base method.
+func (m *MockRevocationStoreInterface) RevokeToken(ctx context.Context, input RevokeTokenInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeToken", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeToken indicates an expected call of RevokeToken.
+func (mr *MockRevocationStoreInterfaceMockRecorder) RevokeToken(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeToken", reflect.TypeOf((*MockRevocationStoreInterface)(nil).RevokeToken), ctx, input)
+}
+
+// RevokeUserTokens mocks base method.
+func (m *MockRevocationStoreInterface) RevokeUserTokens(ctx context.Context, input RevokeUserTokensInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserTokens", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeUserTokens indicates an expected call of RevokeUserTokens.
+func (mr *MockRevocationStoreInterfaceMockRecorder) RevokeUserTokens(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserTokens", reflect.TypeOf((*MockRevocationStoreInterface)(nil).RevokeUserTokens), ctx, input)
+}
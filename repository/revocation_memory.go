@@ -0,0 +1,83 @@
+// This file contains an in-memory implementation of the revocation store.
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type userRevocation struct {
+	revokedBefore   time.Time
+	expiresAt       time.Time
+	exceptSessionId string
+}
+
+// MemoryRevocationStore is a RevocationStoreInterface that lives in the process
+// memory. It is meant for tests and single instance deployments.
+type MemoryRevocationStore struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time
+	users  map[int]userRevocation
+	now    func() time.Time
+}
+
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		tokens: make(map[string]time.Time),
+		users:  make(map[int]userRevocation),
+		now:    time.Now,
+	}
+}
+
+func (m *MemoryRevocationStore) RevokeToken(ctx context.Context, input RevokeTokenInput) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prune()
+	m.tokens[input.Jti] = input.ExpiresAt
+	return nil
+}
+
+func (m *MemoryRevocationStore) RevokeUserTokens(ctx context.Context, input RevokeUserTokensInput) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prune()
+	m.users[input.UserId] = userRevocation{
+		revokedBefore:   input.RevokedBefore,
+		expiresAt:       input.ExpiresAt,
+		exceptSessionId: input.ExceptSessionId,
+	}
+	return nil
+}
+
+func (m *MemoryRevocationStore) IsTokenRevoked(ctx context.Context, input IsTokenRevokedInput) (output IsTokenRevokedOutput, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.tokens[input.Jti]; ok {
+		output.Revoked = true
+		return
+	}
+	user, ok := m.users[input.UserId]
+	if ok && user.revokedBefore.After(input.IssuedAt) && (user.exceptSessionId == "" || user.exceptSessionId != input.SessionId) {
+		output.Revoked = true
+	}
+	return
+}
+
+// prune drops the entries whose tokens have expired, the caller must hold the lock.
+func (m *MemoryRevocationStore) prune() {
+	now := m.now()
+	for jti, expiresAt := range m.tokens {
+		if expiresAt.Before(now) {
+			delete(m.tokens, jti)
+		}
+	}
+	for userId, user := range m.users {
+		if user.expiresAt.Before(now) {
+			delete(m.users, userId)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryRevocationStore_RevokeToken(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	ctx := context.Background()
+
+	err := store.RevokeToken(ctx, RevokeTokenInput{Jti: "jti", UserId: 1, ExpiresAt: time.Now().Add(time.Minute)})
+	assert.NoError(t, err)
+
+	res, err := store.IsTokenRevoked(ctx, IsTokenRevokedInput{Jti: "jti", UserId: 1, IssuedAt: time.Now()})
+	assert.NoError(t, err)
+	assert.True(t, res.Revoked)
+
+	res, err = store.IsTokenRevoked(ctx, IsTokenRevokedInput{Jti: "other", UserId: 1, IssuedAt: time.Now()})
+	assert.NoError(t, err)
+	assert.False(t, res.Revoked)
+}
+
+func TestMemoryRevocationStore_RevokeUserTokens(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	ctx := context.Background()
+	now := time.Now()
+
+	err := store.RevokeUserTokens(ctx, RevokeUserTokensInput{UserId: 1, RevokedBefore: now, ExpiresAt: now.Add(time.Minute)})
+	assert.NoError(t, err)
+
+	res, err := store.IsTokenRevoked(ctx, IsTokenRevokedInput{Jti: "old", UserId: 1, IssuedAt: now.Add(-time.Second)})
+	assert.NoError(t, err)
+	assert.True(t, res.Revoked)
+
+	res, err = store.IsTokenRevoked(ctx, IsTokenRevokedInput{Jti: "new", UserId: 1, IssuedAt: now.Add(time.Second)})
+	assert.NoError(t, err)
+	assert.False(t, res.Revoked)
+
+	res, err = store.IsTokenRevoked(ctx, IsTokenRevokedInput{Jti: "old", UserId: 2, IssuedAt: now.Add(-time.Second)})
+	assert.NoError(t, err)
+	assert.False(t, res.Revoked)
+}
+
+func TestMemoryRevocationStore_RevokeUserTokens_SameSecond(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second).Add(500 * time.Millisecond)
+
+	err := store.RevokeUserTokens(ctx, RevokeUserTokensInput{UserId: 1, RevokedBefore: now, ExpiresAt: now.Add(time.Minute)})
+	assert.NoError(t, err)
+
+	// issued earlier in the same second, the token is revoked
+	res, err := store.IsTokenRevoked(ctx, IsTokenRevokedInput{Jti: "old", UserId: 1, IssuedAt: now.Add(-time.Millisecond)})
+	assert.NoError(t, err)
+	assert.True(t, res.Revoked)
+
+	res, err = store.IsTokenRevoked(ctx, IsTokenRevokedInput{Jti: "new", UserId: 1, IssuedAt: now.Add(time.Millisecond)})
+	assert.NoError(t, err)
+	assert.False(t, res.Revoked)
+}
+
+func TestMemoryRevocationStore_RevokeUserTokens_ExceptSession(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	ctx := context.Background()
+	now := time.Now()
+
+	err := store.RevokeUserTokens(ctx, RevokeUserTokensInput{UserId: 1, RevokedBefore: now, ExpiresAt: now.Add(time.Minute), ExceptSessionId: "current"})
+	assert.NoError(t, err)
+
+	res, err := store.IsTokenRevoked(ctx, IsTokenRevokedInput{Jti: "old", UserId: 1, SessionId: "current", IssuedAt: now.Add(-time.Second)})
+	assert.NoError(t, err)
+	assert.False(t, res.Revoked)
+
+	res, err = store.IsTokenRevoked(ctx, IsTokenRevokedInput{Jti: "old", UserId: 1, SessionId: "other", IssuedAt: now.Add(-time.Second)})
+	assert.NoError(t, err)
+	assert.True(t, res.Revoked)
+}
+
+func TestMemoryRevocationStore_Prune(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	ctx := context.Background()
+	now := time.Now()
+
+	assert.NoError(t, store.RevokeToken(ctx, RevokeTokenInput{Jti: "expired", UserId: 1, ExpiresAt: now.Add(-time.Minute)}))
+	assert.NoError(t, store.RevokeUserTokens(ctx, RevokeUserTokensInput{UserId: 1, RevokedBefore: now, ExpiresAt: now.Add(-time.Minute)}))
+
+	// expired entries are dropped on the next write
+	assert.NoError(t, store.RevokeToken(ctx, RevokeTokenInput{Jti: "active", UserId: 2, ExpiresAt: now.Add(time.Minute)}))
+
+	assert.NotContains(t, store.tokens, "expired")
+	assert.NotContains(t, store.users, 1)
+	assert.Contains(t, store.tokens, "active")
+}
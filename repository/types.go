@@ -1,6 +1,8 @@
 // This file contains types that are used in the repository layer.
 package repository
 
+import "time"
+
 type GetTestByIdInput struct {
 	Id string
 }
@@ -26,23 +28,475 @@ type GetLoginInput struct {
 }
 
 type GetLoginOutput struct {
+	Id               int
+	Password         string
+	PhoneNumber      string
+	FullName         string
+	FailedLoginCount int
+	// LockedUntil is set while the account is locked after too many failed logins
+	LockedUntil *time.Time
+	// PhoneVerifiedAt is nil until the phone number is verified
+	PhoneVerifiedAt *time.Time
+	// TotpEnabled is true when the login needs a second factor
+	TotpEnabled bool
+}
+
+type PostUpdateUserSuccesLoginInput struct {
+	Id int
+}
+
+type RecordFailedLoginInput struct {
+	Id int
+}
+
+type RecordFailedLoginOutput struct {
+	// FailedLoginCount is the number of consecutive failures, this one included
+	FailedLoginCount int
+}
+
+type LockUserInput struct {
+	Id          int
+	LockedUntil time.Time
+}
+
+// Account Lifecycle
+type GetUserCredentialsByIdInput struct {
+	Id int
+}
+
+type GetUserCredentialsByIdOutput struct {
 	Id          int
 	Password    string
-	PhoneNumber string
 	FullName    string
+	PhoneNumber string
+	// PhoneVerifiedAt is nil until the phone number is verified
+	PhoneVerifiedAt *time.Time
+	// LockedUntil is set while the account is locked after too many failed logins
+	LockedUntil *time.Time
 }
 
-type PostUpdateUserSuccesLoginInput struct {
+type SoftDeleteUserInput struct {
+	Id int
+}
+
+type SoftDeleteUserOutput struct {
+	// Deleted is false when the user does not exist or was already deleted
+	Deleted bool
+}
+
+type RestoreUserInput struct {
+	Id int
+}
+
+type RestoreUserOutput struct {
+	// Restored is false when there is no deleted user with this id
+	Restored bool
+}
+
+type PurgeDeletedUsersInput struct {
+	DeletedBefore time.Time
+}
+
+type PurgeDeletedUsersOutput struct {
+	Purged int64
+	// Ids are those of the purged users, their files are to be deleted
+	Ids []int
+}
+
+// Change Password
+type UpdateUserPasswordInput struct {
+	Id       int
+	Password string
+}
+
+// Password Rehash
+type RehashUserPasswordInput struct {
 	Id int
+	// CurrentHash is the hash the password was verified against
+	CurrentHash string
+	NewHash     string
+}
+
+// Password Reset
+type CreatePasswordResetInput struct {
+	UserId    int
+	OtpHash   string
+	OtpSalt   string
+	ExpiresAt time.Time
+}
+
+type GetPasswordResetInput struct {
+	PhoneNumber string
+}
+
+type GetPasswordResetOutput struct {
+	Id        int
+	UserId    int
+	FullName  string
+	OtpHash   string
+	OtpSalt   string
+	Attempts  int
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+type IncrementPasswordResetAttemptsInput struct {
+	Id int
+}
+
+type IncrementPasswordResetAttemptsOutput struct {
+	// Attempts is the number of confirmations tried, this one included
+	Attempts int
+}
+
+type ConsumePasswordResetInput struct {
+	Id int
+}
+
+type ConsumePasswordResetOutput struct {
+	// Consumed is false when the reset had already been used
+	Consumed bool
+}
+
+// Phone Verification
+type CreatePhoneVerificationInput struct {
+	UserId int
+	// PhoneNumber is the number the OTP is sent to, it differs from the one of
+	// the user for a pending change
+	PhoneNumber string
+	OtpHash     string
+	OtpSalt     string
+	ExpiresAt   time.Time
+}
+
+type GetPhoneVerificationInput struct {
+	UserId int
+}
+
+type GetPhoneVerificationOutput struct {
+	Id          int
+	UserId      int
+	PhoneNumber string
+	OtpHash     string
+	OtpSalt     string
+	Attempts    int
+	ExpiresAt   time.Time
+	UsedAt      *time.Time
+}
+
+type IncrementPhoneVerificationAttemptsInput struct {
+	Id int
+}
+
+type IncrementPhoneVerificationAttemptsOutput struct {
+	// Attempts is the number of confirmations tried, this one included
+	Attempts int
+}
+
+type ConsumePhoneVerificationInput struct {
+	Id int
+}
+
+type ConsumePhoneVerificationOutput struct {
+	// Consumed is false when the verification had already been used
+	Consumed bool
+}
+
+type VerifyUserPhoneNumberInput struct {
+	Id          int
+	PhoneNumber string
+}
+
+// Two-Factor Authentication
+type CreateTotpSecretInput struct {
+	UserId int
+	Secret string
+}
+
+type CreateTotpSecretOutput struct {
+	// Created is false when the user already enabled the TOTP, it is kept as is
+	Created bool
+}
+
+type GetTotpInput struct {
+	UserId int
+}
+
+type GetTotpOutput struct {
+	Secret string
+	// EnabledAt is nil while the enrollment is pending
+	EnabledAt *time.Time
+}
+
+type UseTotpStepInput struct {
+	UserId int
+	Step   int64
+}
+
+type UseTotpStepOutput struct {
+	// Used is false when a code of this step, or a later one, was already accepted
+	Used bool
+}
+
+type EnableTotpInput struct {
+	UserId int
+	// RecoveryCodeHashes replace the previous recovery codes of the user
+	RecoveryCodeHashes []string
+}
+
+type EnableTotpOutput struct {
+	// Enabled is false when there is no pending enrollment
+	Enabled bool
+}
+
+type ConsumeRecoveryCodeInput struct {
+	UserId   int
+	CodeHash string
+}
+
+type ConsumeRecoveryCodeOutput struct {
+	// Consumed is false when the code is unknown or already used
+	Consumed bool
+}
+
+type CreateMfaChallengeInput struct {
+	UserId    int
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+type GetMfaChallengeInput struct {
+	TokenHash string
+}
+
+type GetMfaChallengeOutput struct {
+	Id         int
+	UserId     int
+	ExpiresAt  time.Time
+	UsedAt     *time.Time
+	TotpSecret string
+}
+
+type IncrementMfaChallengeAttemptsInput struct {
+	Id int
+}
+
+type IncrementMfaChallengeAttemptsOutput struct {
+	// Attempts is the number of codes tried, this one included
+	Attempts int
+}
+
+type ConsumeMfaChallengeInput struct {
+	Id int
+}
+
+type ConsumeMfaChallengeOutput struct {
+	// Consumed is false when the challenge had already been used
+	Consumed bool
+}
+
+// Passkeys
+type CreateWebauthnSessionInput struct {
+	Challenge string
+	// UserId is 0 for a login, the user is only known once the passkey answers
+	UserId    int
+	Data      string
+	ExpiresAt time.Time
+}
+
+type ConsumeWebauthnSessionInput struct {
+	Challenge string
+}
+
+type ConsumeWebauthnSessionOutput struct {
+	UserId int
+	Data   string
+}
+
+type CreateWebauthnCredentialInput struct {
+	UserId       int
+	CredentialId []byte
+	Name         string
+	Data         string
+}
+
+type CreateWebauthnCredentialOutput struct {
+	Id        int
+	CreatedAt time.Time
+}
+
+type ListWebauthnCredentialsInput struct {
+	UserId int
+}
+
+type WebauthnCredential struct {
+	Id           int
+	CredentialId []byte
+	Name         string
+	Data         string
+	CreatedAt    time.Time
+	LastUsedAt   *time.Time
+}
+
+type ListWebauthnCredentialsOutput struct {
+	Credentials []WebauthnCredential
+}
+
+type UpdateWebauthnCredentialInput struct {
+	Id   int
+	Data string
+}
+
+type DeleteWebauthnCredentialInput struct {
+	Id     int
+	UserId int
+}
+
+type DeleteWebauthnCredentialOutput struct {
+	// Deleted is false when the user has no such passkey
+	Deleted bool
+}
+
+// Login History
+type CreateLoginEventInput struct {
+	UserId  int
+	Success bool
+	// FailureReason is empty for successful logins
+	FailureReason string
+	IpAddress     string
+	UserAgent     string
+}
+
+type ListLoginEventsInput struct {
+	UserId int
+	// BeforeId only lists the events older than this one, 0 lists from the most recent
+	BeforeId int64
+	Limit    int
+}
+
+type ListLoginEventsOutput struct {
+	Events []LoginEvent
+}
+
+type LoginEvent struct {
+	Id            int64
+	Success       bool
+	FailureReason *string
+	IpAddress     string
+	UserAgent     string
+	CreatedAt     time.Time
 }
 
 // UpdateUser/Profile
-type UpdateUserInput struct {
-	Name           *string
-	PhoneNumber    *string
-	OldPhoneNumber string
+// UpdateUserByIdInput updates the fields that are not nil, the others keep
+// their current value.
+type UpdateUserByIdInput struct {
+	Id       int
+	FullName *string
+	// PhoneNumber has to be verified again when it changes
+	PhoneNumber *string
+	// Email has to be verified again when it changes
+	Email       *string
+	DateOfBirth *time.Time
+	Locale      *string
+	Timezone    *string
+	// AvatarUrl replaces the avatar along with its AvatarThumbnails, the
+	// thumbnails are dropped when nil. AvatarThumbnails alone are ignored.
+	AvatarUrl        *string
+	AvatarThumbnails map[string]string
+}
+
+type UpdateUserByIdOutput struct {
+	// Updated is false when the user does not exist or is deleted, or when
+	// the input has no field to update
+	Updated bool
 }
 
-type UpdateUserOutput struct {
+type GetUserByIdInput struct {
 	Id int
 }
+
+type GetUserByIdOutput struct {
+	Id          int
+	FullName    string
+	PhoneNumber string
+	// PhoneVerifiedAt is nil until the phone number is verified
+	PhoneVerifiedAt *time.Time
+	Email           *string
+	// EmailVerifiedAt is nil until the email is verified, a new email is unverified
+	EmailVerifiedAt *time.Time
+	DateOfBirth     *time.Time
+	Locale          *string
+	Timezone        *string
+	AvatarUrl       *string
+	// AvatarThumbnails are the URLs of the uploaded avatar keyed by their size, nil otherwise
+	AvatarThumbnails map[string]string
+	LoginCount       int64
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// Refresh Token
+type CreateRefreshTokenInput struct {
+	UserId    int
+	FamilyId  string
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+type GetRefreshTokenInput struct {
+	TokenHash string
+}
+
+type GetRefreshTokenOutput struct {
+	Id        int
+	UserId    int
+	FamilyId  string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
+
+type RevokeRefreshTokenInput struct {
+	Id int
+}
+
+type RevokeRefreshTokenOutput struct {
+	Revoked bool
+}
+
+type RevokeRefreshTokenFamilyInput struct {
+	FamilyId string
+}
+
+type RevokeUserRefreshTokensInput struct {
+	UserId int
+	// ExceptFamilyId keeps the refresh tokens of this session, when set
+	ExceptFamilyId string
+}
+
+// Token Revocation
+type RevokeTokenInput struct {
+	Jti       string
+	UserId    int
+	ExpiresAt time.Time
+}
+
+type RevokeUserTokensInput struct {
+	UserId int
+	// RevokedBefore is compared to the iat claim, which carries milliseconds
+	RevokedBefore time.Time
+	ExpiresAt     time.Time
+	// ExceptSessionId keeps the tokens of this session valid, when set
+	ExceptSessionId string
+}
+
+type IsTokenRevokedInput struct {
+	Jti       string
+	UserId    int
+	SessionId string
+	IssuedAt  time.Time
+}
+
+type IsTokenRevokedOutput struct {
+	Revoked bool
+}
@@ -0,0 +1,75 @@
+// This file contains the builder of the partial UPDATE queries.
+package repository
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// identifiers are written in the query as is, only plain lowercase names are accepted
+var identifierPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// updateQuery builds an UPDATE setting only the columns of a patch. Every value
+// is passed as a $n placeholder, the table and column names come from the code.
+type updateQuery struct {
+	table      string
+	sets       []string
+	conditions []string
+	args       []interface{}
+}
+
+func newUpdateQuery(table string) *updateQuery {
+	mustBeIdentifier(table)
+	return &updateQuery{table: table}
+}
+
+// Arg adds a value to the query and returns its placeholder.
+func (q *updateQuery) Arg(value interface{}) string {
+	q.args = append(q.args, value)
+	return "$" + strconv.Itoa(len(q.args))
+}
+
+// Set sets the column to the value.
+func (q *updateQuery) Set(column string, value interface{}) *updateQuery {
+	return q.SetExpression(column, q.Arg(value))
+}
+
+// SetExpression sets the column to an SQL expression, its values must be
+// added with Arg.
+func (q *updateQuery) SetExpression(column string, expression string) *updateQuery {
+	mustBeIdentifier(column)
+	q.sets = append(q.sets, column+" = "+expression)
+	return q
+}
+
+// Where adds a condition the rows must match, its values must be added with Arg.
+func (q *updateQuery) Where(condition string) *updateQuery {
+	q.conditions = append(q.conditions, condition)
+	return q
+}
+
+// Empty tells whether no column is set, the query would do nothing.
+func (q *updateQuery) Empty() bool {
+	return len(q.sets) == 0
+}
+
+// Build returns the query and its arguments. It panics without a condition,
+// an UPDATE of every row is never meant.
+func (q *updateQuery) Build() (string, []interface{}) {
+	if q.Empty() {
+		panic("repository: update query without any column to set")
+	}
+	if len(q.conditions) == 0 {
+		panic("repository: update query without any condition")
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", q.table, strings.Join(q.sets, ", "), strings.Join(q.conditions, " AND "))
+	return query, q.args
+}
+
+func mustBeIdentifier(name string) {
+	if !identifierPattern.MatchString(name) {
+		panic(fmt.Sprintf("repository: %q is not a valid identifier", name))
+	}
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateQuery_Build(t *testing.T) {
+	query := newUpdateQuery("users")
+	query.Set("full_name", "Robert'); DROP TABLE users;--")
+	email := query.Arg("arthur@example.com")
+	query.SetExpression("email_verified_at", "CASE WHEN email = "+email+" THEN email_verified_at END")
+	query.SetExpression("email", email)
+	query.Where("id = " + query.Arg(7)).Where("deleted_at IS NULL")
+
+	sqlQuery, args := query.Build()
+
+	// the values never make it into the query itself
+	assert.Equal(t, "UPDATE users SET full_name = $1, email_verified_at = CASE WHEN email = $2 THEN email_verified_at END, email = $2 WHERE id = $3 AND deleted_at IS NULL", sqlQuery)
+	assert.Equal(t, []interface{}{"Robert'); DROP TABLE users;--", "arthur@example.com", 7}, args)
+}
+
+func TestUpdateQuery_Empty(t *testing.T) {
+	query := newUpdateQuery("users").Where("id = 1")
+
+	assert.True(t, query.Empty())
+	assert.Panics(t, func() { query.Build() })
+}
+
+func TestUpdateQuery_Error_Identifier(t *testing.T) {
+	assert.Panics(t, func() { newUpdateQuery("users; --") })
+	assert.Panics(t, func() { newUpdateQuery("users").Set("full_name = 'x', phone_number", "y") })
+}
+
+func TestUpdateQuery_Error_NoCondition(t *testing.T) {
+	query := newUpdateQuery("users").Set("full_name", "x")
+
+	assert.Panics(t, func() { query.Build() })
+}
+
+func TestUpdateUserById_EmptyInput(t *testing.T) {
+	// nothing to update, the database is not even reached
+	repo := &Repository{}
+
+	res, err := repo.UpdateUserById(context.Background(), UpdateUserByIdInput{Id: 1})
+
+	assert.NoError(t, err)
+	assert.False(t, res.Updated)
+}
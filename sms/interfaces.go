@@ -0,0 +1,12 @@
+// This file contains the interfaces for the SMS senders.
+// Messages are sent through an SMSSender so the SMS provider can be swapped,
+// the log and file senders are meant for local development and tests.
+package sms
+
+import "context"
+
+type SMSSender interface {
+	// Send delivers the message to the phone number, it returns once the
+	// provider accepted it.
+	Send(ctx context.Context, message Message) error
+}
@@ -0,0 +1,111 @@
+// This file contains the SMS senders that don't need a provider.
+package sms
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	PhoneNumber string    `json:"phoneNumber"`
+	Body        string    `json:"body"`
+	SentAt      time.Time `json:"sentAt"`
+}
+
+// LogSender writes the messages to the log instead of sending them.
+type LogSender struct {
+	logger *log.Logger
+}
+
+// NewLogSender logs to the given logger, or to the standard logger when nil.
+func NewLogSender(logger *log.Logger) *LogSender {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &LogSender{logger: logger}
+}
+
+func (s *LogSender) Send(ctx context.Context, message Message) error {
+	s.logger.Printf("sms to %s: %s", message.PhoneNumber, message.Body)
+	return nil
+}
+
+// FileSender appends the messages to a file, one JSON document per line, so
+// tests and local tools can read them back with ReadMessages.
+type FileSender struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileSender(path string) *FileSender {
+	return &FileSender{path: path}
+}
+
+func (s *FileSender) Send(ctx context.Context, message Message) error {
+	if message.SentAt.IsZero() {
+		message.SentAt = time.Now().UTC()
+	}
+	line, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ReadMessages returns the messages written by a FileSender, oldest first.
+func ReadMessages(path string) ([]Message, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var messages []Message
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var message Message
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			return nil, fmt.Errorf("invalid message line: %w", err)
+		}
+		messages = append(messages, message)
+	}
+	return messages, scanner.Err()
+}
+
+// NewSenderFromEnv returns the sender chosen by SMS_SENDER, "log" (default)
+// or "file" writing to SMS_FILE_PATH.
+func NewSenderFromEnv() (SMSSender, error) {
+	switch sender := os.Getenv("SMS_SENDER"); sender {
+	case "", "log":
+		return NewLogSender(nil), nil
+	case "file":
+		path := os.Getenv("SMS_FILE_PATH")
+		if path == "" {
+			return nil, fmt.Errorf("SMS_FILE_PATH is required by the file SMS sender")
+		}
+		return NewFileSender(path), nil
+	default:
+		return nil, fmt.Errorf("unknown SMS_SENDER %q, expected log or file", sender)
+	}
+}
@@ -0,0 +1,61 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSender_Send(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sms.jsonl")
+	sender := NewFileSender(path)
+	ctx := context.Background()
+
+	assert.NoError(t, sender.Send(ctx, Message{PhoneNumber: "+6282222222", Body: "first"}))
+	assert.NoError(t, sender.Send(ctx, Message{PhoneNumber: "+6283333333", Body: "second"}))
+
+	messages, err := ReadMessages(path)
+
+	assert.NoError(t, err)
+	if assert.Len(t, messages, 2) {
+		assert.Equal(t, "+6282222222", messages[0].PhoneNumber)
+		assert.Equal(t, "first", messages[0].Body)
+		assert.False(t, messages[0].SentAt.IsZero())
+		assert.Equal(t, "second", messages[1].Body)
+	}
+}
+
+func TestReadMessages_NoFile(t *testing.T) {
+	messages, err := ReadMessages(filepath.Join(t.TempDir(), "missing.jsonl"))
+
+	assert.NoError(t, err)
+	assert.Empty(t, messages)
+}
+
+func TestLogSender_Send(t *testing.T) {
+	var buffer bytes.Buffer
+	sender := NewLogSender(log.New(&buffer, "", 0))
+
+	assert.NoError(t, sender.Send(context.Background(), Message{PhoneNumber: "+6282222222", Body: "hello"}))
+	assert.Equal(t, "sms to +6282222222: hello\n", buffer.String())
+}
+
+func TestNewSenderFromEnv(t *testing.T) {
+	t.Setenv("SMS_SENDER", "file")
+	t.Setenv("SMS_FILE_PATH", "")
+	_, err := NewSenderFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("SMS_FILE_PATH", filepath.Join(t.TempDir(), "sms.jsonl"))
+	sender, err := NewSenderFromEnv()
+	assert.NoError(t, err)
+	assert.IsType(t, &FileSender{}, sender)
+
+	t.Setenv("SMS_SENDER", "carrier-pigeon")
+	_, err = NewSenderFromEnv()
+	assert.Error(t, err)
+}